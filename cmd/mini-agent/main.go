@@ -2,10 +2,16 @@ package main
 
 import (
 	"cmp"
+	"context"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/lsp"
+	"github.com/loveRyujin/mini-agent/internal/mcp"
 	"github.com/loveRyujin/mini-agent/internal/prompt"
 	"github.com/loveRyujin/mini-agent/internal/tools"
 	"github.com/loveRyujin/mini-agent/internal/tui"
@@ -18,16 +24,77 @@ const (
 
 func main() {
 	if err := run(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
 func run() error {
+	worktree := flag.Bool("worktree", false, "在新分支的 git worktree 中运行 Session，结束时选择合并、保留或丢弃")
+	flag.Parse()
+
+	if flag.Arg(0) == "mcp-serve" {
+		return serveMCP()
+	}
+	if !*worktree {
+		return runSession(nil)
+	}
+	ctx := context.Background()
+	w, err := startWorktree(ctx)
+	if err != nil {
+		return fmt.Errorf("worktree: %w", err)
+	}
+	sessionErr := runSession(w)
+	if err := finishWorktree(ctx, w, os.Stdin, os.Stdout); err != nil {
+		return fmt.Errorf("worktree: %w", err)
+	}
+	return sessionErr
+}
+
+func runSession(w *git.Worktree) error {
 	if err := tools.InitWorkspace(); err != nil {
 		return fmt.Errorf("init workspace: %w", err)
 	}
 
+	writePolicy, err := tools.WritePolicyFromEnv()
+	if err != nil {
+		return fmt.Errorf("write policy: %w", err)
+	}
+	tools.SetWritePolicy(writePolicy)
+	defer tools.ResetSession()
+
+	cfg, err := config.Load(tools.WorkspaceRoot())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	opts := tui.Options{Worktree: w}
+	manifests, errs := tools.LoadManifests(manifestDir(w))
+	for _, err := range errs {
+		opts.Notices = append(opts.Notices, err.Error())
+	}
+	launches := cfg.Launches()
+	for _, t := range manifests {
+		if l := t.Launch(); l != "" {
+			launches = append(launches, l)
+		} else if cfg.Allows(t.Name()) {
+			launches = append(launches, fmt.Sprintf("approval.%s: allow", t.Name()))
+		}
+	}
+	if len(launches) > 0 {
+		trusted, err := confirmTrust(trustRoot(w), launches, os.Stdin, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("trust: %w", err)
+		}
+		if !trusted {
+			cfg.DropLaunches()
+			for _, t := range manifests {
+				t.RequireApproval()
+				cfg.DropAllow(t.Name())
+			}
+			opts.Notices = append(opts.Notices, "未信任 Workspace 配置，已跳过其中的语言服务器、MCP 服务器与验证命令，自定义工具的每次调用都需要确认。")
+		}
+	}
+
 	apiKey := os.Getenv("LLM_API_KEY")
 	url := cmp.Or(os.Getenv("LLM_API_URL"), defaultURL)
 	model := cmp.Or(os.Getenv("LLM_MODEL"), defaultModel)
@@ -38,5 +105,40 @@ func run() error {
 	}
 
 	a := agent.NewAgent(apiKey, url, model, systemPrompt)
-	return tui.Run(a)
+	a.Verify = cfg.Verify
+	a.Results = cfg.Results
+	a.Modes = agent.ConfigureModes(cfg.Modes)
+	a.ApprovalPolicy = cfg.Approval
+	a.AskUser = cfg.AskUser
+
+	if servers := lsp.NewManager(tools.WorkspaceRoot(), cfg.LSP); servers.Enabled() {
+		defer servers.Close()
+		tools.AddMutationObserver(servers)
+		a.RegisterTool(servers.Tools()...)
+	}
+
+	for _, t := range manifests {
+		if _, exists := a.Tools.Get(t.Name()); exists {
+			opts.Notices = append(opts.Notices, fmt.Sprintf("%s: tool %q is already defined", t.Path(), t.Name()))
+			continue
+		}
+		a.RegisterTool(t)
+	}
+	if len(cfg.MCP) > 0 {
+		servers := mcp.Start(context.Background(), tools.WorkspaceRoot(), cfg.MCP)
+		defer servers.Close()
+		a.RegisterTool(servers.Tools()...)
+		for name, rule := range servers.ApprovalPolicy() {
+			if a.ApprovalPolicy == nil {
+				a.ApprovalPolicy = make(map[string]config.ApprovalRule)
+			}
+			if _, ok := a.ApprovalPolicy[name]; !ok {
+				a.ApprovalPolicy[name] = rule
+			}
+		}
+		for _, err := range servers.Errors {
+			opts.Notices = append(opts.Notices, err.Error())
+		}
+	}
+	return tui.Run(a, opts)
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+	"github.com/loveRyujin/mini-agent/internal/mcp"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// serveMCP offers the Built-in Tools to an MCP client over stdin and
+// stdout, with the same Workspace, write policy and approval policy as a
+// Session.
+func serveMCP() error {
+	if err := tools.InitWorkspace(); err != nil {
+		return fmt.Errorf("init workspace: %w", err)
+	}
+	writePolicy, err := tools.WritePolicyFromEnv()
+	if err != nil {
+		return fmt.Errorf("write policy: %w", err)
+	}
+	tools.SetWritePolicy(writePolicy)
+	defer tools.ResetSession()
+
+	cfg, err := config.Load(tools.WorkspaceRoot())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	server := mcp.NewServer(tools.Builtin(), cfg.Approval)
+	return server.Serve(jsonrpc.NewLineStream(os.Stdin, os.Stdout))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+)
+
+// confirmTrust asks once per Workspace, and again whenever they change,
+// before the configuration file may start language servers and MCP
+// servers. Without an answer nothing is started.
+func confirmTrust(root string, launches []string, in io.Reader, out io.Writer) (bool, error) {
+	if trusted, err := config.IsTrusted(root, launches); err != nil || trusted {
+		return trusted, err
+	}
+	fmt.Fprintf(out, "Workspace 配置要求启动以下程序或连接以下服务器（%s）：\n", root)
+	for _, l := range launches {
+		fmt.Fprintf(out, "  %s\n", l)
+	}
+	fmt.Fprint(out, "它们以你的权限运行，并可读取配置中引用的环境变量。信任此 Workspace 配置？[y/N]：")
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		return false, nil
+	}
+	if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+		return false, nil
+	}
+	return true, config.Trust(root, launches)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// startWorktree creates a worktree of the current checkout on a new branch
+// and moves into it, so tools.InitWorkspace makes it the Workspace.
+func startWorktree(ctx context.Context) (*git.Worktree, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	origin, err := git.Open(ctx, wd)
+	if err != nil {
+		return nil, err
+	}
+	w, err := git.CreateWorktree(ctx, origin, time.Now().Format("20060102-150405"))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(w.Dir); err != nil {
+		_ = w.Remove(ctx)
+		return nil, err
+	}
+	useOriginConfig(wd, w.Dir)
+	return w, nil
+}
+
+// useOriginConfig points config.Load at the original checkout's
+// configuration when it is not part of the worktree, as with an untracked
+// .mini-agent/config.json. The file is read in place rather than copied, so
+// it never shows up as a change to merge.
+func useOriginConfig(origin, dir string) {
+	if os.Getenv(config.EnvConfigFile) != "" {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(dir, config.DefaultPath)); err == nil {
+		return
+	}
+	path := filepath.Join(origin, config.DefaultPath)
+	if _, err := os.Stat(path); err == nil {
+		os.Setenv(config.EnvConfigFile, path)
+	}
+}
+
+// manifestDir is where the Session's tool manifests live: the Workspace's,
+// or in a worktree without them the original checkout's, as with an
+// untracked .mini-agent/tools.
+func manifestDir(w *git.Worktree) string {
+	dir := filepath.Join(tools.WorkspaceRoot(), tools.ManifestDir)
+	if w == nil {
+		return dir
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return dir
+	}
+	return filepath.Join(w.Origin.Dir, tools.ManifestDir)
+}
+
+// trustRoot is the directory the Workspace trust is recorded for. A
+// worktree is new every Session, so it shares the original checkout's.
+func trustRoot(w *git.Worktree) string {
+	if w == nil {
+		return tools.WorkspaceRoot()
+	}
+	return w.Origin.Dir
+}
+
+// finishWorktree asks whether to merge, keep or discard the worktree once
+// the Session has ended. Without an answer the worktree is kept.
+func finishWorktree(ctx context.Context, w *git.Worktree, in io.Reader, out io.Writer) error {
+	if err := os.Chdir(w.Origin.Dir); err != nil {
+		return err
+	}
+	changes, err := w.Changes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "worktree 中没有改动，已删除。")
+		return w.Remove(ctx)
+	}
+
+	fmt.Fprintf(out, "Session 在 worktree 中改动了 %d 个文件（分支 %s）：\n", len(changes), w.Branch)
+	for _, c := range changes {
+		fmt.Fprintf(out, "  %s\n", formatChange(c))
+	}
+	choices := "[k] 保留  [d] 丢弃"
+	if w.BaseBranch != "" {
+		choices = fmt.Sprintf("[m] 合并到 %s  %s", w.BaseBranch, choices)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "%s：", choices)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			break
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "m":
+			if w.BaseBranch == "" {
+				continue
+			}
+			if err := w.Merge(ctx, "Apply mini-agent Session from "+w.Branch); err != nil {
+				fmt.Fprintf(out, "合并失败：%v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "已合并到 %s。\n", w.BaseBranch)
+			return nil
+		case "d":
+			if err := w.Remove(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "已丢弃 worktree 及其分支。")
+			return nil
+		case "k":
+			return keepWorktree(w, out)
+		}
+	}
+	return keepWorktree(w, out)
+}
+
+func keepWorktree(w *git.Worktree, out io.Writer) error {
+	fmt.Fprintf(out, "已保留 worktree：%s（分支 %s）。不再需要时可运行 git worktree remove 删除。\n", w.Root, w.Branch)
+	return nil
+}
+
+func formatChange(c git.FileChange) string {
+	if c.Binary {
+		return c.Path + "  (binary)"
+	}
+	return fmt.Sprintf("%s  +%d -%d", c.Path, c.Added, c.Deleted)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/loveRyujin/mini-agent/internal/config"
 	"github.com/loveRyujin/mini-agent/internal/inference"
 	"github.com/loveRyujin/mini-agent/internal/prompt"
 	"github.com/loveRyujin/mini-agent/internal/tools"
@@ -13,14 +14,32 @@ import (
 
 const maxToolRoundsPerTurn = 25
 
-var errToolLoopLimit = errors.New("tool loop limit exceeded")
+var (
+	errToolLoopLimit = errors.New("tool loop limit exceeded")
+	errVerifyGaveUp  = errors.New("verification is still failing")
+)
 
 type Agent struct {
 	Backend      inference.Backend
 	Model        string
-	Tools        map[string]tools.Tool
+	Tools        *tools.Registry
 	History      []map[string]any
 	ApprovalGate ApprovalGate
+	// QuestionGate answers ask_user instead of the front end; see ask.go.
+	QuestionGate QuestionGate
+	// AskUser holds the answer to questions nobody answered.
+	AskUser config.AskUser
+	// ApprovalPolicy lets gated tools skip the Approval Gate or be refused
+	// outright, by tool name.
+	ApprovalPolicy map[string]config.ApprovalRule
+	// Verify is run after a Turn that mutated files; see verify.go.
+	Verify config.Verify
+	// Results bounds the tool results of each round; larger ones are
+	// stored as artifacts and truncated.
+	Results config.ResultBudget
+	// Modes can be switched with SetMode; see mode.go.
+	Modes        []Mode
+	mode         string
 	systemPrompt string
 }
 
@@ -33,21 +52,35 @@ func NewAgent(apiKey, url, model, systemPrompt string) *Agent {
 			Model:      model,
 		},
 		Model:        model,
-		Tools:        make(map[string]tools.Tool),
+		Tools:        tools.NewRegistry(),
+		Modes:        DefaultModes(),
+		mode:         ModeCode,
 		systemPrompt: systemPrompt,
 	}
 	for _, tool := range tools.Builtin() {
 		agent.RegisterTool(tool)
 	}
+	agent.RegisterTool(&tools.ReadArtifact{}, NewAskUserTool(agent), NewDelegateTool(agent))
 	agent.initHistory(systemPrompt)
 	return agent
 }
 
 func (a *Agent) initHistory(systemPrompt string) {
-	a.History = []map[string]any{{
+	a.systemPrompt = systemPrompt
+	a.History = []map[string]any{a.systemMessage()}
+}
+
+// systemMessage is the System Prompt followed by the addendum of the
+// active mode.
+func (a *Agent) systemMessage() map[string]any {
+	content := a.systemPrompt
+	if p := a.Mode().Prompt; p != "" {
+		content += "\n\n" + p
+	}
+	return map[string]any{
 		"role":    "system",
-		"content": systemPrompt,
-	}}
+		"content": content,
+	}
 }
 
 func (a *Agent) ClearSession() {
@@ -55,11 +88,13 @@ func (a *Agent) ClearSession() {
 		a.systemPrompt = prompt.Default()
 	}
 	a.initHistory(a.systemPrompt)
+	tools.ResetSession()
 }
 
 func (a *Agent) ClearSessionWithPrompt(systemPrompt string) {
 	a.systemPrompt = systemPrompt
 	a.initHistory(systemPrompt)
+	tools.ResetSession()
 }
 
 func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitter) error {
@@ -68,35 +103,82 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 		"content": userMessage,
 	})
 
-	req := map[string]any{
-		"model":    a.Model,
-		"messages": a.History,
-		"stream":   true,
-		"stream_options": map[string]any{
-			"include_usage": true,
-		},
-		"tools":       a.ToolDefinitions(),
-		"tool_choice": "auto",
+	var tokenUsage []inference.Usage
+	mutations := tools.MutationCount()
+	assistantMessage, err := a.runRounds(ctx, emit, &tokenUsage)
+	if err != nil {
+		return err
 	}
 
+	for attempt := 1; a.Verify.Command != "" && tools.MutationCount() != mutations; attempt++ {
+		mutations = tools.MutationCount()
+		feedback, err := a.verify(ctx, attempt, emit)
+		if err != nil {
+			emit(Event{Kind: EventError, Err: err})
+			return err
+		}
+		if feedback == "" {
+			break
+		}
+		if attempt >= a.Verify.Attempts() {
+			emit(Event{Kind: EventError, Err: fmt.Errorf("%w after %d attempts", errVerifyGaveUp, attempt)})
+			break
+		}
+		a.History = append(a.History, map[string]any{
+			"role":    "user",
+			"content": feedback,
+		})
+		if assistantMessage, err = a.runRounds(ctx, emit, &tokenUsage); err != nil {
+			return err
+		}
+		if tools.MutationCount() == mutations {
+			emit(Event{Kind: EventError, Err: fmt.Errorf("%w: no files were changed to fix it", errVerifyGaveUp)})
+		}
+	}
+
+	emit(Event{Kind: EventTurnComplete, AssistantMessage: assistantMessage})
+
+	if len(tokenUsage) > 0 {
+		var cToken, pToken int
+		for _, usage := range tokenUsage {
+			cToken += int(usage.CompletionToken)
+			pToken += int(usage.PromptToken)
+		}
+		emit(Event{
+			Kind: EventUsage,
+			Usage: inference.Usage{
+				CompletionToken: int64(cToken),
+				PromptToken:     int64(pToken),
+				TotalToken:      tokenUsage[len(tokenUsage)-1].TotalToken,
+			},
+		})
+	}
+
+	return nil
+}
+
+// runRounds streams model rounds and runs the tools they call until the
+// model answers without tool calls. The answer is appended to History and
+// returned.
+func (a *Agent) runRounds(ctx context.Context, emit EventEmitter, tokenUsage *[]inference.Usage) (string, error) {
+	req := a.request()
+
 	var (
 		chunks      []string
-		tokenUsage  []inference.Usage
 		lastToolSig string
 	)
 
 	for round := 0; ; round++ {
 		if round >= maxToolRoundsPerTurn {
 			emit(Event{Kind: EventError, Err: fmt.Errorf("%w (%d rounds)", errToolLoopLimit, maxToolRoundsPerTurn)})
-			return errToolLoopLimit
+			return "", errToolLoopLimit
 		}
 
 		req["messages"] = a.History
-
 		ch, err := a.Backend.CallLLMStream(ctx, req)
 		if err != nil {
 			emit(Event{Kind: EventError, Err: err})
-			return err
+			return "", err
 		}
 
 		toolAcc := inference.NewToolCallAccumulator()
@@ -104,7 +186,7 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 
 		for msg := range ch {
 			if len(msg.Choices) == 0 {
-				tokenUsage = append(tokenUsage, msg.Usage)
+				*tokenUsage = append(*tokenUsage, msg.Usage)
 				continue
 			}
 
@@ -125,7 +207,7 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 				chunks = append(chunks, delta.Content)
 			}
 
-			tokenUsage = append(tokenUsage, msg.Usage)
+			*tokenUsage = append(*tokenUsage, msg.Usage)
 		}
 
 		calls := toolAcc.Calls()
@@ -135,7 +217,7 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 					Kind: EventError,
 					Err:  fmt.Errorf("model finished with tool_calls but no valid tool call was parsed"),
 				})
-				return fmt.Errorf("incomplete tool call stream")
+				return "", fmt.Errorf("incomplete tool call stream")
 			}
 			break
 		}
@@ -146,7 +228,7 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 				Kind: EventError,
 				Err:  fmt.Errorf("model repeated identical tool calls; stopping to avoid loop"),
 			})
-			return fmt.Errorf("duplicate tool call loop")
+			return "", fmt.Errorf("duplicate tool call loop")
 		}
 		lastToolSig = sig
 
@@ -154,7 +236,7 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 		resp, err := a.toolCall(ctx, calls, emit)
 		if err != nil {
 			emit(Event{Kind: EventError, Err: err})
-			return err
+			return "", err
 		}
 		a.History = append(a.History, resp...)
 		chunks = nil
@@ -167,82 +249,158 @@ func (a *Agent) RunTurn(ctx context.Context, userMessage string, emit EventEmitt
 			"content": assistantMessage,
 		})
 	}
+	return assistantMessage, nil
+}
 
-	emit(Event{Kind: EventTurnComplete, AssistantMessage: assistantMessage})
-
-	if len(tokenUsage) > 0 {
-		var cToken, pToken int
-		for _, usage := range tokenUsage {
-			cToken += int(usage.CompletionToken)
-			pToken += int(usage.PromptToken)
-		}
-		emit(Event{
-			Kind: EventUsage,
-			Usage: inference.Usage{
-				CompletionToken: int64(cToken),
-				PromptToken:     int64(pToken),
-				TotalToken:      tokenUsage[len(tokenUsage)-1].TotalToken,
-			},
-		})
+// request builds the request of a round. Only messages changes between
+// rounds, and only by appending, so consecutive requests share a
+// byte-identical prefix that providers can cache.
+func (a *Agent) request() map[string]any {
+	return map[string]any{
+		"model":    a.ActiveModel(),
+		"messages": a.History,
+		"stream":   true,
+		"stream_options": map[string]any{
+			"include_usage": true,
+		},
+		"tools":       a.ToolDefinitions(),
+		"tool_choice": "auto",
 	}
-
-	return nil
 }
 
-func (a *Agent) RegisterTool(tools ...tools.Tool) {
-	for _, tool := range tools {
-		a.Tools[tool.Name()] = tool
-	}
+// RegisterTool adds tools, replacing registered tools of the same name in
+// place.
+func (a *Agent) RegisterTool(ts ...tools.Tool) {
+	a.Tools.Register(ts...)
 }
 
+// ToolDefinitions returns the definitions of the tools the active mode
+// offers, in registration order.
 func (a *Agent) ToolDefinitions() []map[string]any {
-	defs := make([]map[string]any, 0, len(a.Tools))
-	for _, tool := range a.Tools {
-		defs = append(defs, tool.Definition())
+	mode := a.Mode()
+	defs := make([]map[string]any, 0, a.Tools.Len())
+	for _, tool := range a.Tools.Tools() {
+		if mode.offers(tool) {
+			defs = append(defs, tool.Definition())
+		}
 	}
 	return defs
 }
 
 func (a *Agent) toolCall(ctx context.Context, toolCalls []inference.ToolCall, emit EventEmitter) ([]map[string]any, error) {
-	results := make([]map[string]any, 0, len(toolCalls))
+	messages := make([]map[string]any, 0, len(toolCalls))
+	remaining := a.Results.RoundLimit()
+
+	for i := 0; i < len(toolCalls); i++ {
+		tc := toolCalls[i]
+		// Runs of read-only calls execute together; see parallel.go.
+		if end := i; a.concurrent(tc) {
+			for end < len(toolCalls) && a.concurrent(toolCalls[end]) {
+				end++
+			}
+			for j, result := range a.runConcurrent(ctx, toolCalls[i:end], &remaining, emit) {
+				messages = append(messages, toolMessage(toolCalls[i+j].ID, result))
+			}
+			i = end - 1
+			continue
+		}
 
-	for _, tc := range toolCalls {
 		emit(Event{
 			Kind:          EventToolCall,
+			ToolCallID:    tc.ID,
 			ToolName:      tc.Function.Name,
 			ToolArguments: tc.Function.Arguments,
 		})
 
-		tool, exist := a.Tools[tc.Function.Name]
-		if !exist {
-			resp := tools.FailResp(tc.ID, fmt.Errorf("unknown tool %q", tc.Function.Name))
-			content, _ := resp["content"].(string)
-			emit(Event{Kind: EventToolResult, ToolName: tc.Function.Name, ToolContent: content})
-			results = append(results, resp)
-			continue
-		}
-
-		var resp map[string]any
-		if gt, ok := tool.(tools.GatedTool); ok {
-			allowed, err := a.requestApproval(ctx, gt, tc, emit)
-			if err != nil {
+		var result tools.ToolResult
+		tool, exist := a.Tools.Get(tc.Function.Name)
+		switch {
+		case !exist:
+			result = tools.Failure(fmt.Errorf("unknown tool %q", tc.Function.Name))
+		default:
+			var err error
+			if result, err = a.callTool(ctx, tool, tc, emit); err != nil {
 				return nil, err
 			}
-			if !allowed {
-				resp = tools.FailResp(tc.ID, tools.ErrShellDenied)
-			} else {
-				resp = tool.Call(ctx, tc)
-			}
-		} else {
-			resp = tool.Call(ctx, tc)
 		}
 
-		content, _ := resp["content"].(string)
-		emit(Event{Kind: EventToolResult, ToolName: tc.Function.Name, ToolContent: content})
-		results = append(results, resp)
+		result = a.fitResult(tc.Function.Name, result, &remaining)
+		emit(Event{Kind: EventToolResult, ToolCallID: tc.ID, ToolName: tc.Function.Name, ToolResult: result})
+		messages = append(messages, toolMessage(tc.ID, result))
+	}
+
+	return messages, nil
+}
+
+// callTool checks tc against the active mode, validates it and passes it
+// through the Approval Gate before calling tool. Only a failure to ask for
+// approval is returned as an error.
+func (a *Agent) callTool(ctx context.Context, tool tools.Tool, tc inference.ToolCall, emit EventEmitter) (tools.ToolResult, error) {
+	if err := a.Mode().allows(tool, tc); err != nil {
+		return tools.Failure(err), nil
 	}
+	if err := tools.ValidateArguments(tool, tc.Function.Arguments); err != nil {
+		return tools.Failure(err), nil
+	}
+	ctx = withEmitter(ctx, emit)
+	gt, ok := tool.(tools.GatedTool)
+	if !ok || !tools.NeedsApproval(tool, tc) {
+		return tool.Call(ctx, tc), nil
+	}
+	allowed, err := a.approve(ctx, gt, tc, emit)
+	switch {
+	case err != nil && !errors.Is(err, tools.ErrPolicyDenied):
+		return tools.ToolResult{}, err
+	case err != nil:
+		return tools.Failure(err), nil
+	case !allowed:
+		return tools.Failure(tools.ErrToolDenied), nil
+	default:
+		return tool.Call(ctx, tc), nil
+	}
+}
 
-	return results, nil
+// minResultBytes is what a result may still use once earlier results of
+// its round spent the round's budget.
+const minResultBytes = 4 * 1024
+
+// fitResult applies the Results budget to a result of tool and charges it
+// to remaining, the rest of the round's budget. Pages of artifacts are
+// never truncated again.
+func (a *Agent) fitResult(tool string, result tools.ToolResult, remaining *int) tools.ToolResult {
+	if tool != tools.ReadArtifactName {
+		result = tools.Truncate(tool, result, min(a.Results.ToolLimit(tool), max(*remaining, minResultBytes)))
+	}
+	*remaining -= len(result.ModelText())
+	return result
+}
+
+// toolMessage is the History message that hands a tool result to the
+// Inference Backend.
+func toolMessage(toolCallID string, result tools.ToolResult) map[string]any {
+	return map[string]any{
+		"role":         "tool",
+		"tool_call_id": toolCallID,
+		"content":      result.ModelText(),
+	}
+}
+
+// approve applies the approval policy of the active mode, then the
+// ApprovalPolicy, for the tool, asking the developer unless it says allow
+// or deny.
+func (a *Agent) approve(ctx context.Context, gt tools.GatedTool, toolCall inference.ToolCall, emit EventEmitter) (bool, error) {
+	rule, ok := a.Mode().ApprovalPolicy[toolCall.Function.Name]
+	if !ok {
+		rule = a.ApprovalPolicy[toolCall.Function.Name]
+	}
+	switch rule {
+	case config.ApprovalAllow:
+		return true, nil
+	case config.ApprovalDeny:
+		return false, tools.ErrPolicyDenied
+	default:
+		return a.requestApproval(ctx, gt, toolCall, emit)
+	}
 }
 
 func (a *Agent) requestApproval(ctx context.Context, gt tools.GatedTool, toolCall inference.ToolCall, emit EventEmitter) (bool, error) {
@@ -1,7 +1,9 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,6 +12,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/loveRyujin/mini-agent/internal/config"
 	"github.com/loveRyujin/mini-agent/internal/inference"
 	"github.com/loveRyujin/mini-agent/internal/tools"
 )
@@ -78,7 +81,7 @@ func TestRunTurn_conversationOnly(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.initHistory("system prompt")
 
@@ -145,7 +148,7 @@ func TestRunTurn_toolLoop(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ReadFile{})
 	agent.initHistory("system prompt")
@@ -224,7 +227,7 @@ func TestRunTurn_multiToolLoop(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ReadFile{}, &tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -288,7 +291,7 @@ func TestRunTurn_toolPathEscape(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ReadFile{})
 	agent.initHistory("system prompt")
@@ -303,8 +306,8 @@ func TestRunTurn_toolPathEscape(t *testing.T) {
 	for _, e := range got {
 		if e.Kind == EventToolResult {
 			foundResult = true
-			if !strings.Contains(e.ToolContent, "path escapes workspace") {
-				t.Fatalf("tool result should mention escape: %q", e.ToolContent)
+			if !strings.Contains(e.ToolResult.ModelText(), "path escapes workspace") {
+				t.Fatalf("tool result should mention escape: %q", e.ToolResult.ModelText())
 			}
 		}
 	}
@@ -340,7 +343,7 @@ func TestRunTurn_shellAllowed(t *testing.T) {
 	agent := &Agent{
 		Backend:      backend,
 		Model:        "test-model",
-		Tools:        make(map[string]tools.Tool),
+		Tools:        tools.NewRegistry(),
 		ApprovalGate: NewStaticApprovalGate(true),
 	}
 	agent.RegisterTool(&tools.RunShell{})
@@ -366,8 +369,8 @@ func TestRunTurn_shellAllowed(t *testing.T) {
 
 	for _, e := range events() {
 		if e.Kind == EventToolResult {
-			if !strings.Contains(e.ToolContent, "allowed") {
-				t.Fatalf("tool result should contain command output: %q", e.ToolContent)
+			if !strings.Contains(e.ToolResult.ModelText(), "allowed") {
+				t.Fatalf("tool result should contain command output: %q", e.ToolResult.ModelText())
 			}
 		}
 	}
@@ -400,7 +403,7 @@ func TestRunTurn_shellDenied(t *testing.T) {
 	agent := &Agent{
 		Backend:      backend,
 		Model:        "test-model",
-		Tools:        make(map[string]tools.Tool),
+		Tools:        tools.NewRegistry(),
 		ApprovalGate: NewStaticApprovalGate(false),
 	}
 	agent.RegisterTool(&tools.RunShell{})
@@ -426,16 +429,71 @@ func TestRunTurn_shellDenied(t *testing.T) {
 
 	for _, e := range events() {
 		if e.Kind == EventToolResult {
-			if !strings.Contains(e.ToolContent, "FAILED") {
-				t.Fatalf("expected FAILED tool result, got %q", e.ToolContent)
+			if !strings.Contains(e.ToolResult.ModelText(), "FAILED") {
+				t.Fatalf("expected FAILED tool result, got %q", e.ToolResult.ModelText())
 			}
-			if !strings.Contains(e.ToolContent, "denied") {
-				t.Fatalf("expected denial message, got %q", e.ToolContent)
+			if !strings.Contains(e.ToolResult.ModelText(), "denied") {
+				t.Fatalf("expected denial message, got %q", e.ToolResult.ModelText())
 			}
 		}
 	}
 }
 
+func TestRunTurn_approvalPolicy(t *testing.T) {
+	for rule, wantContent := range map[config.ApprovalRule]string{
+		config.ApprovalAllow: "policy-ran",
+		config.ApprovalDeny:  "approval policy",
+	} {
+		t.Run(string(rule), func(t *testing.T) {
+			chdirWorkspace(t, t.TempDir())
+			backend := &scriptedBackend{
+				scripts: [][]inference.Response{
+					{
+						{Choices: []inference.Choice{{Delta: inference.Delta{
+							ToolCalls: []inference.ToolCall{{
+								ID:   "call-1",
+								Type: "function",
+								Function: inference.Function{
+									Name:      "run_shell",
+									Arguments: map[string]any{"command": "echo policy-ran"},
+								},
+							}},
+						}}}},
+					},
+					{
+						{Choices: []inference.Choice{{Delta: inference.Delta{Content: "ok"}}}},
+					},
+				},
+			}
+			// The gate would refuse; the policy must decide without asking.
+			agent := &Agent{
+				Backend:        backend,
+				Model:          "test-model",
+				Tools:          tools.NewRegistry(),
+				ApprovalGate:   NewStaticApprovalGate(false),
+				ApprovalPolicy: map[string]config.ApprovalRule{"run_shell": rule},
+			}
+			agent.RegisterTool(&tools.RunShell{})
+			agent.initHistory("system prompt")
+
+			emit, events := collectEmitter()
+			if err := agent.RunTurn(context.Background(), "run echo", emit); err != nil {
+				t.Fatalf("RunTurn: %v", err)
+			}
+			got := eventKinds(events())
+			want := []EventKind{EventToolCall, EventToolResult, EventAnswerDelta, EventTurnComplete, EventUsage}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("event kinds:\n got: %v\nwant: %v", got, want)
+			}
+			for _, e := range events() {
+				if e.Kind == EventToolResult && !strings.Contains(e.ToolResult.ModelText(), wantContent) {
+					t.Fatalf("tool result = %q, want %q", e.ToolResult.ModelText(), wantContent)
+				}
+			}
+		})
+	}
+}
+
 func TestRunTurn_toolLoopLimit(t *testing.T) {
 	dir := t.TempDir()
 	chdirWorkspace(t, dir)
@@ -461,7 +519,7 @@ func TestRunTurn_toolLoopLimit(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -523,7 +581,7 @@ func TestRunTurn_streamingToolCallDeduplicated(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -572,7 +630,7 @@ func TestRunTurn_toolHistoryIncludesAssistantToolCalls(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -627,7 +685,7 @@ func TestRunTurn_unknownToolStillRecordsResult(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.initHistory("system prompt")
 
@@ -671,7 +729,7 @@ func TestRunTurn_duplicateToolCallsStop(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -715,7 +773,7 @@ func TestRunTurn_reasoningAndToolCallsSameDelta(t *testing.T) {
 	agent := &Agent{
 		Backend: backend,
 		Model:   "test-model",
-		Tools:   make(map[string]tools.Tool),
+		Tools:   tools.NewRegistry(),
 	}
 	agent.RegisterTool(&tools.ListFile{})
 	agent.initHistory("system prompt")
@@ -750,14 +808,17 @@ func eventKinds(events []Event) []EventKind {
 func TestNewAgent_builtinTools(t *testing.T) {
 	agent := NewAgent("", "", "test", "system")
 
-	want := []string{"read_file", "list_file", "write_file", "workspace_search", "run_shell"}
-	for _, name := range want {
-		if _, ok := agent.Tools[name]; !ok {
-			t.Fatalf("missing built-in tool %q", name)
-		}
+	want := []string{
+		"read_file", "list_file", "write_file", "move_path", "copy_path", "delete_path", "restore_path", "make_dir",
+		"workspace_search", "go_symbols", "go", "git", "run_shell", "todo_write",
+		"read_artifact", "ask_user", "delegate_task",
+	}
+	var names []string
+	for _, tool := range agent.Tools.Tools() {
+		names = append(names, tool.Name())
 	}
-	if len(agent.Tools) != len(want) {
-		t.Fatalf("Tools = %d, want exactly %d coding tools", len(agent.Tools), len(want))
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Tools = %v, want exactly %v in registration order", names, want)
 	}
 }
 
@@ -765,7 +826,7 @@ func TestAgentClearSession_preservesConfiguredPrompt(t *testing.T) {
 	agent := &Agent{
 		Backend:      &scriptedBackend{},
 		Model:        "test-model",
-		Tools:        make(map[string]tools.Tool),
+		Tools:        tools.NewRegistry(),
 		systemPrompt: "configured prompt",
 	}
 	agent.initHistory(agent.systemPrompt)
@@ -783,3 +844,168 @@ func TestAgentClearSession_preservesConfiguredPrompt(t *testing.T) {
 		t.Fatalf("History[0].content = %v, want configured prompt", agent.History[0]["content"])
 	}
 }
+
+type countingGate struct{ asked int }
+
+func (g *countingGate) RequestApproval(context.Context, ApprovalRequest, EventEmitter) (bool, error) {
+	g.asked++
+	return true, nil
+}
+
+func TestRunTurn_invalidArgumentsAreRejected(t *testing.T) {
+	chdirWorkspace(t, t.TempDir())
+	call := func(id, name string, args map[string]any) inference.ToolCall {
+		return inference.ToolCall{ID: id, Type: "function", Function: inference.Function{Name: name, Arguments: args}}
+	}
+	backend := &scriptedBackend{
+		scripts: [][]inference.Response{
+			{
+				{Choices: []inference.Choice{{Delta: inference.Delta{
+					ToolCalls: []inference.ToolCall{
+						call("call-1", "read_file", map[string]any{"offset": "10", "whole": true}),
+						call("call-2", "run_shell", map[string]any{"command": 42.0}),
+					},
+				}}}},
+			},
+			{
+				{Choices: []inference.Choice{{Delta: inference.Delta{Content: "ok"}}}},
+			},
+		},
+	}
+	gate := &countingGate{}
+	agent := &Agent{
+		Backend:      backend,
+		Model:        "test-model",
+		Tools:        tools.NewRegistry(),
+		ApprovalGate: gate,
+	}
+	agent.RegisterTool(&tools.ReadFile{}, &tools.RunShell{})
+	agent.initHistory("system prompt")
+
+	emit, events := collectEmitter()
+	if err := agent.RunTurn(context.Background(), "go", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+	if gate.asked != 0 {
+		t.Fatal("an invalid call reached the Approval Gate")
+	}
+
+	type result struct {
+		Status string `json:"status"`
+		Data   struct {
+			Error      string            `json:"error"`
+			Violations []tools.Violation `json:"violations"`
+		} `json:"data"`
+	}
+	var got []result
+	for _, e := range events() {
+		if e.Kind == EventToolResult {
+			var r result
+			if err := json.Unmarshal([]byte(e.ToolResult.ModelText()), &r); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, r)
+		}
+	}
+	want := [][]tools.Violation{
+		{{Field: "path", Message: "is required"}, {Field: "offset", Message: "expected integer, got string"}},
+		{{Field: "command", Message: "expected string, got integer"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("results = %+v", got)
+	}
+	for i := range want {
+		if got[i].Status != "FAILED" || !reflect.DeepEqual(got[i].Data.Violations, want[i]) {
+			t.Errorf("result %d = %+v, want violations %v", i, got[i], want[i])
+		}
+	}
+	if !strings.HasPrefix(got[0].Data.Error, "invalid arguments for read_file: path: is required; offset:") {
+		t.Errorf("error = %q", got[0].Data.Error)
+	}
+}
+
+// recordingBackend keeps the encoded body of each request.
+type recordingBackend struct {
+	scriptedBackend
+	bodies [][]byte
+}
+
+func (r *recordingBackend) CallLLMStream(ctx context.Context, req map[string]any) (inference.SSEResp, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r.bodies = append(r.bodies, body)
+	return r.scriptedBackend.CallLLMStream(ctx, req)
+}
+
+func TestRunTurn_requestsShareStablePrefix(t *testing.T) {
+	chdirWorkspace(t, t.TempDir())
+	defs, err := json.Marshal(NewAgent("", "", "test", "system").ToolDefinitions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		again, _ := json.Marshal(NewAgent("", "", "test", "system").ToolDefinitions())
+		if !bytes.Equal(defs, again) {
+			t.Fatal("tool definitions differ between agents")
+		}
+	}
+
+	backend := &recordingBackend{scriptedBackend: scriptedBackend{scripts: [][]inference.Response{
+		{{Choices: []inference.Choice{{Delta: inference.Delta{ToolCalls: []inference.ToolCall{{
+			ID: "call-1", Type: "function",
+			Function: inference.Function{Name: "list_file", Arguments: map[string]any{"path": "."}},
+		}}}}}}},
+		{{Choices: []inference.Choice{{Delta: inference.Delta{Content: "empty"}}}}},
+		{{Choices: []inference.Choice{{Delta: inference.Delta{Content: "bye"}}}}},
+	}}}
+	a := NewAgent("", "", "test", "system")
+	a.Backend = backend
+	emit, _ := collectEmitter()
+	for _, msg := range []string{"list the files", "thanks"} {
+		if err := a.RunTurn(context.Background(), msg, emit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(backend.bodies) != 3 {
+		t.Fatalf("requests = %d, want 3", len(backend.bodies))
+	}
+
+	// Apart from messages, every request is byte-identical; messages only
+	// grow at the end.
+	type request struct {
+		Messages []json.RawMessage `json:"messages"`
+		Tools    json.RawMessage   `json:"tools"`
+	}
+	var prev request
+	var prevRest map[string]json.RawMessage
+	for i, body := range backend.bodies {
+		var req request
+		var rest map[string]json.RawMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(body, &rest); err != nil {
+			t.Fatal(err)
+		}
+		delete(rest, "messages")
+		if !bytes.Equal(req.Tools, defs) {
+			t.Fatalf("request %d: tools differ from the registry's definitions", i)
+		}
+		if i > 0 {
+			if !reflect.DeepEqual(rest, prevRest) {
+				t.Fatalf("request %d differs from request %d outside messages", i, i-1)
+			}
+			if len(req.Messages) <= len(prev.Messages) {
+				t.Fatalf("request %d has %d messages, request %d had %d", i, len(req.Messages), i-1, len(prev.Messages))
+			}
+			for j, msg := range prev.Messages {
+				if !bytes.Equal(msg, req.Messages[j]) {
+					t.Fatalf("request %d: message %d changed:\n%s\n%s", i, j, msg, req.Messages[j])
+				}
+			}
+		}
+		prev, prevRest = req, rest
+	}
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const AskUserToolName = "ask_user"
+
+// maxAnswerSummary bounds the answer shown in the Summary of an ask_user
+// result.
+const maxAnswerSummary = 40
+
+// Question is what the model asks the developer with ask_user.
+type Question struct {
+	ToolCallID string
+	Text       string
+	// Choices are suggested answers; the developer may answer freely.
+	Choices []string
+}
+
+// QuestionGate puts questions to the developer. An empty answer means the
+// question was skipped and is answered with the fallback.
+type QuestionGate interface {
+	AskUser(ctx context.Context, q Question, emit EventEmitter) (answer string, err error)
+}
+
+type staticQuestionGate struct {
+	answer string
+}
+
+// NewStaticQuestionGate answers every question with answer, for runs
+// without anyone to ask. An empty answer gives the fallback.
+func NewStaticQuestionGate(answer string) QuestionGate {
+	return &staticQuestionGate{answer: answer}
+}
+
+func (g *staticQuestionGate) AskUser(_ context.Context, q Question, emit EventEmitter) (string, error) {
+	emit(Event{
+		Kind:       EventQuestion,
+		ToolCallID: q.ToolCallID,
+		Text:       q.Text,
+		Choices:    q.Choices,
+	})
+	return g.answer, nil
+}
+
+// AskUserTool pauses the Turn to ask the developer a question and returns
+// the answer.
+type AskUserTool struct {
+	agent *Agent
+}
+
+func NewAskUserTool(a *Agent) *AskUserTool {
+	return &AskUserTool{agent: a}
+}
+
+func (t *AskUserTool) Name() string { return AskUserToolName }
+
+// ReadOnly offers the tool in read-only modes; the Agent still asks one
+// question at a time.
+func (t *AskUserTool) ReadOnly(inference.ToolCall) bool { return true }
+
+func (t *AskUserTool) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name": AskUserToolName,
+			"description": "Ask the user a clarifying question and wait for the answer. Use it when the request is ambiguous and the answer changes what you would do; " +
+				"do not ask for what you can find out from the Workspace. Offer choices when there are a few likely answers; the user may still answer freely.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"question": map[string]any{
+						"type":        "string",
+						"description": "The question, self-contained and specific.",
+					},
+					"choices": map[string]any{
+						"type":        "array",
+						"description": "Suggested answers, most likely first.",
+						"items":       map[string]any{"type": "string"},
+					},
+				},
+				"required": []string{"question"},
+			},
+		},
+	}
+}
+
+func (t *AskUserTool) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	question, _ := args.Function.Arguments["question"].(string)
+	if question = strings.TrimSpace(question); question == "" {
+		return tools.Failure(errors.New("question is required"))
+	}
+	var choices []string
+	items, _ := args.Function.Arguments["choices"].([]any)
+	for _, item := range items {
+		if choice, _ := item.(string); strings.TrimSpace(choice) != "" {
+			choices = append(choices, strings.TrimSpace(choice))
+		}
+	}
+
+	answer, err := t.agent.askUser(ctx, Question{ToolCallID: args.ID, Text: question, Choices: choices}, emitter(ctx))
+	if err != nil {
+		return tools.Failure(err)
+	}
+	if strings.TrimSpace(answer) == "" {
+		return tools.Success("answer", t.agent.AskUser.FallbackAnswer(), "skipped", true).WithSummary("未回答")
+	}
+	summary := answer
+	if r := []rune(summary); len(r) > maxAnswerSummary {
+		summary = string(r[:maxAnswerSummary]) + "…"
+	}
+	return tools.Success("answer", answer).WithSummary("回答：%s", summary)
+}
+
+// askUser puts q to the QuestionGate, or to the front end through an
+// EventQuestion it answers on AnswerReplyCh.
+func (a *Agent) askUser(ctx context.Context, q Question, emit EventEmitter) (string, error) {
+	if a.QuestionGate != nil {
+		return a.QuestionGate.AskUser(ctx, q, emit)
+	}
+
+	ch := make(chan string, 1)
+	emit(Event{
+		Kind:          EventQuestion,
+		ToolCallID:    q.ToolCallID,
+		Text:          q.Text,
+		Choices:       q.Choices,
+		AnswerReplyCh: ch,
+	})
+
+	select {
+	case answer := <-ch:
+		return answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
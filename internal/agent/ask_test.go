@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func askCall(id string, args map[string]any) []inference.ToolCall {
+	return []inference.ToolCall{{ID: id, Function: inference.Function{Name: AskUserToolName, Arguments: args}}}
+}
+
+func TestAskUser_answerReturnsAsToolResult(t *testing.T) {
+	a := NewAgent("", "", "test-model", "system")
+	if err := a.SetMode(ModePlan); err != nil {
+		t.Fatal(err)
+	}
+	var asked Event
+	msgs, err := a.toolCall(context.Background(), askCall("q1", map[string]any{
+		"question": "Which database?", "choices": []any{"sqlite", " ", "postgres"},
+	}), func(e Event) {
+		if e.Kind == EventQuestion {
+			asked = e
+			e.AnswerReplyCh <- "postgres, version 16"
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asked.Text != "Which database?" || asked.ToolCallID != "q1" || !reflect.DeepEqual(asked.Choices, []string{"sqlite", "postgres"}) {
+		t.Fatalf("question event = %+v", asked)
+	}
+	if content := msgs[0]["content"].(string); !strings.Contains(content, `"answer":"postgres, version 16"`) {
+		t.Fatalf("tool message = %s", content)
+	}
+}
+
+func TestAskUser_headlessFallback(t *testing.T) {
+	a := NewAgent("", "", "test-model", "system")
+	a.QuestionGate = NewStaticQuestionGate("")
+	a.AskUser = config.AskUser{Fallback: "Pick the simplest option."}
+	var questions int
+	msgs, err := a.toolCall(context.Background(), askCall("q1", map[string]any{"question": "Which database?"}), func(e Event) {
+		if e.Kind == EventQuestion {
+			questions++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := msgs[0]["content"].(string)
+	if questions != 1 || !strings.Contains(content, "Pick the simplest option.") || !strings.Contains(content, `"skipped":true`) {
+		t.Fatalf("questions = %d, tool message = %s", questions, content)
+	}
+
+	a.QuestionGate = NewStaticQuestionGate("sqlite")
+	msgs, _ = a.toolCall(context.Background(), askCall("q2", map[string]any{"question": "Which database?"}), func(Event) {})
+	if content := msgs[0]["content"].(string); !strings.Contains(content, `"answer":"sqlite"`) {
+		t.Fatalf("tool message = %s", content)
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// maxCommitDiff bounds the diff sent to the model when drafting a commit
+// message.
+const maxCommitDiff = 24 * 1024
+
+var ErrNothingToCommit = errors.New("nothing to commit")
+
+const commitSystemPrompt = `You write git commit messages in the Conventional Commits format.
+
+The first line is "type(scope): summary" or "type: summary", where type is one of feat, fix, refactor, perf, docs, test, build, ci, style or chore. The summary is in the imperative mood, lower case, without a trailing period, and at most 72 characters including the prefix. If the change needs explaining, add a blank line and a short body, wrapped at 72 columns, saying what changed and why.
+
+Reply with the commit message only: no code fences, quotes or commentary.`
+
+// CommitDraft is a commit proposed by the /commit Slash Command.
+type CommitDraft struct {
+	// All stages every change in the working tree before committing;
+	// otherwise only the index is committed.
+	All     bool
+	Files   []git.FileChange
+	Message string
+}
+
+// DraftCommit collects the staged changes, or all changes when all is set,
+// and asks the model for a commit message. The exchange stays out of the
+// Session History.
+func (a *Agent) DraftCommit(ctx context.Context, all bool) (CommitDraft, error) {
+	repo, err := git.Open(ctx, tools.WorkspaceRoot())
+	if err != nil {
+		return CommitDraft{}, err
+	}
+	opts := git.DiffOptions{Staged: !all}
+	if !repo.HasHead(ctx) {
+		opts.Base = git.EmptyTree
+	} else if all {
+		opts.Base = "HEAD"
+	}
+
+	files, err := repo.DiffStat(ctx, opts)
+	if err != nil {
+		return CommitDraft{}, err
+	}
+	var untracked []string
+	if all {
+		st, err := repo.Status(ctx)
+		if err != nil {
+			return CommitDraft{}, err
+		}
+		for _, f := range st.Files {
+			if f.Untracked {
+				untracked = append(untracked, f.Path)
+				files = append(files, git.FileChange{Path: f.Path})
+			}
+		}
+	}
+	if len(files) == 0 {
+		return CommitDraft{}, ErrNothingToCommit
+	}
+	diff, err := repo.Diff(ctx, opts)
+	if err != nil {
+		return CommitDraft{}, err
+	}
+
+	var b strings.Builder
+	if recent, err := repo.Log(ctx, "", 5); err == nil && len(recent) > 0 {
+		b.WriteString("Recent commit subjects, for the project's conventions:\n")
+		for _, c := range recent {
+			fmt.Fprintf(&b, "- %s\n", c.Subject)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Changed files:\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s (+%d -%d)\n", f.Path, f.Added, f.Deleted)
+	}
+	if len(untracked) > 0 {
+		fmt.Fprintf(&b, "\nNew files not shown in the diff: %s\n", strings.Join(untracked, ", "))
+	}
+	fmt.Fprintf(&b, "\nDiff:\n%s", trimOutput(diff, maxCommitDiff))
+
+	reply, err := a.Complete(ctx, commitSystemPrompt, b.String())
+	if err != nil {
+		return CommitDraft{}, err
+	}
+	return CommitDraft{All: all, Files: files, Message: stripFence(reply)}, nil
+}
+
+// CommitDraft records the draft's changes with message, which the developer
+// may have edited.
+func (a *Agent) CommitDraft(ctx context.Context, draft CommitDraft, message string) (git.Commit, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return git.Commit{}, errors.New("commit message is empty")
+	}
+	repo, err := git.Open(ctx, tools.WorkspaceRoot())
+	if err != nil {
+		return git.Commit{}, err
+	}
+	if draft.All {
+		if err := repo.AddAll(ctx); err != nil {
+			return git.Commit{}, err
+		}
+	}
+	if staged, err := repo.HasStagedChanges(ctx); err != nil {
+		return git.Commit{}, err
+	} else if !staged {
+		return git.Commit{}, ErrNothingToCommit
+	}
+	return repo.Commit(ctx, message+"\n")
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// requestRecorder remembers the requests it passes to the scripted backend.
+type requestRecorder struct {
+	scriptedBackend
+	requests []map[string]any
+}
+
+func (r *requestRecorder) CallLLMStream(ctx context.Context, req map[string]any) (inference.SSEResp, error) {
+	r.requests = append(r.requests, req)
+	return r.scriptedBackend.CallLLMStream(ctx, req)
+}
+
+// setupCommitRepo makes the Workspace a repository with one commit.
+func setupCommitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Dev")
+	t.Setenv("GIT_AUTHOR_EMAIL", "dev@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Dev")
+	t.Setenv("GIT_COMMITTER_EMAIL", "dev@example.com")
+
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, dir, "init", "-q", "-b", "main")
+	gitCmd(t, dir, "add", "a.txt")
+	gitCmd(t, dir, "commit", "-q", "-m", "chore: start")
+	return dir
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func newCommitAgent(reply string) (*Agent, *requestRecorder) {
+	backend := &requestRecorder{scriptedBackend: scriptedBackend{
+		scripts: [][]inference.Response{answerScript(reply)},
+	}}
+	a := &Agent{Backend: backend, Model: "test-model", Tools: tools.NewRegistry()}
+	a.initHistory("system prompt")
+	return a, backend
+}
+
+func TestDraftCommit_stagedDiffOutsideHistory(t *testing.T) {
+	dir := setupCommitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, dir, "add", "a.txt")
+	if err := os.WriteFile(filepath.Join(dir, "unstaged.txt"), []byte("u\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, backend := newCommitAgent("```\nfeat: add two\n```")
+	draft, err := a.DraftCommit(context.Background(), false)
+	if err != nil {
+		t.Fatalf("DraftCommit: %v", err)
+	}
+	if draft.Message != "feat: add two" || len(draft.Files) != 1 || draft.Files[0].Path != "a.txt" {
+		t.Fatalf("draft = %+v", draft)
+	}
+	if len(a.History) != 1 {
+		t.Fatalf("History grew to %d messages", len(a.History))
+	}
+	req := backend.requests[0]
+	if _, ok := req["tools"]; ok {
+		t.Fatal("commit drafting offered tools")
+	}
+	user := req["messages"].([]map[string]any)[1]["content"].(string)
+	if !strings.Contains(user, "+two") || strings.Contains(user, "unstaged.txt") || !strings.Contains(user, "chore: start") {
+		t.Fatalf("prompt = %q", user)
+	}
+
+	commit, err := a.CommitDraft(context.Background(), draft, "feat: add two\n\nEdited.")
+	if err != nil {
+		t.Fatalf("CommitDraft: %v", err)
+	}
+	if commit.Subject != "feat: add two" || commit.Body != "Edited." {
+		t.Fatalf("commit = %+v", commit)
+	}
+	if status := gitCmd(t, dir, "status", "--porcelain"); status != "?? unstaged.txt\n" {
+		t.Fatalf("status after commit = %q", status)
+	}
+}
+
+func TestDraftCommit_allIncludesUnstagedAndNewFiles(t *testing.T) {
+	dir := setupCommitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := newCommitAgent("fix: change a")
+	draft, err := a.DraftCommit(context.Background(), true)
+	if err != nil {
+		t.Fatalf("DraftCommit: %v", err)
+	}
+	if len(draft.Files) != 2 {
+		t.Fatalf("files = %+v", draft.Files)
+	}
+	commit, err := a.CommitDraft(context.Background(), draft, draft.Message)
+	if err != nil {
+		t.Fatalf("CommitDraft: %v", err)
+	}
+	if commit.Hash == "" || gitCmd(t, dir, "status", "--porcelain") != "" {
+		t.Fatalf("commit = %+v, status = %q", commit, gitCmd(t, dir, "status", "--porcelain"))
+	}
+}
+
+func TestDraftCommit_nothingStaged(t *testing.T) {
+	setupCommitRepo(t)
+	a, backend := newCommitAgent("unused")
+	if _, err := a.DraftCommit(context.Background(), false); !errors.Is(err, ErrNothingToCommit) {
+		t.Fatalf("DraftCommit = %v, want ErrNothingToCommit", err)
+	}
+	if len(backend.requests) != 0 {
+		t.Fatal("model was asked without changes")
+	}
+}
+
+func TestStripFence(t *testing.T) {
+	for in, want := range map[string]string{
+		"feat: x":                   "feat: x",
+		"```\nfeat: x\n```":         "feat: x",
+		"```text\nfix: y\n\nb\n```": "fix: y\n\nb",
+	} {
+		if got := stripFence(in); got != want {
+			t.Errorf("stripFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var errEmptyCompletion = errors.New("the model returned an empty reply")
+
+// Complete asks the Inference Backend for a single reply to system and user
+// without tools. It neither reads nor changes the Session History, so Slash
+// Commands can use the model without the Turn seeing it.
+func (a *Agent) Complete(ctx context.Context, system, user string) (string, error) {
+	req := map[string]any{
+		"model": a.ActiveModel(),
+		"messages": []map[string]any{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"stream": true,
+	}
+	ch, err := a.Backend.CallLLMStream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for msg := range ch {
+		if len(msg.Choices) > 0 {
+			b.WriteString(msg.Choices[0].Delta.Content)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	reply := strings.TrimSpace(b.String())
+	if reply == "" {
+		return "", errEmptyCompletion
+	}
+	return reply, nil
+}
+
+// stripFence removes a Markdown code fence wrapped around the whole reply.
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if _, rest, ok := strings.Cut(s, "\n"); ok {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(s, "```"))
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const (
+	DelegateToolName = "delegate_task"
+	// maxConcurrentTasks bounds how many delegated tasks of one round run at
+	// the same time.
+	maxConcurrentTasks = 4
+	maxTaskReport      = 16 * 1024
+)
+
+const delegateSystemPrompt = `You are a sub-agent carrying out one task for another coding agent. You cannot talk to the user, and you cannot modify files.
+
+Workspace: %s
+
+Use the tools to investigate, then finish with a concise, self-contained report. The delegating agent sees nothing but that report, so include concrete file paths, line numbers and names rather than references to your earlier tool output.`
+
+var errEmptyReport = errors.New("the delegated task finished without a report")
+
+// DelegateTool runs a task in a child Agent with its own History and
+// returns only the child's final report, keeping the intermediate tool
+// results out of the parent's History.
+type DelegateTool struct {
+	parent *Agent
+	slots  chan struct{}
+}
+
+func NewDelegateTool(parent *Agent) *DelegateTool {
+	return &DelegateTool{parent: parent, slots: make(chan struct{}, maxConcurrentTasks)}
+}
+
+func (d *DelegateTool) Name() string { return DelegateToolName }
+
+// ReadOnly lets delegations of one round run concurrently; the sub-agents
+// only get read-only tools.
+func (d *DelegateTool) ReadOnly(inference.ToolCall) bool { return true }
+
+func (d *DelegateTool) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name": DelegateToolName,
+			"description": "Delegate a self-contained, read-only task to a sub-agent with a fresh context, such as \"find every caller of X and summarize how each uses it\". " +
+				"The sub-agent can read, search and inspect the Workspace but not modify it, and returns only its final report, so large explorations do not fill your context. " +
+				"Several delegate_task calls in one response run concurrently.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"task": map[string]any{
+						"type":        "string",
+						"description": "Complete instructions for the sub-agent, including what its report must contain. It cannot see this conversation.",
+					},
+					"description": map[string]any{
+						"type":        "string",
+						"description": "A short label for the task, shown to the user.",
+					},
+				},
+				"required": []string{"task"},
+			},
+		},
+	}
+}
+
+func (d *DelegateTool) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	task, _ := args.Function.Arguments["task"].(string)
+	if strings.TrimSpace(task) == "" {
+		return tools.Failure(errors.New("task is required"))
+	}
+	select {
+	case d.slots <- struct{}{}:
+		defer func() { <-d.slots }()
+	case <-ctx.Done():
+		return tools.Failure(ctx.Err())
+	}
+	report, err := d.parent.Delegate(ctx, task, taskEmitter(ctx, args.ID))
+	if err != nil {
+		return tools.Failure(err)
+	}
+	return tools.Success("report", report)
+}
+
+// Delegate runs task to completion in a child Agent and returns its final
+// answer. The child gets the tools that declare read-only calls, so
+// concurrent tasks cannot step on each other; their gated calls, such as
+// git commit, are refused, and the files it reads do not count as read by
+// the parent. Events of the child are passed to emit.
+func (a *Agent) Delegate(ctx context.Context, task string, emit EventEmitter) (string, error) {
+	child := &Agent{
+		Backend:        a.Backend,
+		Model:          a.Model,
+		Tools:          tools.NewRegistry(),
+		ApprovalPolicy: make(map[string]config.ApprovalRule),
+		Results:        a.Results,
+	}
+	for _, tool := range a.Tools.Tools() {
+		if _, ok := tool.(tools.ReadOnlyTool); !ok || tool.Name() == DelegateToolName || tool.Name() == AskUserToolName {
+			continue
+		}
+		child.RegisterTool(tool)
+		if _, gated := tool.(tools.GatedTool); gated {
+			child.ApprovalPolicy[tool.Name()] = config.ApprovalDeny
+		}
+	}
+	child.initHistory(fmt.Sprintf(delegateSystemPrompt, tools.WorkspaceRoot()))
+
+	var report string
+	err := child.RunTurn(tools.WithUntrackedReads(ctx), task, func(e Event) {
+		if e.Kind == EventTurnComplete {
+			report = e.AssistantMessage
+		}
+		emit(e)
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(report) == "" {
+		return "", errEmptyReport
+	}
+	return trimOutput(report, maxTaskReport), nil
+}
+
+type emitterKey struct{}
+
+// withEmitter lets tools that report progress, such as DelegateTool, reach
+// the Turn's EventEmitter. Concurrent calls get an emitter that is safe to
+// call from several goroutines.
+func withEmitter(ctx context.Context, emit EventEmitter) context.Context {
+	return context.WithValue(ctx, emitterKey{}, emit)
+}
+
+// emitter returns the Turn's EventEmitter, or one that drops events.
+func emitter(ctx context.Context) EventEmitter {
+	if emit, ok := ctx.Value(emitterKey{}).(EventEmitter); ok && emit != nil {
+		return emit
+	}
+	return func(Event) {}
+}
+
+// taskEmitter returns an emitter that tags events with the tool call that
+// delegated the task and forwards them to the Turn's emitter.
+func taskEmitter(ctx context.Context, taskID string) EventEmitter {
+	emit := emitter(ctx)
+	return func(e Event) {
+		if e.TaskID == "" {
+			e.TaskID = taskID
+		}
+		emit(e)
+	}
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// delegateBackend plays both sides: the parent delegates one task per file,
+// and each sub-agent reads its file and reports it. Sub-agents wait for each
+// other before their first reply, so the test fails unless they run at the
+// same time.
+type delegateBackend struct {
+	files []string
+
+	mu      sync.Mutex
+	arrived int
+	ready   chan struct{}
+}
+
+func (b *delegateBackend) CallLLMStream(_ context.Context, req map[string]any) (inference.SSEResp, error) {
+	messages := req["messages"].([]map[string]any)
+	last := messages[len(messages)-1]
+	var script []inference.Response
+	switch {
+	case !strings.HasPrefix(messages[0]["content"].(string), "You are a sub-agent"):
+		if last["role"] == "user" {
+			var calls []inference.ToolCall
+			for _, f := range b.files {
+				calls = append(calls, inference.ToolCall{ID: "task-" + f, Type: "function", Function: inference.Function{
+					Name: DelegateToolName, Arguments: map[string]any{"task": f, "description": "read " + f},
+				}})
+			}
+			script = []inference.Response{{Choices: []inference.Choice{{Delta: inference.Delta{ToolCalls: calls}}}}}
+		} else {
+			script = answerScript("all done")
+		}
+	case last["role"] == "user":
+		b.mu.Lock()
+		b.arrived++
+		if b.arrived == len(b.files) {
+			close(b.ready)
+		}
+		b.mu.Unlock()
+		select {
+		case <-b.ready:
+		case <-time.After(2 * time.Second):
+			return nil, context.DeadlineExceeded
+		}
+		task := last["content"].(string)
+		script = []inference.Response{{Choices: []inference.Choice{{Delta: inference.Delta{ToolCalls: []inference.ToolCall{{
+			ID: "read-" + task, Type: "function",
+			Function: inference.Function{Name: "read_file", Arguments: map[string]any{"path": task}},
+		}}}}}}}
+	default:
+		script = answerScript("report: " + last["content"].(string))
+	}
+	ch := make(inference.SSEResp, len(script))
+	for _, r := range script {
+		ch <- r
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestDelegate_concurrentTasksReturnOnlyReports(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	tools.ResetSession()
+	for _, f := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("contents of "+f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend := &delegateBackend{files: []string{"a.txt", "b.txt"}, ready: make(chan struct{})}
+	a := &Agent{Backend: backend, Model: "test-model", Tools: tools.NewRegistry()}
+	a.RegisterTool(&tools.ReadFile{}, &tools.WriteFile{}, NewDelegateTool(a))
+	a.initHistory("system prompt")
+
+	var mu sync.Mutex
+	var events []Event
+	err := a.RunTurn(context.Background(), "summarize both files", func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// system, user, assistant tool calls, two reports, answer.
+	if len(a.History) != 6 {
+		t.Fatalf("History has %d messages, want 6: %v", len(a.History), a.History)
+	}
+	for i, f := range []string{"a.txt", "b.txt"} {
+		msg := a.History[3+i]
+		content := msg["content"].(string)
+		if msg["tool_call_id"] != "task-"+f || !strings.Contains(content, "report: ") || !strings.Contains(content, "contents of "+f) {
+			t.Fatalf("History[%d] = %v", 3+i, msg)
+		}
+	}
+
+	var parentCalls, childCalls []string
+	for _, e := range events {
+		if e.Kind != EventToolCall {
+			continue
+		}
+		if e.TaskID == "" {
+			parentCalls = append(parentCalls, e.ToolCallID)
+		} else {
+			childCalls = append(childCalls, e.TaskID+">"+e.ToolName)
+		}
+	}
+	if strings.Join(parentCalls, ",") != "task-a.txt,task-b.txt" || len(childCalls) != 2 {
+		t.Fatalf("parent calls = %v, child calls = %v", parentCalls, childCalls)
+	}
+
+	// The sub-agents' reads do not count as the parent's.
+	tools.SetWritePolicy(tools.WritePolicy{NewFiles: tools.WriteAllow, UnreadFiles: tools.WriteDeny})
+	t.Cleanup(func() { tools.SetWritePolicy(tools.DefaultWritePolicy) })
+	res := (&tools.WriteFile{}).Call(context.Background(), inference.ToolCall{Function: inference.Function{
+		Name: "write_file", Arguments: map[string]any{"path": "a.txt", "content": "x"},
+	}})
+	if !strings.Contains(res.Error(), tools.ErrUnreadFile.Error()) {
+		t.Fatalf("write after a sub-agent read = %+v", res)
+	}
+}
+
+func TestDelegate_restrictsTools(t *testing.T) {
+	a := NewAgent("", "", "test", "system")
+	var seen []string
+	a.Backend = backendFunc(func(req map[string]any) {
+		for _, def := range req["tools"].([]map[string]any) {
+			seen = append(seen, def["function"].(map[string]any)["name"].(string))
+		}
+	})
+	if _, err := a.Delegate(context.Background(), "look around", func(Event) {}); err != errEmptyReport {
+		t.Fatalf("Delegate err = %v, want errEmptyReport", err)
+	}
+	names := strings.Join(seen, ",")
+	for _, banned := range []string{"write_file", "run_shell", "delete_path", DelegateToolName} {
+		if strings.Contains(names, banned) {
+			t.Fatalf("sub-agent was offered %s: %s", banned, names)
+		}
+	}
+	if !strings.Contains(names, "read_file") || !strings.Contains(names, "git") {
+		t.Fatalf("sub-agent tools = %s", names)
+	}
+}
+
+// backendFunc inspects each request and replies with nothing.
+type backendFunc func(req map[string]any)
+
+func (f backendFunc) CallLLMStream(_ context.Context, req map[string]any) (inference.SSEResp, error) {
+	f(req)
+	ch := make(inference.SSEResp)
+	close(ch)
+	return ch, nil
+}
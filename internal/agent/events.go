@@ -1,6 +1,9 @@
 package agent
 
-import "github.com/loveRyujin/mini-agent/internal/inference"
+import (
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
 
 type EventKind int
 
@@ -13,6 +16,11 @@ const (
 	EventTurnComplete
 	EventUsage
 	EventError
+	EventVerifyStart
+	EventVerifyResult
+	// EventQuestion carries a question of ask_user in Text, with its
+	// Choices.
+	EventQuestion
 )
 
 type Event struct {
@@ -20,13 +28,26 @@ type Event struct {
 
 	Text             string
 	Command          string
+	ToolCallID       string
 	ToolName         string
 	ToolArguments    map[string]any
-	ToolContent      string
+	ToolResult       tools.ToolResult
 	AssistantMessage string
 	Usage            inference.Usage
 	Err              error
 	ApprovalReplyCh  chan<- bool
+	Choices          []string
+	// AnswerReplyCh takes the answer to an EventQuestion; an empty answer
+	// skips the question.
+	AnswerReplyCh chan<- string
+
+	// Attempt numbers verification runs within a Turn, starting at 1.
+	Attempt  int
+	ExitCode int
+
+	// TaskID is the ToolCallID of the delegate_task call an event of a
+	// delegated task belongs to; empty for the main Agent's own events.
+	TaskID string
 }
 
 type EventEmitter func(Event)
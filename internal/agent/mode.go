@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// Names of the built-in modes.
+const (
+	ModeCode = "code"
+	ModePlan = "plan"
+	ModeAsk  = "ask"
+)
+
+// Mode is a named way of working: which tools the Agent is offered, how it
+// is prompted and which model answers.
+type Mode struct {
+	Name string
+	// Description is shown to the developer when switching.
+	Description string
+	// Prompt is appended to the System Prompt.
+	Prompt string
+	// Tools lists the tool names the mode offers; nil offers every tool.
+	Tools []string
+	// ReadOnly offers only ReadOnlyTool tools and refuses their calls that
+	// write.
+	ReadOnly bool
+	// ApprovalPolicy takes precedence over the Agent's ApprovalPolicy.
+	ApprovalPolicy map[string]config.ApprovalRule
+	// Model overrides the Agent's Model.
+	Model string
+	// ApproveTo names the mode that executes an answer of this mode once
+	// the developer approves it as a plan.
+	ApproveTo string
+}
+
+const planModePrompt = `You are in plan mode. Investigate the workspace with the read-only tools you are offered; do not modify files or run commands that change anything. End your answer with a concrete, numbered plan: the files to change, what to change in each, and how to verify the result. The developer reviews the plan and, once approved, it is executed in code mode.`
+
+const askModePrompt = `You are in ask mode. Answer the developer's questions about the code base, using the read-only tools you are offered to ground your answers in the actual code. Do not propose edits unless asked.`
+
+// PlanApprovedRequest starts executing a plan the developer approved.
+const PlanApprovedRequest = "The plan above is approved. Carry it out step by step, then verify the result."
+
+// DefaultModes returns the built-in modes, code first.
+func DefaultModes() []Mode {
+	return []Mode{
+		{Name: ModeCode, Description: "读写文件、运行命令，完整执行任务"},
+		{Name: ModePlan, Description: "只读调研，最后给出计划，批准后切换到 code 模式执行", Prompt: planModePrompt, ReadOnly: true, ApproveTo: ModeCode},
+		{Name: ModeAsk, Description: "只读问答，不改动 Workspace", Prompt: askModePrompt, ReadOnly: true},
+	}
+}
+
+// ConfigureModes layers the modes of the configuration file over
+// DefaultModes. Modes that are not built in follow them in name order.
+func ConfigureModes(cfg map[string]config.Mode) []Mode {
+	modes := DefaultModes()
+	names := make([]string, 0, len(cfg))
+	for name := range cfg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := cfg[name]
+		i := slices.IndexFunc(modes, func(m Mode) bool { return m.Name == name })
+		if i < 0 {
+			modes = append(modes, Mode{Name: name})
+			i = len(modes) - 1
+		}
+		m := &modes[i]
+		if c.Description != "" {
+			m.Description = c.Description
+		}
+		if c.Prompt != "" {
+			m.Prompt = c.Prompt
+		}
+		if c.Tools != nil {
+			m.Tools = c.Tools
+		}
+		if c.ReadOnly != nil {
+			m.ReadOnly = *c.ReadOnly
+		}
+		if c.Approval != nil {
+			m.ApprovalPolicy = c.Approval
+		}
+		if c.Model != "" {
+			m.Model = c.Model
+		}
+	}
+	return modes
+}
+
+// Mode returns the active mode; an Agent without modes is in an
+// unrestricted mode with no name.
+func (a *Agent) Mode() Mode {
+	for _, m := range a.Modes {
+		if m.Name == a.mode {
+			return m
+		}
+	}
+	return Mode{}
+}
+
+// SetMode switches to the named mode and updates the System Prompt of the
+// Session for it.
+func (a *Agent) SetMode(name string) error {
+	if !slices.ContainsFunc(a.Modes, func(m Mode) bool { return m.Name == name }) {
+		names := make([]string, len(a.Modes))
+		for i, m := range a.Modes {
+			names[i] = m.Name
+		}
+		return fmt.Errorf("unknown mode %q; available: %s", name, strings.Join(names, ", "))
+	}
+	a.mode = name
+	if len(a.History) > 0 && a.History[0]["role"] == "system" {
+		a.History[0] = a.systemMessage()
+	}
+	return nil
+}
+
+// NextMode returns the mode that follows the active one, wrapping around.
+func (a *Agent) NextMode() string {
+	if len(a.Modes) == 0 {
+		return ""
+	}
+	i := slices.IndexFunc(a.Modes, func(m Mode) bool { return m.Name == a.mode })
+	return a.Modes[(i+1)%len(a.Modes)].Name
+}
+
+// ActiveModel is the model that answers in the active mode.
+func (a *Agent) ActiveModel() string {
+	if m := a.Mode().Model; m != "" {
+		return m
+	}
+	return a.Model
+}
+
+// offers reports whether the active mode offers tool.
+func (m Mode) offers(tool tools.Tool) bool {
+	if m.Tools != nil && !slices.Contains(m.Tools, tool.Name()) {
+		return false
+	}
+	_, readOnly := tool.(tools.ReadOnlyTool)
+	return !m.ReadOnly || readOnly
+}
+
+// allows returns why the active mode refuses tc, or nil.
+func (m Mode) allows(tool tools.Tool, tc inference.ToolCall) error {
+	switch {
+	case !m.offers(tool):
+		return fmt.Errorf("%s is not available in %s mode", tc.Function.Name, m.Name)
+	case m.ReadOnly && !tools.IsReadOnly(tool, tc):
+		return fmt.Errorf("%s mode is read-only; this call of %s would modify the workspace", m.Name, tc.Function.Name)
+	}
+	return nil
+}
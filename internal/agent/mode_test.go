@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+func toolNames(defs []map[string]any) []string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def["function"].(map[string]any)["name"].(string)
+	}
+	return names
+}
+
+func TestSetMode_filtersToolsAndPrompt(t *testing.T) {
+	a := NewAgent("", "", "test-model", "system")
+	if a.Mode().Name != ModeCode || len(a.ToolDefinitions()) != a.Tools.Len() {
+		t.Fatalf("code mode offers %d of %d tools", len(a.ToolDefinitions()), a.Tools.Len())
+	}
+
+	if err := a.SetMode(ModePlan); err != nil {
+		t.Fatal(err)
+	}
+	names := strings.Join(toolNames(a.ToolDefinitions()), ",")
+	for _, banned := range []string{"write_file", "run_shell", "delete_path", "make_dir"} {
+		if strings.Contains(names, banned) {
+			t.Fatalf("plan mode offers %s: %s", banned, names)
+		}
+	}
+	if !strings.Contains(names, "read_file") || !strings.Contains(names, "git") {
+		t.Fatalf("plan mode tools = %s", names)
+	}
+	if content := a.History[0]["content"].(string); !strings.HasPrefix(content, "system\n\n") || !strings.Contains(content, "plan mode") {
+		t.Fatalf("system prompt = %q", content)
+	}
+
+	if err := a.SetMode("debug"); err == nil || a.Mode().Name != ModePlan {
+		t.Fatalf("SetMode(debug) = %v, mode %s", err, a.Mode().Name)
+	}
+	if err := a.SetMode(ModeCode); err != nil || a.History[0]["content"] != "system" {
+		t.Fatalf("back in code mode: %v, %q", err, a.History[0]["content"])
+	}
+	if a.NextMode() != ModePlan {
+		t.Fatalf("NextMode = %s", a.NextMode())
+	}
+}
+
+func TestToolCall_readOnlyModeRefusesWrites(t *testing.T) {
+	chdirWorkspace(t, t.TempDir())
+	a := NewAgent("", "", "test-model", "system")
+	a.ApprovalGate = NewStaticApprovalGate(true)
+	if err := a.SetMode(ModeAsk); err != nil {
+		t.Fatal(err)
+	}
+	calls := []inference.ToolCall{
+		{ID: "write", Function: inference.Function{Name: "write_file", Arguments: map[string]any{"path": "a.txt", "content": "x"}}},
+		{ID: "commit", Function: inference.Function{Name: "git", Arguments: map[string]any{"subcommand": "commit", "message": "x"}}},
+		{ID: "list", Function: inference.Function{Name: "list_file", Arguments: map[string]any{}}},
+	}
+	msgs, err := a.toolCall(context.Background(), calls, func(Event) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"not available in ask mode", "ask mode is read-only", `"status":"SUCCESS"`} {
+		if content := msgs[i]["content"].(string); !strings.Contains(content, want) {
+			t.Errorf("%s: %s, want %q", calls[i].ID, content, want)
+		}
+	}
+}
+
+func TestConfigureModes(t *testing.T) {
+	readOnly := false
+	modes := ConfigureModes(map[string]config.Mode{
+		"plan":   {Model: "big-model", Approval: map[string]config.ApprovalRule{"go": config.ApprovalAllow}},
+		"review": {Description: "只看 diff", Tools: []string{"git", "read_file"}, ReadOnly: &readOnly},
+	})
+	var names []string
+	for _, m := range modes {
+		names = append(names, m.Name)
+	}
+	if !reflect.DeepEqual(names, []string{ModeCode, ModePlan, ModeAsk, "review"}) {
+		t.Fatalf("modes = %v", names)
+	}
+	if plan := modes[1]; plan.Model != "big-model" || !plan.ReadOnly || plan.Prompt != planModePrompt || plan.ApprovalPolicy["go"] != config.ApprovalAllow {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	a := &Agent{Model: "test-model", Tools: tools.NewRegistry(&tools.ReadFile{}, &tools.WriteFile{}, &tools.GitTool{}), Modes: modes}
+	a.initHistory("system")
+	if err := a.SetMode(ModePlan); err != nil || a.request()["model"] != "big-model" {
+		t.Fatalf("plan model = %v, %v", a.request()["model"], err)
+	}
+	if err := a.SetMode("review"); err != nil {
+		t.Fatal(err)
+	}
+	if got := toolNames(a.ToolDefinitions()); !reflect.DeepEqual(got, []string{"read_file", "git"}) || a.ActiveModel() != "test-model" {
+		t.Fatalf("review tools = %v, model = %s", got, a.ActiveModel())
+	}
+}
+
+func TestComplete_usesActiveModel(t *testing.T) {
+	backend := &recordingBackend{scriptedBackend: scriptedBackend{scripts: [][]inference.Response{
+		{{Choices: []inference.Choice{{Delta: inference.Delta{Content: "ok"}}}}},
+	}}}
+	a := &Agent{Model: "test-model", Backend: backend, Tools: tools.NewRegistry(),
+		Modes: ConfigureModes(map[string]config.Mode{"plan": {Model: "big-model"}})}
+	a.initHistory("system")
+	if err := a.SetMode(ModePlan); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Complete(context.Background(), "system", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(backend.bodies[0], []byte(`"model":"big-model"`)) {
+		t.Fatalf("request = %s", backend.bodies[0])
+	}
+}
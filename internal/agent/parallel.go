@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// maxConcurrentCalls bounds how many tool calls of one round run at the
+// same time.
+const maxConcurrentCalls = 8
+
+// concurrent reports whether tc may run alongside other calls of its round:
+// the active mode allows it, it only reads and it does not wait for the
+// Approval Gate or the developer's answer.
+func (a *Agent) concurrent(tc inference.ToolCall) bool {
+	tool, ok := a.Tools.Get(tc.Function.Name)
+	return ok && a.Mode().allows(tool, tc) == nil && tools.IsReadOnly(tool, tc) && !tools.NeedsApproval(tool, tc) &&
+		tc.Function.Name != AskUserToolName
+}
+
+// delegates reports whether tc delegates a task to a sub-agent.
+func (a *Agent) delegates(tc inference.ToolCall) bool {
+	tool, _ := a.Tools.Get(tc.Function.Name)
+	_, ok := tool.(*DelegateTool)
+	return ok
+}
+
+// runConcurrent runs calls in parallel and returns their results in call
+// order. Each call's events are emitted as an adjacent EventToolCall and
+// EventToolResult pair, in call order, once all calls finished. Delegated
+// tasks are the exception: their EventToolCall comes first, so the
+// Transcript can nest the task's progress under it. Results are fitted to
+// the round's remaining budget in call order.
+func (a *Agent) runConcurrent(ctx context.Context, calls []inference.ToolCall, remaining *int, emit EventEmitter) []tools.ToolResult {
+	var mu sync.Mutex
+	serial := func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(e)
+	}
+	callEvent := func(tc inference.ToolCall) Event {
+		return Event{Kind: EventToolCall, ToolCallID: tc.ID, ToolName: tc.Function.Name, ToolArguments: tc.Function.Arguments}
+	}
+	for _, tc := range calls {
+		if a.delegates(tc) {
+			serial(callEvent(tc))
+		}
+	}
+
+	results := make([]tools.ToolResult, len(calls))
+	sem := make(chan struct{}, maxConcurrentCalls)
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			tool, _ := a.Tools.Get(tc.Function.Name)
+			if err := tools.ValidateArguments(tool, tc.Function.Arguments); err != nil {
+				results[i] = tools.Failure(err)
+				return
+			}
+			results[i] = tool.Call(withEmitter(ctx, serial), tc)
+		}()
+	}
+	wg.Wait()
+
+	for i, tc := range calls {
+		if !a.delegates(tc) {
+			emit(callEvent(tc))
+		}
+		results[i] = a.fitResult(tc.Function.Name, results[i], remaining)
+		emit(Event{Kind: EventToolResult, ToolCallID: tc.ID, ToolName: tc.Function.Name, ToolResult: results[i]})
+	}
+	return results
+}
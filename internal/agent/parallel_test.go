@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// barrierTool blocks each call until want calls are running at once, so a
+// test only passes if the calls overlap. Calls with "write" set claim to
+// mutate and must run alone.
+type barrierTool struct {
+	want int
+
+	mu      sync.Mutex
+	running int
+	order   []string
+	release chan struct{}
+}
+
+func (b *barrierTool) Name() string { return "probe" }
+
+func (b *barrierTool) Definition() map[string]any {
+	return map[string]any{"type": "function", "function": map[string]any{"name": "probe"}}
+}
+
+func (b *barrierTool) ReadOnly(args inference.ToolCall) bool {
+	return args.Function.Arguments["write"] != true
+}
+
+func (b *barrierTool) Call(_ context.Context, args inference.ToolCall) tools.ToolResult {
+	b.mu.Lock()
+	b.order = append(b.order, args.ID)
+	if !b.ReadOnly(args) {
+		concurrent := b.running
+		b.mu.Unlock()
+		if concurrent > 0 {
+			return tools.Failure(errors.New("write ran alongside reads"))
+		}
+		return tools.Success("id", args.ID)
+	}
+	b.running++
+	if b.running == b.want {
+		close(b.release)
+	}
+	release := b.release
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}()
+	select {
+	case <-release:
+		return tools.Success("id", args.ID)
+	case <-time.After(2 * time.Second):
+		return tools.Failure(errors.New("calls did not run concurrently"))
+	}
+}
+
+func probeCall(id string, write bool) inference.ToolCall {
+	return inference.ToolCall{ID: id, Type: "function", Function: inference.Function{
+		Name: "probe", Arguments: map[string]any{"write": write},
+	}}
+}
+
+func TestToolCall_readOnlyCallsRunConcurrently(t *testing.T) {
+	probe := &barrierTool{want: 3, release: make(chan struct{})}
+	a := &Agent{Model: "test-model", Tools: tools.NewRegistry()}
+	a.RegisterTool(probe)
+
+	calls := []inference.ToolCall{
+		probeCall("r1", false), probeCall("r2", false), probeCall("r3", false),
+		probeCall("w1", true),
+		probeCall("r4", false),
+	}
+	var mu sync.Mutex
+	var events []Event
+	// r4 runs alone after the write, so it needs its own barrier.
+	emit := func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+		if e.Kind == EventToolResult && e.ToolCallID == "w1" {
+			probe.mu.Lock()
+			probe.want, probe.release = 1, make(chan struct{})
+			probe.mu.Unlock()
+		}
+	}
+	results, err := a.toolCall(context.Background(), calls, emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r["tool_call_id"].(string))
+		if content := r["content"].(string); content != `{"status":"SUCCESS","data":{"id":"`+r["tool_call_id"].(string)+`"}}` {
+			t.Fatalf("result = %s", content)
+		}
+	}
+	if want := []string{"r1", "r2", "r3", "w1", "r4"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("result order = %v, want %v", ids, want)
+	}
+	if len(events) != 2*len(calls) {
+		t.Fatalf("events = %d", len(events))
+	}
+	for i := 0; i < len(events); i += 2 {
+		call, result := events[i], events[i+1]
+		if call.Kind != EventToolCall || result.Kind != EventToolResult || call.ToolCallID != result.ToolCallID || call.ToolCallID != ids[i/2] {
+			t.Fatalf("events %d,%d are not a pair for %s: %+v %+v", i, i+1, ids[i/2], call, result)
+		}
+	}
+	if probe.order[3] != "w1" || probe.order[4] != "r4" {
+		t.Fatalf("call order = %v", probe.order)
+	}
+}
+
+// bulkyTool returns a result of the requested size.
+type bulkyTool struct{}
+
+func (bulkyTool) Name() string { return "bulky" }
+
+func (bulkyTool) Definition() map[string]any {
+	return map[string]any{"type": "function", "function": map[string]any{"name": "bulky"}}
+}
+
+func (bulkyTool) ReadOnly(inference.ToolCall) bool { return true }
+
+func (bulkyTool) Call(_ context.Context, args inference.ToolCall) tools.ToolResult {
+	size, _ := args.Function.Arguments["size"].(float64)
+	return tools.ToolResult{Status: tools.StatusSuccess, Text: strings.Repeat("x", int(size))}
+}
+
+func TestToolCall_resultBudget(t *testing.T) {
+	t.Cleanup(tools.ResetSession)
+	a := &Agent{Model: "test-model", Tools: tools.NewRegistry()}
+	a.RegisterTool(bulkyTool{}, &tools.ReadArtifact{})
+	a.Results = config.ResultBudget{MaxBytes: 8000, RoundMaxBytes: 10000}
+
+	call := func(id string, size int) inference.ToolCall {
+		return inference.ToolCall{ID: id, Function: inference.Function{Name: "bulky", Arguments: map[string]any{"size": float64(size)}}}
+	}
+	emit, events := collectEmitter()
+	results, err := a.toolCall(context.Background(), []inference.ToolCall{
+		call("small", 100), call("big", 20000), call("late", 6000),
+	}, emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := func(i int) string { return results[i]["content"].(string) }
+
+	if len(content(0)) != 100 {
+		t.Fatalf("small result = %d bytes, want it untouched", len(content(0)))
+	}
+	// big is capped by its own budget, late by what is left of the round's
+	// budget, but never below minResultBytes.
+	if n := len(content(1)); n > 8000 || !strings.Contains(content(1), "artifact-1") {
+		t.Fatalf("big result = %d bytes: %.200s", n, content(1))
+	}
+	if n := len(content(2)); n > minResultBytes || !strings.Contains(content(2), "artifact-2") {
+		t.Fatalf("late result = %d bytes: %.200s", n, content(2))
+	}
+	if got := events()[3].ToolResult.Summary; got != "已截断，完整结果见 artifact-1" {
+		t.Fatalf("summary of big = %q", got)
+	}
+
+	page := (&tools.ReadArtifact{}).Call(context.Background(), inference.ToolCall{Function: inference.Function{
+		Name: tools.ReadArtifactName, Arguments: map[string]any{"artifact_id": "artifact-1", "offset": 19990.0},
+	}})
+	if page.Failed() || page.Data["content"] != "xxxxxxxxxx" {
+		t.Fatalf("read_artifact = %+v", page)
+	}
+}
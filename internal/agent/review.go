@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const (
+	// maxReviewChunk bounds the diff text of one review request. Files are
+	// packed into chunks whole; a file larger than this is trimmed.
+	maxReviewChunk = 16 * 1024
+	// maxReviewFiles bounds how many changed files one review looks at.
+	maxReviewFiles = 100
+)
+
+var ErrNoChanges = errors.New("no changes to review")
+
+const reviewSystemPrompt = `You are a meticulous code reviewer. Review the diff for bugs, security problems, race conditions, wrong error handling, resource leaks, missing tests for new behaviour and misleading code or comments. Do not report style issues a formatter would fix, and do not praise.
+
+Each diff line is prefixed with its line number in the new version of the file; removed lines have no number.
+
+Reply with a JSON array only, without code fences. Each element is an object with:
+- "file": the path as shown in the diff
+- "line": the new-file line number the finding is about
+- "severity": "high" (breaks behaviour or security), "medium" (likely bug or fragile code) or "low" (minor improvement)
+- "message": the problem, in one or two sentences
+- "suggestion": a concrete fix, specific enough to apply without reading the review again
+
+Reply [] if nothing is worth reporting.`
+
+// Severities of review findings, most severe first.
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// ReviewFinding is one problem the /review Slash Command found, anchored at
+// a file and line of the working tree.
+type ReviewFinding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// Anchor is the finding's location as file:line.
+func (f ReviewFinding) Anchor() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+type Review struct {
+	Ref      string
+	Files    []git.FileChange
+	Findings []ReviewFinding
+	// Skipped counts chunks whose reply could not be parsed; their files
+	// were not reviewed.
+	Skipped int
+}
+
+// Review compares the Workspace with ref, HEAD when empty, and asks the
+// model to review the diff one chunk of files at a time. Untracked files
+// are recorded with intent-to-add in a scratch index so the diff includes
+// them. Like DraftCommit it stays out of the Session History.
+func (a *Agent) Review(ctx context.Context, ref string) (Review, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if strings.HasPrefix(ref, "-") {
+		return Review{}, fmt.Errorf("invalid ref %q", ref)
+	}
+	repo, err := git.Open(ctx, tools.WorkspaceRoot())
+	if err != nil {
+		return Review{}, err
+	}
+	if _, err := repo.Run(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return Review{}, fmt.Errorf("unknown ref %q", ref)
+	}
+	repo, done, err := repo.ScratchIndex(ctx)
+	if err != nil {
+		return Review{}, err
+	}
+	defer done()
+	if _, err := repo.Run(ctx, "add", "--all", "--intent-to-add", "--", "."); err != nil {
+		return Review{}, err
+	}
+	files, err := repo.DiffStat(ctx, git.DiffOptions{Base: ref, Relative: true})
+	if err != nil {
+		return Review{}, err
+	}
+	review := Review{Ref: ref, Files: files}
+	if len(files) == 0 {
+		return review, ErrNoChanges
+	}
+	if len(files) > maxReviewFiles {
+		files = files[:maxReviewFiles]
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	for _, f := range files {
+		if f.Binary {
+			continue
+		}
+		diff, err := repo.Diff(ctx, git.DiffOptions{Base: ref, Relative: true, Paths: []string{f.Path}})
+		if err != nil {
+			return review, err
+		}
+		diff = trimOutput(numberDiff(diff), maxReviewChunk)
+		if cur.Len() > 0 && cur.Len()+len(diff) > maxReviewChunk {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(diff)
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+
+	for _, chunk := range chunks {
+		reply, err := a.Complete(ctx, reviewSystemPrompt, chunk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return review, err
+			}
+			review.Skipped++
+			continue
+		}
+		findings, err := parseFindings(reply)
+		if err != nil {
+			review.Skipped++
+			continue
+		}
+		review.Findings = append(review.Findings, findings...)
+	}
+	sort.SliceStable(review.Findings, func(i, j int) bool {
+		fi, fj := review.Findings[i], review.Findings[j]
+		if si, sj := severityRank(fi.Severity), severityRank(fj.Severity); si != sj {
+			return si < sj
+		}
+		if fi.File != fj.File {
+			return fi.File < fj.File
+		}
+		return fi.Line < fj.Line
+	})
+	return review, nil
+}
+
+// numberDiff prefixes each line of a unified diff's hunks with its line
+// number in the new file, so the model can anchor findings precisely.
+func numberDiff(diff string) string {
+	var b strings.Builder
+	line := 0
+	inHunk := false
+	for _, l := range strings.SplitAfter(diff, "\n") {
+		if l == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			// @@ -a,b +c,d @@
+			inHunk = true
+			if _, after, ok := strings.Cut(l, " +"); ok {
+				num, _, _ := strings.Cut(after, ",")
+				num, _, _ = strings.Cut(num, " ")
+				line, _ = strconv.Atoi(num)
+			}
+			b.WriteString(l)
+		case inHunk && strings.HasPrefix(l, "-"):
+			fmt.Fprintf(&b, "%6s %s", "", l)
+		case inHunk && (strings.HasPrefix(l, "+") || strings.HasPrefix(l, " ")):
+			fmt.Fprintf(&b, "%6d %s", line, l)
+			line++
+		case strings.HasPrefix(l, "diff --git"):
+			inHunk = false
+			b.WriteString(l)
+		default:
+			b.WriteString(l)
+		}
+	}
+	return b.String()
+}
+
+// parseFindings reads the model's JSON array, tolerating code fences and
+// text around it, line numbers given as strings and unknown severities.
+func parseFindings(reply string) ([]ReviewFinding, error) {
+	reply = stripFence(reply)
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, errors.New("review reply is not a JSON array")
+	}
+	var raw []struct {
+		File       string `json:"file"`
+		Line       any    `json:"line"`
+		Severity   string `json:"severity"`
+		Message    string `json:"message"`
+		Suggestion string `json:"suggestion"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return nil, err
+	}
+	findings := make([]ReviewFinding, 0, len(raw))
+	for _, r := range raw {
+		if strings.TrimSpace(r.Message) == "" {
+			continue
+		}
+		f := ReviewFinding{
+			File:       strings.TrimPrefix(strings.TrimSpace(r.File), "b/"),
+			Severity:   normalizeSeverity(r.Severity),
+			Message:    strings.TrimSpace(r.Message),
+			Suggestion: strings.TrimSpace(r.Suggestion),
+		}
+		switch v := r.Line.(type) {
+		case float64:
+			f.Line = int(v)
+		case string:
+			f.Line, _ = strconv.Atoi(strings.TrimSpace(v))
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func normalizeSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "high", "critical", "error", "blocker", "major":
+		return SeverityHigh
+	case "low", "minor", "nit", "info", "suggestion":
+		return SeverityLow
+	default:
+		return SeverityMedium
+	}
+}
+
+func severityRank(s string) int {
+	switch s {
+	case SeverityHigh:
+		return 0
+	case SeverityMedium:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// FixRequest is the message that asks the model, in a normal Turn, to apply
+// the given findings.
+func FixRequest(findings []ReviewFinding) string {
+	var b strings.Builder
+	if len(findings) == 1 {
+		b.WriteString("Apply the fix for this code review finding. Read the code first; if the finding is wrong, explain why instead of changing it.\n")
+	} else {
+		b.WriteString("Apply the fixes for these code review findings. Read the code first; if a finding is wrong, explain why instead of changing it.\n")
+	}
+	for i, f := range findings {
+		fmt.Fprintf(&b, "\n%d. %s [%s] %s\n   Suggested fix: %s\n", i+1, f.Anchor(), f.Severity, f.Message, f.Suggestion)
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNumberDiff(t *testing.T) {
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -10,3 +12,3 @@ func f() {\n ctx\n-old\n+new\n tail\n"
+	got := numberDiff(diff)
+	for _, want := range []string{"    12  ctx\n", "       -old\n", "    13 +new\n", "    14  tail\n", "+++ b/a.go\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("numbered diff missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestParseFindings(t *testing.T) {
+	reply := "Here you go:\n```json\n[{\"file\":\"b/a.go\",\"line\":\"7\",\"severity\":\"Critical\",\"message\":\"nil deref\",\"suggestion\":\"check err\"},{\"file\":\"a.go\",\"line\":3,\"message\":\"\"}]\n```"
+	findings, err := parseFindings(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ReviewFinding{File: "a.go", Line: 7, Severity: SeverityHigh, Message: "nil deref", Suggestion: "check err"}
+	if len(findings) != 1 || findings[0] != want {
+		t.Fatalf("findings = %+v", findings)
+	}
+	if _, err := parseFindings("looks good to me"); err == nil {
+		t.Fatal("expected an error for a reply without JSON")
+	}
+}
+
+func TestReview_chunksAndSortsFindings(t *testing.T) {
+	dir := setupCommitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, dir, "add", "b.txt")
+	gitCmd(t, dir, "commit", "-q", "-m", "add b")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\nbroken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Big enough that a.txt and b.txt cannot share a chunk.
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte(strings.Repeat("filler line\n", maxReviewChunk/12)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, backend := newCommitAgent(`[{"file":"a.txt","line":2,"severity":"low","message":"typo","suggestion":"fix it"}]`)
+	backend.scripts = append(backend.scripts,
+		answerScript(`[{"file":"b.txt","line":1,"severity":"high","message":"bad","suggestion":"better"}]`))
+
+	review, err := a.Review(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Review: %v", err)
+	}
+	if len(backend.requests) != 2 {
+		t.Fatalf("requests = %d, want one per chunk", len(backend.requests))
+	}
+	first := backend.requests[0]["messages"].([]map[string]any)[1]["content"].(string)
+	if !strings.Contains(first, "     2 +broken") || strings.Contains(first, "filler") {
+		t.Fatalf("first chunk = %q", first)
+	}
+	if review.Ref != "HEAD" || len(review.Findings) != 2 || review.Findings[0].Severity != SeverityHigh || review.Findings[1].Anchor() != "a.txt:2" {
+		t.Fatalf("review = %+v", review)
+	}
+	if len(a.History) != 1 {
+		t.Fatal("review touched the Session History")
+	}
+}
+
+func TestReview_subdirectoryWorkspace(t *testing.T) {
+	root := setupCommitRepo(t)
+	dir := filepath.Join(root, "sub")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "x.txt"), []byte("x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, root, "add", "sub/x.txt")
+	gitCmd(t, root, "commit", "-q", "-m", "add x")
+	chdirWorkspace(t, dir)
+	for name, content := range map[string]string{"sub/x.txt": "x\nchanged\n", "sub/new.txt": "untracked\n", "a.txt": "outside\n"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status := gitCmd(t, root, "status", "--porcelain")
+	a, backend := newCommitAgent(`[{"file":"x.txt","line":2,"severity":"low","message":"m","suggestion":"s"}]`)
+	review, err := a.Review(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Review: %v", err)
+	}
+	if after := gitCmd(t, root, "status", "--porcelain"); after != status {
+		t.Fatalf("git status changed from %q to %q", status, after)
+	}
+	var paths []string
+	for _, f := range review.Files {
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, ",") != "new.txt,x.txt" {
+		t.Fatalf("reviewed files = %v", paths)
+	}
+	chunk := backend.requests[0]["messages"].([]map[string]any)[1]["content"].(string)
+	if !strings.Contains(chunk, "+changed") || !strings.Contains(chunk, "+untracked") || strings.Contains(chunk, "outside") {
+		t.Fatalf("chunk = %q", chunk)
+	}
+}
+
+func TestReview_errors(t *testing.T) {
+	setupCommitRepo(t)
+	a, _ := newCommitAgent("[]")
+	if _, err := a.Review(context.Background(), ""); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("clean tree: %v", err)
+	}
+	if _, err := a.Review(context.Background(), "no-such-branch"); err == nil || !strings.Contains(err.Error(), "unknown ref") {
+		t.Fatalf("unknown ref: %v", err)
+	}
+	if _, err := a.Review(context.Background(), "--output=x"); err == nil {
+		t.Fatal("option-like ref accepted")
+	}
+}
+
+func TestFixRequest(t *testing.T) {
+	msg := FixRequest([]ReviewFinding{{File: "a.go", Line: 3, Severity: SeverityMedium, Message: "leak", Suggestion: "close it"}})
+	if !strings.Contains(msg, "a.go:3 [medium] leak") || !strings.Contains(msg, "Suggested fix: close it") {
+		t.Fatalf("FixRequest = %q", msg)
+	}
+}
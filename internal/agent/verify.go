@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// maxVerifyOutput bounds the verification output handed back to the model
+// and shown in the Transcript.
+const maxVerifyOutput = 8 * 1024
+
+// verify runs the verify command once. On failure it returns the message
+// that asks the model to fix the problems; it returns "" when the command
+// passed.
+func (a *Agent) verify(ctx context.Context, attempt int, emit EventEmitter) (string, error) {
+	command := a.Verify.Command
+	emit(Event{Kind: EventVerifyStart, Command: command, Attempt: attempt})
+
+	runCtx, cancel := context.WithTimeout(ctx, a.Verify.Timeout())
+	defer cancel()
+	stdout, stderr, exitCode, err := tools.ExecuteShell(runCtx, command)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	output := trimOutput(strings.TrimSpace(stdout+"\n"+stderr), maxVerifyOutput)
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		output = strings.TrimSpace(output + "\n" + fmt.Sprintf("verification timed out after %s", a.Verify.Timeout()))
+		exitCode = -1
+	case err != nil:
+		output = strings.TrimSpace(output + "\n" + err.Error())
+		exitCode = -1
+	}
+	emit(Event{Kind: EventVerifyResult, Command: command, Attempt: attempt, ExitCode: exitCode, Text: output})
+
+	if exitCode == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf(
+		"Automatic verification failed: `%s` exited with status %d (attempt %d of %d). Fix the problems below; the command runs again after your changes.\n\n%s",
+		command, exitCode, attempt, a.Verify.Attempts(), output,
+	), nil
+}
+
+// trimOutput keeps the start and, mostly, the end of s, where compilers and
+// test runners put their summaries.
+func trimOutput(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	head := max / 4
+	tail := max - head
+	omitted := len(s) - head - tail
+	return fmt.Sprintf("%s\n… (%d bytes omitted) …\n%s", strings.ToValidUTF8(s[:head], ""), omitted, strings.ToValidUTF8(s[len(s)-tail:], ""))
+}
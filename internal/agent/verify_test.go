@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+func writeFileScript(id, path string) []inference.Response {
+	return []inference.Response{
+		{Choices: []inference.Choice{{Delta: inference.Delta{
+			ToolCalls: []inference.ToolCall{{
+				ID:   id,
+				Type: "function",
+				Function: inference.Function{
+					Name:      "write_file",
+					Arguments: map[string]any{"path": path, "content": "x"},
+				},
+			}},
+		}}}},
+	}
+}
+
+func answerScript(text string) []inference.Response {
+	return []inference.Response{{Choices: []inference.Choice{{Delta: inference.Delta{Content: text}}}}}
+}
+
+func newVerifyAgent(t *testing.T, backend inference.Backend, verify config.Verify) *Agent {
+	t.Helper()
+	chdirWorkspace(t, t.TempDir())
+	tools.ResetSession()
+	a := &Agent{
+		Backend: backend,
+		Model:   "test-model",
+		Tools:   tools.NewRegistry(),
+		Verify:  verify,
+	}
+	a.RegisterTool(&tools.WriteFile{})
+	a.initHistory("system prompt")
+	return a
+}
+
+func verifyResults(events []Event) []Event {
+	var out []Event
+	for _, e := range events {
+		if e.Kind == EventVerifyResult {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestRunTurn_verifyFailureGoesBackToModel(t *testing.T) {
+	backend := &scriptedBackend{scripts: [][]inference.Response{
+		writeFileScript("call-1", "bad.txt"),
+		answerScript("done"),
+		writeFileScript("call-2", "ok.txt"),
+		answerScript("fixed"),
+	}}
+	a := newVerifyAgent(t, backend, config.Verify{Command: "echo checking; test -f ok.txt", MaxAttempts: 3})
+
+	emit, events := collectEmitter()
+	if err := a.RunTurn(context.Background(), "make it work", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+
+	results := verifyResults(events())
+	if len(results) != 2 || results[0].ExitCode == 0 || results[1].ExitCode != 0 {
+		t.Fatalf("verify results = %+v, want a failure then a pass", results)
+	}
+	if results[0].Attempt != 1 || results[1].Attempt != 2 || !strings.Contains(results[0].Text, "checking") {
+		t.Fatalf("verify results = %+v", results)
+	}
+	if backend.calls != 4 {
+		t.Fatalf("backend calls = %d, want 4", backend.calls)
+	}
+
+	var feedback string
+	for _, msg := range a.History {
+		if content, _ := msg["content"].(string); msg["role"] == "user" && strings.Contains(content, "Automatic verification failed") {
+			feedback = content
+		}
+	}
+	if !strings.Contains(feedback, "test -f ok.txt") || !strings.Contains(feedback, "checking") {
+		t.Fatalf("feedback message = %q", feedback)
+	}
+
+	all := events()
+	if last := all[len(all)-2]; last.Kind != EventTurnComplete || last.AssistantMessage != "fixed" {
+		t.Fatalf("turn complete = %+v, want final answer after verification", last)
+	}
+}
+
+func TestRunTurn_verifySkippedWithoutMutation(t *testing.T) {
+	backend := &scriptedBackend{scripts: [][]inference.Response{answerScript("nothing to do")}}
+	a := newVerifyAgent(t, backend, config.Verify{Command: "false", MaxAttempts: 3})
+
+	emit, events := collectEmitter()
+	if err := a.RunTurn(context.Background(), "hi", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+	for _, e := range events() {
+		if e.Kind == EventVerifyStart {
+			t.Fatal("verification ran for a Turn without File Mutations")
+		}
+	}
+}
+
+func TestRunTurn_verifyStopsAfterMaxAttempts(t *testing.T) {
+	backend := &scriptedBackend{scripts: [][]inference.Response{
+		writeFileScript("call-1", "a.txt"),
+		answerScript("done"),
+		writeFileScript("call-2", "b.txt"),
+		answerScript("still done"),
+		writeFileScript("call-3", "c.txt"),
+		answerScript("unreachable"),
+	}}
+	a := newVerifyAgent(t, backend, config.Verify{Command: "false", MaxAttempts: 2})
+
+	emit, events := collectEmitter()
+	if err := a.RunTurn(context.Background(), "go", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+	if got := len(verifyResults(events())); got != 2 {
+		t.Fatalf("verification runs = %d, want 2", got)
+	}
+	if backend.calls != 4 {
+		t.Fatalf("backend calls = %d, want 4", backend.calls)
+	}
+	if !slices.ContainsFunc(a.History, func(m map[string]any) bool {
+		content, _ := m["content"].(string)
+		return strings.Contains(content, "attempt 1 of 2")
+	}) {
+		t.Fatal("feedback for attempt 1 of 2 not in history")
+	}
+	if err := lastError(events()); !errors.Is(err, errVerifyGaveUp) || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("final error = %v", err)
+	}
+}
+
+// lastError returns the error of the last EventError.
+func lastError(events []Event) error {
+	var err error
+	for _, e := range events {
+		if e.Kind == EventError {
+			err = e.Err
+		}
+	}
+	return err
+}
+
+func TestRunTurn_verifyStopsWhenModelMakesNoChanges(t *testing.T) {
+	backend := &scriptedBackend{scripts: [][]inference.Response{
+		writeFileScript("call-1", "a.txt"),
+		answerScript("done"),
+		answerScript("I cannot fix this"),
+	}}
+	a := newVerifyAgent(t, backend, config.Verify{Command: "false", MaxAttempts: 3})
+
+	emit, events := collectEmitter()
+	if err := a.RunTurn(context.Background(), "go", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+	if got := len(verifyResults(events())); got != 1 {
+		t.Fatalf("verification runs = %d, want 1", got)
+	}
+	if err := lastError(events()); !errors.Is(err, errVerifyGaveUp) {
+		t.Fatalf("final error = %v", err)
+	}
+}
+
+func TestTrimOutput(t *testing.T) {
+	if got := trimOutput("short", 100); got != "short" {
+		t.Fatalf("trimOutput = %q", got)
+	}
+	long := strings.Repeat("a", 500) + strings.Repeat("z", 500)
+	got := trimOutput(long, 100)
+	if !strings.HasPrefix(got, strings.Repeat("a", 25)) || !strings.HasSuffix(got, strings.Repeat("z", 75)) {
+		t.Fatalf("trimOutput = %q", got)
+	}
+	if !strings.Contains(got, "900 bytes omitted") {
+		t.Fatalf("trimOutput = %q, want omission note", got)
+	}
+}
+
+func TestRunTurn_untrustedVerifyNeverRuns(t *testing.T) {
+	backend := &scriptedBackend{scripts: [][]inference.Response{
+		writeFileScript("call-1", "a.txt"),
+		answerScript("done"),
+	}}
+	a := newVerifyAgent(t, backend, config.Verify{})
+	root := tools.WorkspaceRoot()
+	t.Setenv(config.EnvConfigFile, "")
+	if err := os.MkdirAll(filepath.Join(root, ".mini-agent"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, config.DefaultPath), []byte(`{"verify": {"command": "touch ran"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DropLaunches()
+	a.Verify = cfg.Verify
+
+	emit, events := collectEmitter()
+	if err := a.RunTurn(context.Background(), "go", emit); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ran")); err == nil || len(verifyResults(events())) != 0 {
+		t.Fatal("the verify command of an untrusted config ran")
+	}
+}
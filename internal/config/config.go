@@ -0,0 +1,332 @@
+// Package config loads the per-Workspace configuration file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EnvConfigFile overrides the location of the configuration file.
+const EnvConfigFile = "MINI_AGENT_CONFIG"
+
+// DefaultPath is where the configuration file lives, relative to the
+// Workspace root.
+const DefaultPath = ".mini-agent/config.json"
+
+type Config struct {
+	// LSP maps a language id (e.g. "go") to the language server for it.
+	LSP map[string]LanguageServer `json:"lsp,omitempty"`
+	// Verify is run after every Turn that mutated files.
+	Verify Verify `json:"verify,omitempty"`
+	// Approval overrides the Approval Gate per tool name.
+	Approval map[string]ApprovalRule `json:"approval,omitempty"`
+	// MCP maps a server name to a Model Context Protocol server whose tools
+	// are offered to the model.
+	MCP map[string]MCPServer `json:"mcp,omitempty"`
+	// Results bounds the size of the tool results handed to the model.
+	Results ResultBudget `json:"results,omitempty"`
+	// Modes customizes the agent modes by name, or adds new ones.
+	Modes map[string]Mode `json:"modes,omitempty"`
+	// AskUser configures the questions the model asks with ask_user.
+	AskUser AskUser `json:"ask_user,omitempty"`
+}
+
+// DefaultAskUserFallback answers a question nobody answered.
+const DefaultAskUserFallback = "The user is not available to answer. Proceed with your best judgement and state the assumptions you made."
+
+type AskUser struct {
+	// Fallback is the answer when the developer skips a question, or when
+	// the Agent runs without anyone to ask.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// FallbackAnswer returns the configured Fallback or DefaultAskUserFallback.
+func (a AskUser) FallbackAnswer() string {
+	if strings.TrimSpace(a.Fallback) != "" {
+		return a.Fallback
+	}
+	return DefaultAskUserFallback
+}
+
+// Mode configures an agent mode. Fields left out keep the built-in value of
+// a mode of the same name.
+type Mode struct {
+	Description string `json:"description,omitempty"`
+	// Prompt is appended to the System Prompt while the mode is active.
+	Prompt string `json:"prompt,omitempty"`
+	// Tools lists the tool names the mode offers.
+	Tools []string `json:"tools,omitempty"`
+	// ReadOnly offers only read-only tools and refuses their calls that
+	// write.
+	ReadOnly *bool                   `json:"read_only,omitempty"`
+	Approval map[string]ApprovalRule `json:"approval,omitempty"`
+	Model    string                  `json:"model,omitempty"`
+}
+
+// ApprovalRule decides what happens when a gated tool is called.
+type ApprovalRule string
+
+const (
+	// ApprovalAsk prompts the developer; it is the default.
+	ApprovalAsk   ApprovalRule = "ask"
+	ApprovalAllow ApprovalRule = "allow"
+	ApprovalDeny  ApprovalRule = "deny"
+)
+
+type LanguageServer struct {
+	Command    []string `json:"command"`
+	Extensions []string `json:"extensions"`
+	Disabled   bool     `json:"disabled,omitempty"`
+}
+
+// MCPServer is started with Command (the stdio transport) or reached at URL
+// (the streamable HTTP transport). Values in Env and Headers may refer to
+// environment variables as $VAR or ${VAR}.
+type MCPServer struct {
+	Command []string          `json:"command,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Approval applies to every tool of the server that the approval
+	// section does not name; it defaults to ask.
+	Approval ApprovalRule `json:"approval,omitempty"`
+	Disabled bool         `json:"disabled,omitempty"`
+}
+
+const (
+	DefaultVerifyAttempts = 3
+	DefaultVerifyTimeout  = 10 * time.Minute
+)
+
+type Verify struct {
+	// Command is a shell command such as "go build ./... && go test ./...".
+	// Verification is off when it is empty.
+	Command string `json:"command"`
+	// MaxAttempts is how many times the command runs within one Turn; a
+	// failure of all but the last run is handed back to the model to fix.
+	MaxAttempts    int `json:"max_attempts,omitempty"`
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Attempts returns MaxAttempts, at least 1.
+func (v Verify) Attempts() int {
+	return max(v.MaxAttempts, 1)
+}
+
+// Timeout returns the configured timeout or DefaultVerifyTimeout.
+func (v Verify) Timeout() time.Duration {
+	if v.TimeoutSeconds > 0 {
+		return time.Duration(v.TimeoutSeconds) * time.Second
+	}
+	return DefaultVerifyTimeout
+}
+
+const (
+	DefaultResultBytes      = 96 * 1024
+	DefaultRoundResultBytes = 256 * 1024
+	// MinResultBytes leaves room for the truncation notice and some of the
+	// result around it.
+	MinResultBytes = 1024
+)
+
+// ResultBudget caps tool results in bytes of model-facing text. A result
+// over its budget is stored as an artifact and truncated.
+type ResultBudget struct {
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// Tools overrides MaxBytes by tool name.
+	Tools map[string]int `json:"tools,omitempty"`
+	// RoundMaxBytes caps all results of one model round together.
+	RoundMaxBytes int `json:"round_max_bytes,omitempty"`
+}
+
+// ToolLimit returns the budget of one result of tool.
+func (b ResultBudget) ToolLimit(tool string) int {
+	if n := b.Tools[tool]; n > 0 {
+		return n
+	}
+	if b.MaxBytes > 0 {
+		return b.MaxBytes
+	}
+	return DefaultResultBytes
+}
+
+// RoundLimit returns the budget of all results of a round.
+func (b ResultBudget) RoundLimit() int {
+	if b.RoundMaxBytes > 0 {
+		return b.RoundMaxBytes
+	}
+	return DefaultRoundResultBytes
+}
+
+func (b ResultBudget) validate() error {
+	tooSmall := func(n int) bool { return n < 0 || n > 0 && n < MinResultBytes }
+	if tooSmall(b.MaxBytes) || tooSmall(b.RoundMaxBytes) {
+		return fmt.Errorf("max_bytes and round_max_bytes must be 0 or at least %d", MinResultBytes)
+	}
+	for tool, n := range b.Tools {
+		if tooSmall(n) {
+			return fmt.Errorf("tools.%s must be 0 or at least %d", tool, MinResultBytes)
+		}
+	}
+	return nil
+}
+
+// Default is used when no configuration file exists: gopls for Go files.
+func Default() Config {
+	return Config{
+		LSP: map[string]LanguageServer{
+			"go": {Command: []string{"gopls"}, Extensions: []string{".go"}},
+		},
+	}
+}
+
+// Load reads the configuration for the Workspace at root. Entries in the file
+// are layered over Default; a missing file is not an error.
+func Load(root string) (Config, error) {
+	path := os.Getenv(EnvConfigFile)
+	if path == "" {
+		path = filepath.Join(root, DefaultPath)
+	}
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var file Config
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for lang, server := range file.LSP {
+		if err := server.validate(); err != nil {
+			return Config{}, fmt.Errorf("%s: lsp.%s: %w", path, lang, err)
+		}
+		cfg.LSP[lang] = server
+	}
+	if file.Verify.MaxAttempts < 0 || file.Verify.TimeoutSeconds < 0 {
+		return Config{}, fmt.Errorf("%s: verify: max_attempts and timeout_seconds must not be negative", path)
+	}
+	cfg.Verify = file.Verify
+	for tool, rule := range file.Approval {
+		if err := rule.validateFor(tool); err != nil {
+			return Config{}, fmt.Errorf("%s: approval.%s: %w", path, tool, err)
+		}
+	}
+	cfg.Approval = file.Approval
+	for name, server := range file.MCP {
+		if err := server.validate(name); err != nil {
+			return Config{}, fmt.Errorf("%s: mcp.%s: %w", path, name, err)
+		}
+	}
+	cfg.MCP = file.MCP
+	if err := file.Results.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: results: %w", path, err)
+	}
+	cfg.Results = file.Results
+	for name, mode := range file.Modes {
+		if err := mode.validate(name); err != nil {
+			return Config{}, fmt.Errorf("%s: modes.%s: %w", path, name, err)
+		}
+	}
+	cfg.Modes = file.Modes
+	cfg.AskUser = file.AskUser
+	if cfg.Verify.Command != "" && cfg.Verify.MaxAttempts == 0 {
+		cfg.Verify.MaxAttempts = DefaultVerifyAttempts
+	}
+	return cfg, nil
+}
+
+func (s LanguageServer) validate() error {
+	if s.Disabled {
+		return nil
+	}
+	if len(s.Command) == 0 {
+		return errors.New("command is required")
+	}
+	if len(s.Extensions) == 0 {
+		return errors.New("extensions is required")
+	}
+	for _, ext := range s.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("extension %q must start with a dot", ext)
+		}
+	}
+	return nil
+}
+
+func (r ApprovalRule) validate() error {
+	switch r {
+	case ApprovalAsk, ApprovalAllow, ApprovalDeny:
+		return nil
+	default:
+		return fmt.Errorf("must be %q, %q or %q, got %q", ApprovalAsk, ApprovalAllow, ApprovalDeny, r)
+	}
+}
+
+// askedTools always pass the Approval Gate: the configuration file comes
+// with the Workspace, so it must not let Shell Execution, Workspace code or
+// changes to history run unasked.
+var askedTools = []string{"run_shell", "go", "git"}
+
+// validateFor validates r as the rule for tool.
+func (r ApprovalRule) validateFor(tool string) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	if r == ApprovalAllow && slices.Contains(askedTools, tool) {
+		return fmt.Errorf("%s always passes the Approval Gate; use %q or %q", tool, ApprovalAsk, ApprovalDeny)
+	}
+	return nil
+}
+
+var modeName = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,15}$`)
+
+func (m Mode) validate(name string) error {
+	if !modeName.MatchString(name) {
+		return errors.New("name must be 1 to 16 lower-case letters, digits, '_' or '-', starting with a letter")
+	}
+	for tool, rule := range m.Approval {
+		if err := rule.validateFor(tool); err != nil {
+			return fmt.Errorf("approval.%s: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+// mcpServerName limits server names to what tool names may contain, since
+// they become part of them.
+var mcpServerName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+func (s MCPServer) validate(name string) error {
+	if !mcpServerName.MatchString(name) {
+		return errors.New("name must be 1 to 32 letters, digits, '_' or '-'")
+	}
+	if s.Approval != "" {
+		if err := s.Approval.validate(); err != nil {
+			return fmt.Errorf("approval: %w", err)
+		}
+	}
+	if s.Disabled {
+		return nil
+	}
+	switch {
+	case len(s.Command) == 0 && s.URL == "":
+		return errors.New("command or url is required")
+	case len(s.Command) > 0 && s.URL != "":
+		return errors.New("command and url are mutually exclusive")
+	case s.URL != "" && !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://"):
+		return fmt.Errorf("url %q must be http or https", s.URL)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, root, content string) {
+	t.Helper()
+	path := filepath.Join(root, DefaultPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_missingFileUsesDefault(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.LSP["go"].Command; len(got) != 1 || got[0] != "gopls" {
+		t.Fatalf("go server = %v, want gopls", got)
+	}
+}
+
+func TestLoad_layersOverDefault(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"lsp": {
+		"go": {"disabled": true},
+		"python": {"command": ["pyright-langserver", "--stdio"], "extensions": [".py"]}
+	}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.LSP["go"].Disabled {
+		t.Fatal("go server should be disabled")
+	}
+	if cfg.LSP["python"].Command[0] != "pyright-langserver" {
+		t.Fatalf("python = %+v", cfg.LSP["python"])
+	}
+}
+
+func TestLoad_invalid(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	for name, content := range map[string]string{
+		"syntax":    `{"lsp": `,
+		"command":   `{"lsp": {"rust": {"extensions": [".rs"]}}}`,
+		"extension": `{"lsp": {"rust": {"command": ["rust-analyzer"], "extensions": ["rs"]}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeConfig(t, root, content)
+			if _, err := Load(root); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestLoad_envOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	if err := os.WriteFile(path, []byte(`{"lsp": {"go": {"command": ["gopls", "-remote=auto"], "extensions": [".go"]}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvConfigFile, path)
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.LSP["go"].Command) != 2 {
+		t.Fatalf("go = %+v", cfg.LSP["go"])
+	}
+}
+
+func TestLoad_verify(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"verify": {"command": "go build ./... && go test ./..."}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Verify.MaxAttempts != DefaultVerifyAttempts || cfg.Verify.Timeout() != DefaultVerifyTimeout {
+		t.Fatalf("verify = %+v, want defaults filled in", cfg.Verify)
+	}
+
+	writeConfig(t, root, `{"verify": {"command": "make check", "max_attempts": -1}}`)
+	if _, err := Load(root); err == nil {
+		t.Fatal("expected error for negative max_attempts")
+	}
+}
+
+func TestLoad_approval(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"approval": {"jira_issue": "allow", "run_shell": "ask"}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Approval["jira_issue"] != ApprovalAllow || cfg.Approval["run_shell"] != ApprovalAsk {
+		t.Fatalf("approval = %v", cfg.Approval)
+	}
+
+	writeConfig(t, root, `{"approval": {"go": "always"}}`)
+	if _, err := Load(root); err == nil {
+		t.Fatal("expected error for unknown rule")
+	}
+
+	for _, content := range []string{
+		`{"approval": {"run_shell": "allow"}}`,
+		`{"approval": {"go": "allow"}}`,
+		`{"modes": {"plan": {"approval": {"git": "allow"}}}}`,
+		`{"modes": {"code": {"approval": {"run_shell": "allow"}}}}`,
+	} {
+		writeConfig(t, root, content)
+		if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "always passes the Approval Gate") {
+			t.Fatalf("%s: %v", content, err)
+		}
+	}
+	writeConfig(t, root, `{"approval": {"run_shell": "deny", "write_file": "allow"}}`)
+	if _, err := Load(root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_results(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Results.ToolLimit("run_shell") != DefaultResultBytes || cfg.Results.RoundLimit() != DefaultRoundResultBytes {
+		t.Fatalf("results = %+v, want defaults", cfg.Results)
+	}
+
+	writeConfig(t, root, `{"results": {"max_bytes": 2000, "tools": {"read_file": 5000}}}`)
+	if cfg, err = Load(root); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Results.ToolLimit("read_file") != 5000 || cfg.Results.ToolLimit("run_shell") != 2000 {
+		t.Fatalf("results = %+v", cfg.Results)
+	}
+
+	writeConfig(t, root, `{"results": {"tools": {"read_file": -1}}}`)
+	if _, err := Load(root); err == nil {
+		t.Fatal("expected error for a negative budget")
+	}
+	writeConfig(t, root, `{"results": {"round_max_bytes": 10}}`)
+	if _, err := Load(root); err == nil {
+		t.Fatal("expected error for a budget below MinResultBytes")
+	}
+}
+
+func TestLoad_askUser(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AskUser.FallbackAnswer() != DefaultAskUserFallback {
+		t.Fatalf("fallback = %q, want the default", cfg.AskUser.FallbackAnswer())
+	}
+
+	writeConfig(t, root, `{"ask_user": {"fallback": "Use the defaults."}}`)
+	if cfg, err = Load(root); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AskUser.FallbackAnswer() != "Use the defaults." {
+		t.Fatalf("fallback = %q", cfg.AskUser.FallbackAnswer())
+	}
+}
+
+func TestLoad_modes(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"modes": {"plan": {"model": "big"}, "docs": {"tools": ["read_file"], "read_only": false}}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Modes["plan"].Model != "big" || cfg.Modes["docs"].ReadOnly == nil || *cfg.Modes["docs"].ReadOnly {
+		t.Fatalf("modes = %+v", cfg.Modes)
+	}
+
+	for name, content := range map[string]string{
+		"name":     `{"modes": {"Big Mode": {}}}`,
+		"approval": `{"modes": {"plan": {"approval": {"go": "sometimes"}}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfig(t, root, content)
+			if _, err := Load(root); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestLoad_mcp(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"mcp": {
+		"issues": {"command": ["issues-mcp", "--stdio"], "env": {"TOKEN": "${ISSUES_TOKEN}"}, "approval": "allow"},
+		"db-schema": {"url": "https://mcp.example.com/db", "headers": {"Authorization": "Bearer $DB_TOKEN"}}
+	}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := cfg.MCP["issues"]; s.Command[0] != "issues-mcp" || s.Approval != ApprovalAllow || s.Env["TOKEN"] != "${ISSUES_TOKEN}" {
+		t.Fatalf("issues = %+v", s)
+	}
+	if s := cfg.MCP["db-schema"]; s.URL != "https://mcp.example.com/db" || s.Approval != "" {
+		t.Fatalf("db-schema = %+v", s)
+	}
+
+	for name, content := range map[string]string{
+		"transport": `{"mcp": {"x": {}}}`,
+		"both":      `{"mcp": {"x": {"command": ["x"], "url": "http://x"}}}`,
+		"scheme":    `{"mcp": {"x": {"url": "ftp://x"}}}`,
+		"name":      `{"mcp": {"x.y": {"command": ["x"]}}}`,
+		"approval":  `{"mcp": {"x": {"command": ["x"], "approval": "yes"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfig(t, root, content)
+			if _, err := Load(root); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EnvTrustFile overrides the location of the trust store.
+const EnvTrustFile = "MINI_AGENT_TRUST_FILE"
+
+// Launches lists what the configuration starts on its own: language servers
+// other than the defaults, MCP servers and the verify command, which run with
+// the developer's privileges or receive their environment variables. The
+// configuration file comes with the Workspace, so these need the developer's
+// trust.
+func (c Config) Launches() []string {
+	defaults := Default().LSP
+	var launches []string
+	for lang, s := range c.LSP {
+		if d, ok := defaults[lang]; s.Disabled || ok && slices.Equal(s.Command, d.Command) {
+			continue
+		}
+		launches = append(launches, fmt.Sprintf("lsp.%s: %s", lang, strings.Join(s.Command, " ")))
+	}
+	for name, s := range c.MCP {
+		switch {
+		case s.Disabled:
+		case s.URL != "":
+			launches = append(launches, fmt.Sprintf("mcp.%s: %s", name, s.URL))
+		default:
+			launches = append(launches, fmt.Sprintf("mcp.%s: %s", name, strings.Join(s.Command, " ")))
+		}
+	}
+	if c.Verify.Command != "" {
+		launches = append(launches, "verify: "+c.Verify.Command)
+	}
+	sort.Strings(launches)
+	return launches
+}
+
+// DropLaunches removes what Launches lists, keeping the default language
+// servers.
+func (c *Config) DropLaunches() {
+	defaults := Default().LSP
+	for lang, s := range c.LSP {
+		if d, ok := defaults[lang]; !s.Disabled && (!ok || !slices.Equal(s.Command, d.Command)) {
+			if ok {
+				c.LSP[lang] = d
+			} else {
+				delete(c.LSP, lang)
+			}
+		}
+	}
+	c.MCP = nil
+	c.Verify = Verify{}
+}
+
+// Allows reports whether an approval rule, at the top level or in a mode,
+// lets tool through unasked.
+func (c Config) Allows(tool string) bool {
+	if c.Approval[tool] == ApprovalAllow {
+		return true
+	}
+	for _, m := range c.Modes {
+		if m.Approval[tool] == ApprovalAllow {
+			return true
+		}
+	}
+	return false
+}
+
+// DropAllow turns the allow rules for tool into ask.
+func (c *Config) DropAllow(tool string) {
+	if c.Approval[tool] == ApprovalAllow {
+		c.Approval[tool] = ApprovalAsk
+	}
+	for _, m := range c.Modes {
+		if m.Approval[tool] == ApprovalAllow {
+			m.Approval[tool] = ApprovalAsk
+		}
+	}
+}
+
+// trustPath is the user-level file recording, per Workspace root, the
+// Launches the developer trusted.
+func trustPath() (string, error) {
+	if path := os.Getenv(EnvTrustFile); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mini-agent", "trusted.json"), nil
+}
+
+func launchesDigest(launches []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(launches, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func readTrust() (map[string]string, string, error) {
+	path, err := trustPath()
+	if err != nil {
+		return nil, "", err
+	}
+	trusted := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return trusted, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, "", fmt.Errorf("parse %s: %w", path, err)
+	}
+	return trusted, path, nil
+}
+
+// IsTrusted reports whether the developer trusted exactly these launches
+// for the Workspace at root. Any change to them needs trust again.
+func IsTrusted(root string, launches []string) (bool, error) {
+	trusted, _, err := readTrust()
+	if err != nil {
+		return false, err
+	}
+	return trusted[root] == launchesDigest(launches), nil
+}
+
+// Trust records that the developer trusts launches for the Workspace at
+// root.
+func Trust(root string, launches []string) error {
+	trusted, path, err := readTrust()
+	if err != nil {
+		return err
+	}
+	trusted[root] = launchesDigest(launches)
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
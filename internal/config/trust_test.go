@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTrust_launches(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	t.Setenv(EnvTrustFile, filepath.Join(t.TempDir(), "trusted.json"))
+	root := t.TempDir()
+	if cfg, err := Load(root); err != nil || cfg.Launches() != nil {
+		t.Fatalf("default config launches %v, %v", cfg.Launches(), err)
+	}
+
+	writeConfig(t, root, `{
+		"lsp": {"go": {"command": ["./evil"], "extensions": [".go"]}, "rust": {"disabled": true}},
+		"mcp": {"gh": {"command": ["gh-mcp", "stdio"]}, "docs": {"url": "https://example.com/mcp"}},
+		"verify": {"command": "make check"}
+	}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	launches := cfg.Launches()
+	want := []string{"lsp.go: ./evil", "mcp.docs: https://example.com/mcp", "mcp.gh: gh-mcp stdio", "verify: make check"}
+	if !reflect.DeepEqual(launches, want) {
+		t.Fatalf("launches = %v", launches)
+	}
+
+	if ok, err := IsTrusted(root, launches); ok || err != nil {
+		t.Fatalf("trusted before asking: %v, %v", ok, err)
+	}
+	if err := Trust(root, launches); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := IsTrusted(root, launches); !ok {
+		t.Fatal("trust was not recorded")
+	}
+	if ok, _ := IsTrusted(root, launches[:2]); ok {
+		t.Fatal("changed launches are still trusted")
+	}
+
+	cfg.DropLaunches()
+	if cfg.Launches() != nil || cfg.LSP["go"].Command[0] != "gopls" || !cfg.LSP["rust"].Disabled || cfg.Verify.Command != "" {
+		t.Fatalf("after DropLaunches: %+v", cfg)
+	}
+}
+
+func TestTrust_dropAllow(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	root := t.TempDir()
+	writeConfig(t, root, `{"approval": {"lint": "allow"}, "modes": {"review": {"approval": {"lint": "allow"}}}}`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Allows("lint") || cfg.Allows("other") {
+		t.Fatal("Allows does not match the rules")
+	}
+	cfg.DropAllow("lint")
+	if cfg.Allows("lint") || cfg.Approval["lint"] != ApprovalAsk || cfg.Modes["review"].Approval["lint"] != ApprovalAsk {
+		t.Fatalf("after DropAllow: %+v", cfg)
+	}
+}
@@ -0,0 +1,522 @@
+// Package git runs the git command line against one repository and parses
+// its machine-readable output.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotRepository = errors.New("not inside a git repository")
+
+// EmptyTree is the object name of the empty tree, the base to diff against
+// before the first commit.
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Error is a git command that exited with a non-zero status.
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("git %s: %s", e.Args[0], msg)
+}
+
+// Repo is the repository containing Dir. Commands run in Dir, so pathspecs
+// are relative to it, while paths in parsed output are relative to Root.
+type Repo struct {
+	Root string
+	Dir  string
+	// Prefix is Dir relative to Root, in slash form; empty at the top.
+	Prefix string
+	// indexFile replaces the repository's index; see ScratchIndex.
+	indexFile string
+}
+
+// Open finds the repository containing dir.
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	out, err := run(ctx, dir, "", nil, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		var gitErr *Error
+		if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "not a git repository") {
+			return nil, ErrNotRepository
+		}
+		return nil, err
+	}
+	root, prefix, _ := strings.Cut(strings.TrimRight(out, "\n"), "\n")
+	return &Repo{Root: filepath.Clean(root), Dir: dir, Prefix: strings.TrimSuffix(prefix, "/")}, nil
+}
+
+// Rel converts a path from git output, relative to Root, to one relative
+// to Dir.
+func (r *Repo) Rel(path string) string {
+	if r.Prefix == "" {
+		return path
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(r.Prefix), filepath.FromSlash(path))
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Run runs git with args in the repository and returns its stdout.
+func (r *Repo) Run(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, r.Dir, r.indexFile, nil, args...)
+}
+
+// ScratchIndex returns a Repo whose commands use a copy of the index, so
+// staging through it leaves the developer's index alone. done removes the
+// copy.
+func (r *Repo) ScratchIndex(ctx context.Context) (scratch *Repo, done func(), err error) {
+	index, err := r.Run(ctx, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.MkdirTemp("", "mini-agent-index-")
+	if err != nil {
+		return nil, nil, err
+	}
+	done = func() { _ = os.RemoveAll(tmp) }
+	s := *r
+	s.indexFile = filepath.Join(tmp, "index")
+	// Without an index yet, git starts the copy empty.
+	data, err := os.ReadFile(strings.TrimSpace(index))
+	if err == nil {
+		err = os.WriteFile(s.indexFile, data, 0o600)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		done()
+		return nil, nil, err
+	}
+	return &s, done, nil
+}
+
+func run(ctx context.Context, dir, indexFile string, stdin []byte, args ...string) (string, error) {
+	// Output is never paged, coloured or quoted; prompts for credentials
+	// fail instead of hanging.
+	full := append([]string{"--no-pager", "-c", "color.ui=never", "-c", "core.quotePath=off"}, args...)
+	cmd := exec.CommandContext(ctx, "git", full...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_OPTIONAL_LOCKS=0", "LC_ALL=C")
+	if indexFile != "" {
+		cmd.Env = append(cmd.Env, "GIT_INDEX_FILE="+indexFile)
+	}
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.String(), &Error{Args: args, ExitCode: exitErr.ExitCode(), Stderr: stderr.String()}
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// literal marks paths so git matches them exactly rather than as globs.
+// The global --literal-pathspecs flag would do the same, but it breaks the
+// pathspecs git stash uses internally.
+func literal(paths []string) []string {
+	specs := make([]string, len(paths))
+	for i, p := range paths {
+		specs[i] = ":(literal)" + p
+	}
+	return specs
+}
+
+type Status struct {
+	Branch   string       `json:"branch"`
+	Upstream string       `json:"upstream,omitempty"`
+	Ahead    int          `json:"ahead,omitempty"`
+	Behind   int          `json:"behind,omitempty"`
+	Files    []FileStatus `json:"files"`
+}
+
+// FileStatus describes one changed path. Staged and Unstaged hold git's
+// status letters (M, A, D, R, C, T, U) and are empty when unchanged.
+type FileStatus struct {
+	Path       string `json:"path"`
+	OrigPath   string `json:"orig_path,omitempty"`
+	Staged     string `json:"staged,omitempty"`
+	Unstaged   string `json:"unstaged,omitempty"`
+	Untracked  bool   `json:"untracked,omitempty"`
+	Conflicted bool   `json:"conflicted,omitempty"`
+}
+
+// Status reports the branch and the changed files, optionally limited to
+// paths.
+func (r *Repo) Status(ctx context.Context, paths ...string) (*Status, error) {
+	args := append([]string{"status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all", "--"}, literal(paths)...)
+	out, err := r.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(out), nil
+}
+
+func parseStatus(out string) *Status {
+	st := &Status{Files: []FileStatus{}}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		switch {
+		case strings.HasPrefix(rec, "# branch.head "):
+			st.Branch = strings.TrimPrefix(rec, "# branch.head ")
+		case strings.HasPrefix(rec, "# branch.upstream "):
+			st.Upstream = strings.TrimPrefix(rec, "# branch.upstream ")
+		case strings.HasPrefix(rec, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(rec, "# branch.ab "), "+%d -%d", &st.Ahead, &st.Behind)
+		case strings.HasPrefix(rec, "1 "):
+			f := strings.SplitN(rec, " ", 9)
+			if len(f) == 9 {
+				st.Files = append(st.Files, changedFile(f[1], f[8]))
+			}
+		case strings.HasPrefix(rec, "2 "):
+			f := strings.SplitN(rec, " ", 10)
+			if len(f) == 10 {
+				fs := changedFile(f[1], f[9])
+				// With -z the original path is the next record.
+				if i+1 < len(records) {
+					i++
+					fs.OrigPath = records[i]
+				}
+				st.Files = append(st.Files, fs)
+			}
+		case strings.HasPrefix(rec, "u "):
+			f := strings.SplitN(rec, " ", 11)
+			if len(f) == 11 {
+				fs := changedFile(f[1], f[10])
+				fs.Conflicted = true
+				st.Files = append(st.Files, fs)
+			}
+		case strings.HasPrefix(rec, "? "):
+			st.Files = append(st.Files, FileStatus{Path: rec[2:], Untracked: true})
+		}
+	}
+	return st
+}
+
+func changedFile(xy, path string) FileStatus {
+	fs := FileStatus{Path: path}
+	if len(xy) == 2 {
+		if xy[0] != '.' {
+			fs.Staged = xy[:1]
+		}
+		if xy[1] != '.' {
+			fs.Unstaged = xy[1:]
+		}
+	}
+	return fs
+}
+
+// DiffOptions selects what Diff compares. By default it is the working tree
+// against the index; Staged compares the index against Base, or HEAD.
+type DiffOptions struct {
+	Staged bool
+	Base   string
+	Paths  []string
+	// Relative limits the diff to Dir and reports paths relative to it.
+	Relative bool
+}
+
+func (o DiffOptions) args(format ...string) []string {
+	args := append([]string{"diff", "--no-ext-diff", "--no-textconv"}, format...)
+	if o.Staged {
+		args = append(args, "--cached")
+	}
+	if o.Relative {
+		args = append(args, "--relative")
+	}
+	if o.Base != "" {
+		args = append(args, o.Base)
+	}
+	return append(append(args, "--"), literal(o.Paths)...)
+}
+
+// Diff returns the unified diff selected by opts.
+func (r *Repo) Diff(ctx context.Context, opts DiffOptions) (string, error) {
+	return r.Run(ctx, opts.args()...)
+}
+
+// FileChange is one file's line counts in a diff. Binary files have no
+// counts.
+type FileChange struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"`
+	Added    int    `json:"added"`
+	Deleted  int    `json:"deleted"`
+	Binary   bool   `json:"binary,omitempty"`
+}
+
+// DiffStat returns per-file line counts for the diff selected by opts.
+func (r *Repo) DiffStat(ctx context.Context, opts DiffOptions) ([]FileChange, error) {
+	out, err := r.Run(ctx, opts.args("--numstat", "-z", "-M")...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(out), nil
+}
+
+// parseNumstat reads --numstat -z output, where a rename is written as
+// "added\tdeleted\t\0old\0new\0".
+func parseNumstat(out string) []FileChange {
+	changes := []FileChange{}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		f := strings.SplitN(records[i], "\t", 3)
+		if len(f) != 3 {
+			continue
+		}
+		c := FileChange{Path: f[2]}
+		if f[0] == "-" {
+			c.Binary = true
+		} else {
+			c.Added, _ = strconv.Atoi(f[0])
+			c.Deleted, _ = strconv.Atoi(f[1])
+		}
+		if c.Path == "" && i+2 < len(records) {
+			c.OrigPath, c.Path = records[i+1], records[i+2]
+			i += 2
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+type Commit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email,omitempty"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+}
+
+const commitFormat = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1f%b%x1e"
+
+// Log lists up to max commits reachable from rev (HEAD when empty) that
+// touch paths, newest first.
+func (r *Repo) Log(ctx context.Context, rev string, max int, paths ...string) ([]Commit, error) {
+	args := []string{"log", commitFormat, "-n", strconv.Itoa(max)}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := r.Run(ctx, append(append(args, "--"), literal(paths)...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommits(out), nil
+}
+
+// ShowCommit returns the commit rev names.
+func (r *Repo) ShowCommit(ctx context.Context, rev string) (Commit, error) {
+	out, err := r.Run(ctx, "show", "-s", commitFormat, rev, "--")
+	if err != nil {
+		return Commit{}, err
+	}
+	commits := parseCommits(out)
+	if len(commits) == 0 {
+		return Commit{}, fmt.Errorf("git show: no commit %q", rev)
+	}
+	return commits[0], nil
+}
+
+// ShowPatch returns the changes rev introduced, optionally limited to paths.
+func (r *Repo) ShowPatch(ctx context.Context, rev string, paths ...string) (string, error) {
+	args := []string{"show", "--format=", "--patch", "--no-ext-diff", "--no-textconv", rev, "--"}
+	return r.Run(ctx, append(args, literal(paths)...)...)
+}
+
+func parseCommits(out string) []Commit {
+	commits := []Commit{}
+	for _, rec := range strings.Split(out, "\x1e") {
+		f := strings.Split(strings.TrimLeft(rec, "\n"), "\x1f")
+		if len(f) != 6 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash: f[0], Author: f[1], Email: f[2], Date: f[3], Subject: f[4],
+			Body: strings.TrimSpace(f[5]),
+		})
+	}
+	return commits
+}
+
+type BlameLine struct {
+	Line    int    `json:"line"`
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Summary string `json:"summary"`
+	Text    string `json:"text"`
+}
+
+// Blame attributes lines start through end of path (the whole file when
+// both are zero) to the commits that last changed them.
+func (r *Repo) Blame(ctx context.Context, path string, start, end int) ([]BlameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	if start > 0 || end > 0 {
+		if start < 1 {
+			start = 1
+		}
+		rng := strconv.Itoa(start) + ","
+		if end > 0 {
+			rng += strconv.Itoa(end)
+		}
+		args = append(args, "-L", rng)
+	}
+	out, err := r.Run(ctx, append(args, "--", path)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out), nil
+}
+
+// parseBlame reads --porcelain output: each line is a "hash orig final"
+// header, commit headers the first time a commit appears, and the text
+// prefixed with a tab.
+func parseBlame(out string) []BlameLine {
+	type info struct{ author, date, summary string }
+	commits := make(map[string]*info)
+	lines := []BlameLine{}
+	var cur BlameLine
+	var ci *info
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(l, "\t") {
+			cur.Text = l[1:]
+			cur.Author, cur.Date, cur.Summary = ci.author, ci.date, ci.summary
+			lines = append(lines, cur)
+			continue
+		}
+		f := strings.Fields(l)
+		if len(f) >= 3 && (len(f[0]) == 40 || len(f[0]) == 64) {
+			final, _ := strconv.Atoi(f[2])
+			cur = BlameLine{Line: final, Hash: f[0]}
+			if ci = commits[f[0]]; ci == nil {
+				ci = &info{}
+				commits[f[0]] = ci
+			}
+			continue
+		}
+		if ci == nil {
+			continue
+		}
+		key, value, _ := strings.Cut(l, " ")
+		switch key {
+		case "author":
+			ci.author = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				ci.date = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+			}
+		case "summary":
+			ci.summary = value
+		}
+	}
+	return lines
+}
+
+// Add stages paths.
+func (r *Repo) Add(ctx context.Context, paths ...string) error {
+	_, err := r.Run(ctx, append([]string{"add", "--"}, literal(paths)...)...)
+	return err
+}
+
+// AddAll stages every change in the working tree, including untracked and
+// deleted files.
+func (r *Repo) AddAll(ctx context.Context) error {
+	_, err := r.Run(ctx, "add", "--all")
+	return err
+}
+
+// Commit records the staged changes with message and returns the new
+// commit.
+func (r *Repo) Commit(ctx context.Context, message string) (Commit, error) {
+	if _, err := run(ctx, r.Dir, r.indexFile, []byte(message), "commit", "--file=-"); err != nil {
+		return Commit{}, err
+	}
+	return r.ShowCommit(ctx, "HEAD")
+}
+
+// HasHead reports whether HEAD names a commit, which it does not in a new
+// repository.
+func (r *Repo) HasHead(ctx context.Context) bool {
+	_, err := r.Run(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+// HasStagedChanges reports whether the index differs from HEAD.
+func (r *Repo) HasStagedChanges(ctx context.Context) (bool, error) {
+	_, err := r.Run(ctx, "diff", "--cached", "--quiet")
+	var gitErr *Error
+	if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// CheckoutFiles restores paths from rev, or from the index when rev is
+// empty, discarding their working tree changes.
+func (r *Repo) CheckoutFiles(ctx context.Context, rev string, paths ...string) error {
+	args := []string{"checkout"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	_, err := r.Run(ctx, append(append(args, "--"), literal(paths)...)...)
+	return err
+}
+
+type StashEntry struct {
+	Ref     string `json:"ref"`
+	Message string `json:"message"`
+}
+
+// StashPush stashes working tree and index changes, including untracked
+// files.
+func (r *Repo) StashPush(ctx context.Context, message string) error {
+	args := []string{"stash", "push", "--include-untracked"}
+	if message != "" {
+		args = append(args, "--message", message)
+	}
+	_, err := r.Run(ctx, args...)
+	return err
+}
+
+// StashPop applies the latest stash and drops it.
+func (r *Repo) StashPop(ctx context.Context) error {
+	_, err := r.Run(ctx, "stash", "pop")
+	return err
+}
+
+func (r *Repo) StashList(ctx context.Context) ([]StashEntry, error) {
+	out, err := r.Run(ctx, "stash", "list", "--format=%gd%x1f%gs")
+	if err != nil {
+		return nil, err
+	}
+	entries := []StashEntry{}
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		if ref, msg, ok := strings.Cut(l, "\x1f"); ok {
+			entries = append(entries, StashEntry{Ref: ref, Message: msg})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,288 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit containing a.txt and
+// isolates git from the user's configuration.
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Dev")
+	t.Setenv("GIT_AUTHOR_EMAIL", "dev@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Dev")
+	t.Setenv("GIT_COMMITTER_EMAIL", "dev@example.com")
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	if _, err := run(ctx, dir, "", nil, "init", "-q", "-b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.txt", "one\ntwo\n")
+	repo, err := Open(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Commit(ctx, "Add a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen_notRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	if _, err := Open(context.Background(), t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("Open = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestRepo_relFromSubdirectory(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo.Dir, "sub/b.txt", "b\n")
+	sub, err := Open(context.Background(), filepath.Join(repo.Dir, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Prefix != "sub" || sub.Rel("sub/b.txt") != "b.txt" || sub.Rel("a.txt") != "../a.txt" {
+		t.Fatalf("prefix = %q, rel = %q, %q", sub.Prefix, sub.Rel("sub/b.txt"), sub.Rel("a.txt"))
+	}
+}
+
+func TestStatus_changes(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	writeFile(t, repo.Dir, "a.txt", "one\n2\n")
+	writeFile(t, repo.Dir, "dir/new file.txt", "x\n")
+	if _, err := repo.Run(ctx, "mv", "a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := repo.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Branch != "main" || len(st.Files) != 2 {
+		t.Fatalf("status = %+v", st)
+	}
+	renamed, untracked := st.Files[0], st.Files[1]
+	if renamed.Path != "b.txt" || renamed.OrigPath != "a.txt" || renamed.Staged != "R" || renamed.Unstaged != "M" {
+		t.Fatalf("renamed = %+v", renamed)
+	}
+	if untracked.Path != "dir/new file.txt" || !untracked.Untracked {
+		t.Fatalf("untracked = %+v", untracked)
+	}
+}
+
+func TestDiffStat_stagedAndWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	writeFile(t, repo.Dir, "a.txt", "one\ntwo\nthree\n")
+	if err := repo.Add(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo.Dir, "a.txt", "three\n")
+
+	staged, err := repo.DiffStat(ctx, DiffOptions{Staged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged) != 1 || staged[0].Added != 1 || staged[0].Deleted != 0 {
+		t.Fatalf("staged = %+v", staged)
+	}
+	unstaged, err := repo.DiffStat(ctx, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unstaged) != 1 || unstaged[0].Deleted != 2 {
+		t.Fatalf("unstaged = %+v", unstaged)
+	}
+	diff, err := repo.Diff(ctx, DiffOptions{Base: "HEAD", Paths: []string{"a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+three") || !strings.Contains(diff, "-one") {
+		t.Fatalf("diff = %q", diff)
+	}
+}
+
+func TestParseNumstat_rename(t *testing.T) {
+	got := parseNumstat("1\t2\t\x00old.go\x00new.go\x00-\t-\tlogo.png\x00")
+	if len(got) != 2 || got[0].OrigPath != "old.go" || got[0].Path != "new.go" || got[0].Deleted != 2 || !got[1].Binary {
+		t.Fatalf("numstat = %+v", got)
+	}
+}
+
+func TestLogAndBlame(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	writeFile(t, repo.Dir, "a.txt", "one\nTWO\n")
+	if err := repo.Add(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Commit(ctx, "Shout two\n\nBecause it matters.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Subject != "Shout two" || second.Body != "Because it matters." {
+		t.Fatalf("commit = %+v", second)
+	}
+
+	commits, err := repo.Log(ctx, "", 10, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Hash != second.Hash || commits[1].Subject != "Add a.txt" {
+		t.Fatalf("log = %+v", commits)
+	}
+
+	lines, err := repo.Blame(ctx, "a.txt", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Line != 2 || lines[0].Text != "TWO" || lines[0].Hash != second.Hash || lines[0].Summary != "Shout two" || lines[0].Author != "Dev" {
+		t.Fatalf("blame = %+v", lines)
+	}
+}
+
+func TestStashAndCheckout(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	writeFile(t, repo.Dir, "a.txt", "changed\n")
+	if err := repo.CheckoutFiles(ctx, "", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo.Dir, "a.txt")); string(got) != "one\ntwo\n" {
+		t.Fatalf("after checkout = %q", got)
+	}
+
+	writeFile(t, repo.Dir, "new.txt", "n\n")
+	if err := repo.StashPush(ctx, "wip"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatal("untracked file was not stashed")
+	}
+	entries, err := repo.StashList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Ref != "stash@{0}" || !strings.Contains(entries[0].Message, "wip") {
+		t.Fatalf("stash list = %+v", entries)
+	}
+	if err := repo.StashPop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, "new.txt")); err != nil {
+		t.Fatal("stash pop did not restore new.txt")
+	}
+}
+
+func TestHasStagedChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	if staged, err := repo.HasStagedChanges(ctx); err != nil || staged {
+		t.Fatalf("clean: staged=%v err=%v", staged, err)
+	}
+	writeFile(t, repo.Dir, "a.txt", "x\n")
+	if err := repo.Add(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if staged, err := repo.HasStagedChanges(ctx); err != nil || !staged {
+		t.Fatalf("after add: staged=%v err=%v", staged, err)
+	}
+}
+
+func TestWorktree_mergeIntoBaseBranch(t *testing.T) {
+	origin := newTestRepo(t)
+	ctx := context.Background()
+	w, err := CreateWorktree(ctx, origin, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Branch != "mini-agent/s1" || w.BaseBranch != "main" {
+		t.Fatalf("worktree = %+v", w)
+	}
+	if status, _ := origin.Status(ctx); len(status.Files) != 0 {
+		t.Fatalf("worktree shows up in the original checkout: %+v", status.Files)
+	}
+	writeFile(t, w.Dir, "a.txt", "one\n2\n")
+	writeFile(t, w.Dir, "new.txt", "new\n")
+	changes, err := w.Changes(ctx)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("changes = %+v, %v", changes, err)
+	}
+	if diff, _ := w.Diff(ctx); !strings.Contains(diff, "+new") {
+		t.Fatalf("diff = %q", diff)
+	}
+
+	if err := w.Merge(ctx, "Session changes"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(origin.Dir, "new.txt")); string(data) != "new\n" {
+		t.Fatalf("new.txt = %q", data)
+	}
+	if _, err := os.Stat(w.Root); !os.IsNotExist(err) {
+		t.Fatalf("worktree not removed: %v", err)
+	}
+	if out, _ := origin.Run(ctx, "branch", "--list", w.Branch); out != "" {
+		t.Fatalf("branch not deleted: %q", out)
+	}
+}
+
+func TestWorktree_conflictKeepsWorktree(t *testing.T) {
+	origin := newTestRepo(t)
+	ctx := context.Background()
+	w, err := CreateWorktree(ctx, origin, "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, w.Dir, "a.txt", "worktree\n")
+	writeFile(t, origin.Dir, "a.txt", "origin\n")
+	if err := origin.AddAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := origin.Commit(ctx, "Change a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Merge(ctx, "Session changes"); err == nil {
+		t.Fatal("conflicting merge succeeded")
+	}
+	if status, _ := origin.Status(ctx); len(status.Files) != 0 {
+		t.Fatalf("merge was not aborted: %+v", status.Files)
+	}
+	if _, err := os.Stat(w.Root); err != nil {
+		t.Fatalf("worktree removed after a failed merge: %v", err)
+	}
+	if err := w.Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BranchPrefix starts the name of every branch CreateWorktree makes.
+const BranchPrefix = "mini-agent/"
+
+var ErrDetachedHead = errors.New("the original checkout is not on a branch")
+
+// Worktree is a linked working tree on its own branch, created so a Session
+// can change files without touching the user's checkout. The embedded Repo
+// runs in the worktree.
+type Worktree struct {
+	*Repo
+	// Origin is the checkout the worktree was created from.
+	Origin *Repo
+	Branch string
+	// BaseBranch is the branch Origin had checked out, empty when its HEAD
+	// was detached. Base is the commit the worktree started from.
+	BaseBranch string
+	Base       string
+}
+
+// CreateWorktree adds a worktree of origin's HEAD on a new branch named
+// BranchPrefix+name. It lives in the repository's git directory, so it never
+// shows up in the original checkout. Uncommitted changes in origin are not
+// carried over.
+func CreateWorktree(ctx context.Context, origin *Repo, name string) (*Worktree, error) {
+	base, err := origin.Run(ctx, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	if err != nil {
+		return nil, errors.New("the repository has no commit to start a worktree from")
+	}
+	gitDir, err := origin.Run(ctx, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	baseBranch, _ := origin.Run(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	w := &Worktree{
+		Origin:     origin,
+		Branch:     BranchPrefix + name,
+		BaseBranch: strings.TrimSpace(baseBranch),
+		Base:       strings.TrimSpace(base),
+	}
+	root := filepath.Join(strings.TrimSpace(gitDir), "mini-agent", "worktrees", name)
+	if _, err := origin.Run(ctx, "worktree", "add", "--quiet", "-b", w.Branch, root, w.Base); err != nil {
+		return nil, err
+	}
+	// Keep the Workspace at the same place inside the tree.
+	dir := filepath.Join(root, filepath.FromSlash(origin.Prefix))
+	if w.Repo, err = Open(ctx, dir); err != nil {
+		_, _ = origin.Run(ctx, "worktree", "remove", "--force", root)
+		_, _ = origin.Run(ctx, "branch", "-D", w.Branch)
+		return nil, err
+	}
+	return w, nil
+}
+
+// Changes lists the files the worktree changed since Base, committed or
+// not, including untracked files.
+func (w *Worktree) Changes(ctx context.Context) ([]FileChange, error) {
+	if err := w.addIntents(ctx); err != nil {
+		return nil, err
+	}
+	return w.DiffStat(ctx, DiffOptions{Base: w.Base})
+}
+
+// Diff is the unified diff of Changes.
+func (w *Worktree) Diff(ctx context.Context) (string, error) {
+	if err := w.addIntents(ctx); err != nil {
+		return "", err
+	}
+	return w.Repo.Diff(ctx, DiffOptions{Base: w.Base})
+}
+
+// addIntents records untracked files in the index without their content,
+// so diffs against Base include them.
+func (w *Worktree) addIntents(ctx context.Context) error {
+	_, err := w.Run(ctx, "add", "--all", "--intent-to-add")
+	return err
+}
+
+// Merge commits everything left in the worktree with message and merges
+// the branch into BaseBranch in Origin, which must still have it checked
+// out. A conflicting merge is aborted and the worktree kept. On success the
+// worktree and its branch are removed.
+func (w *Worktree) Merge(ctx context.Context, message string) error {
+	if w.BaseBranch == "" {
+		return ErrDetachedHead
+	}
+	if current, _ := w.Origin.Run(ctx, "symbolic-ref", "--quiet", "--short", "HEAD"); strings.TrimSpace(current) != w.BaseBranch {
+		return fmt.Errorf("the original checkout is no longer on %s", w.BaseBranch)
+	}
+	if err := w.AddAll(ctx); err != nil {
+		return err
+	}
+	if staged, err := w.HasStagedChanges(ctx); err != nil {
+		return err
+	} else if staged {
+		if _, err := w.Commit(ctx, message); err != nil {
+			return err
+		}
+	}
+	ahead, err := w.Run(ctx, "rev-list", "--count", w.Base+"..HEAD")
+	if err != nil {
+		return err
+	}
+	if n, _ := strconv.Atoi(strings.TrimSpace(ahead)); n > 0 {
+		if _, err := w.Origin.Run(ctx, "merge", "--no-ff", "--no-edit", "-m", "Merge "+w.Branch, w.Branch); err != nil {
+			_, _ = w.Origin.Run(ctx, "merge", "--abort")
+			return err
+		}
+	}
+	return w.Remove(ctx)
+}
+
+// Remove deletes the worktree, discarding its changes, and its branch.
+func (w *Worktree) Remove(ctx context.Context) error {
+	if _, err := w.Origin.Run(ctx, "worktree", "remove", "--force", w.Root); err != nil {
+		return err
+	}
+	_, err := w.Origin.Run(ctx, "branch", "-D", w.Branch)
+	return err
+}
@@ -0,0 +1,254 @@
+// Package jsonrpc implements a bidirectional JSON-RPC 2.0 connection over
+// a pluggable message framing, shared by the LSP and MCP integrations.
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+func MethodNotFound(method string) *Error {
+	return &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
+
+// Message is a request, notification or response on the wire.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+func (m *Message) isResponse() bool { return m.Method == "" && len(m.ID) > 0 }
+
+// Stream reads and writes whole framed messages.
+type Stream interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage([]byte) error
+	Close() error
+}
+
+// Handler serves requests and notifications sent by the peer. For
+// notifications (isNotify) the result is discarded; they are delivered one
+// at a time in order, so the handler must not block on them.
+type Handler func(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error)
+
+// Conn is one JSON-RPC peer. Calls may be issued concurrently.
+type Conn struct {
+	stream  Stream
+	handler Handler
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *Message
+	err     error
+	done    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewConn starts reading from stream. handler may be nil, in which case
+// every incoming request is answered with "method not found".
+func NewConn(stream Stream, handler Handler) *Conn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		stream:  stream,
+		handler: handler,
+		pending: make(map[string]chan *Message),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go c.readLoop()
+	return c
+}
+
+// Call sends a request and decodes the response result into result, which
+// may be nil to discard it.
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	ch := make(chan *Message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(&Message{ID: json.RawMessage(id), Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification.
+func (c *Conn) Notify(method string, params any) error {
+	return c.send(&Message{Method: method}, params)
+}
+
+func (c *Conn) send(msg *Message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	return c.write(msg)
+}
+
+func (c *Conn) write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.stream.WriteMessage(data)
+}
+
+// Close closes the stream and fails outstanding calls.
+func (c *Conn) Close() error {
+	err := c.stream.Close()
+	c.fail(ErrClosed)
+	return err
+}
+
+// Done is closed once the connection stops reading.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Err reports why the connection stopped.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.cancel()
+	close(c.done)
+}
+
+func (c *Conn) readLoop() {
+	for {
+		data, err := c.stream.ReadMessage()
+		if err != nil {
+			c.fail(fmt.Errorf("%w: %v", ErrClosed, err))
+			return
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			_ = c.write(&Message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.isResponse() {
+			c.mu.Lock()
+			ch, ok := c.pending[string(msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+			continue
+		}
+		// Notifications are handled in arrival order, which protocols such
+		// as LSP depend on; requests may block (e.g. waiting on the user),
+		// so they must not stall the read loop.
+		if len(msg.ID) == 0 {
+			c.handle(&msg)
+		} else {
+			go c.handle(&msg)
+		}
+	}
+}
+
+func (c *Conn) handle(msg *Message) {
+	isNotify := len(msg.ID) == 0
+	var (
+		result any
+		err    error
+	)
+	if c.handler == nil {
+		err = MethodNotFound(msg.Method)
+	} else {
+		result, err = c.handler(c.ctx, msg.Method, msg.Params, isNotify)
+	}
+	if isNotify {
+		return
+	}
+
+	resp := &Message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		raw, merr := json.Marshal(result)
+		if merr != nil {
+			resp.Error = &Error{Code: CodeInternalError, Message: merr.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	_ = c.write(resp)
+}
@@ -0,0 +1,131 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func connPair(t *testing.T, serverHandler, clientHandler Handler) (client, server *Conn) {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	client = NewConn(NewHeaderStream(cr, cw), clientHandler)
+	server = NewConn(NewHeaderStream(sr, sw), serverHandler)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestConn_callAndNotify(t *testing.T) {
+	notified := make(chan string, 1)
+	client, _ := connPair(t, func(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+		switch method {
+		case "add":
+			var nums []int
+			if err := json.Unmarshal(params, &nums); err != nil {
+				return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+			}
+			return nums[0] + nums[1], nil
+		case "ping":
+			notified <- string(params)
+			return nil, nil
+		}
+		return nil, MethodNotFound(method)
+	}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var sum int
+	if err := client.Call(ctx, "add", []int{2, 3}, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 5 {
+		t.Fatalf("sum = %d", sum)
+	}
+
+	err := client.Call(ctx, "missing", nil, nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Fatalf("err = %v, want method not found", err)
+	}
+
+	if err := client.Notify("ping", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-notified:
+		if got != `{"n":1}` {
+			t.Fatalf("params = %s", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestConn_serverToClientRequest(t *testing.T) {
+	_, server := connPair(t, nil, func(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+		return map[string]string{"method": method}, nil
+	})
+	var got map[string]string
+	if err := server.Call(context.Background(), "workspace/configuration", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["method"] != "workspace/configuration" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestConn_closeFailsPendingCalls(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	client, _ := connPair(t, func(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+		<-block
+		return nil, nil
+	}, nil)
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call(context.Background(), "slow", nil, nil) }()
+	time.Sleep(20 * time.Millisecond)
+	client.Close()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("err = %v, want ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not return after Close")
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestLineStream(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	in := "{\"a\":1}\n\n  {\"b\":\"" + long + "\"}\r\n{\"c\":3}"
+	var out bytes.Buffer
+	s := NewLineStream(strings.NewReader(in), nopWriteCloser{&out})
+	for _, want := range []string{`{"a":1}`, `{"b":"` + long + `"}`, `{"c":3}`} {
+		got, err := s.ReadMessage()
+		if err != nil || string(got) != want {
+			t.Fatalf("ReadMessage = %.40q, %v; want %.40q", got, err, want)
+		}
+	}
+	if _, err := s.ReadMessage(); err != io.EOF {
+		t.Fatalf("ReadMessage at end = %v, want EOF", err)
+	}
+	if err := s.WriteMessage([]byte(`{"d":4}`)); err != nil || out.String() != "{\"d\":4}\n" {
+		t.Fatalf("WriteMessage wrote %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,121 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxMessageBytes bounds a single framed message.
+const maxMessageBytes = 64 << 20
+
+// headerStream frames messages with Content-Length headers, as used by the
+// Language Server Protocol.
+type headerStream struct {
+	r *bufio.Reader
+	w io.WriteCloser
+}
+
+// NewHeaderStream reads Content-Length framed messages from r and writes
+// them to w. Closing the stream closes w.
+func NewHeaderStream(r io.Reader, w io.WriteCloser) Stream {
+	return &headerStream{r: bufio.NewReader(r), w: w}
+}
+
+func (s *headerStream) ReadMessage() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 || n > maxMessageBytes {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+			length = n
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *headerStream) WriteMessage(data []byte) error {
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err := s.w.Write(data)
+	return err
+}
+
+func (s *headerStream) Close() error { return s.w.Close() }
+
+// lineStream frames messages as single lines of JSON, as used by the Model
+// Context Protocol's stdio transport.
+type lineStream struct {
+	r *bufio.Reader
+	w io.WriteCloser
+}
+
+// NewLineStream reads newline-delimited messages from r and writes them to
+// w. Closing the stream closes w.
+func NewLineStream(r io.Reader, w io.WriteCloser) Stream {
+	return &lineStream{r: bufio.NewReader(r), w: w}
+}
+
+func (s *lineStream) ReadMessage() ([]byte, error) {
+	for {
+		line, err := s.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			line, err = s.readLong(line)
+		}
+		if msg := bytes.TrimSpace(line); len(msg) > 0 && (err == nil || err == io.EOF) {
+			// ReadSlice returns a view of the buffer.
+			return append([]byte(nil), msg...), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readLong finishes a line longer than the read buffer.
+func (s *lineStream) readLong(prefix []byte) ([]byte, error) {
+	line := append([]byte(nil), prefix...)
+	for {
+		more, err := s.r.ReadSlice('\n')
+		line = append(line, more...)
+		if len(line) > maxMessageBytes {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageBytes)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+func (s *lineStream) WriteMessage(data []byte) error {
+	// Encoded JSON never contains a raw newline.
+	_, err := s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *lineStream) Close() error { return s.w.Close() }
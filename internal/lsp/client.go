@@ -0,0 +1,364 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+)
+
+const (
+	initializeTimeout = 30 * time.Second
+	shutdownTimeout   = 2 * time.Second
+	maxStderrBytes    = 4096
+)
+
+var errOverlappingEdits = errors.New("language server returned overlapping edits")
+
+// Client is a running language server for one language in the Workspace.
+type Client struct {
+	lang string
+	cmd  *exec.Cmd
+	conn *jsonrpc.Conn
+
+	stderr *limitedBuffer
+	exited chan struct{}
+
+	mu    sync.Mutex
+	docs  map[string]*document
+	diags map[string]diagnosticSet
+	// published is closed and replaced whenever diagnostics arrive.
+	published chan struct{}
+	seq       uint64
+}
+
+// document is a file opened on the server and the text it was last sent.
+type document struct {
+	version int
+	text    string
+}
+
+type diagnosticSet struct {
+	seq   uint64
+	items []Diagnostic
+}
+
+// startClient launches server in root and performs the initialize
+// handshake.
+func startClient(ctx context.Context, lang, root string, server config.LanguageServer) (*Client, error) {
+	cmd := exec.Command(server.Command[0], server.Command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		lang:      lang,
+		cmd:       cmd,
+		stderr:    &limitedBuffer{max: maxStderrBytes},
+		exited:    make(chan struct{}),
+		docs:      make(map[string]*document),
+		diags:     make(map[string]diagnosticSet),
+		published: make(chan struct{}),
+	}
+	cmd.Stderr = c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s language server: %w", lang, err)
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(c.exited)
+	}()
+	c.conn = jsonrpc.NewConn(jsonrpc.NewHeaderStream(stdout, stdin), c.handle)
+
+	ctx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+	if err := c.initialize(ctx, root); err != nil {
+		c.kill()
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("initialize %s language server: %w", lang, err)
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context, root string) error {
+	rootURI := pathToURI(root)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"clientInfo": map[string]any{
+			"name": "mini-agent",
+		},
+		"rootUri": rootURI,
+		"workspaceFolders": []map[string]any{
+			{"uri": rootURI, "name": root},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": false},
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"definition":         map[string]any{"linkSupport": true},
+				"references":         map[string]any{},
+				"rename":             map[string]any{"prepareSupport": false},
+			},
+			"workspace": map[string]any{
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+				"configuration":    true,
+				"workspaceFolders": true,
+			},
+		},
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return err
+	}
+	return c.conn.Notify("initialized", map[string]any{})
+}
+
+// handle answers the requests and notifications a server sends to its
+// client. Only diagnostics carry information; the rest get neutral replies.
+func (c *Client) handle(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil {
+			c.storeDiagnostics(p)
+		}
+		return nil, nil
+	case "workspace/configuration":
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "window/workDoneProgress/create", "client/registerCapability",
+		"client/unregisterCapability", "window/showMessageRequest":
+		return nil, nil
+	}
+	if isNotify {
+		return nil, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+func (c *Client) storeDiagnostics(p publishDiagnosticsParams) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if doc, ok := c.docs[p.URI]; ok && p.Version != nil && *p.Version < doc.version {
+		// Diagnostics for text the server has since been sent.
+		return
+	}
+	c.seq++
+	c.diags[p.URI] = diagnosticSet{seq: c.seq, items: p.Diagnostics}
+	close(c.published)
+	c.published = make(chan struct{})
+}
+
+// sync sends the current content of path to the server: didOpen the first
+// time, didChange when it differs from what was sent, didClose when the file
+// is gone. It returns the diagnostics sequence number a fresh publish must
+// exceed, and whether anything was sent.
+func (c *Client) sync(path string) (uint64, bool, error) {
+	uri := pathToURI(path)
+	data, err := os.ReadFile(path)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !missing {
+		return 0, false, err
+	}
+
+	c.mu.Lock()
+	doc, open := c.docs[uri]
+	seq := c.seq
+	switch {
+	case missing && !open:
+		c.mu.Unlock()
+		return seq, false, nil
+	case missing:
+		delete(c.docs, uri)
+		delete(c.diags, uri)
+		c.mu.Unlock()
+		return seq, false, c.conn.Notify("textDocument/didClose", map[string]any{
+			"textDocument": textDocumentIdentifier{URI: uri},
+		})
+	case open && doc.text == string(data):
+		c.mu.Unlock()
+		return seq, false, nil
+	case open:
+		doc.version++
+		doc.text = string(data)
+		version := doc.version
+		c.mu.Unlock()
+		return seq, true, c.conn.Notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]any{{"text": string(data)}},
+		})
+	default:
+		c.docs[uri] = &document{version: 1, text: string(data)}
+		c.mu.Unlock()
+		return seq, true, c.conn.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        uri,
+				"languageId": c.lang,
+				"version":    1,
+				"text":       string(data),
+			},
+		})
+	}
+}
+
+// closeUnder sends didClose for open documents at or below path, which no
+// longer exists.
+func (c *Client) closeUnder(path string) {
+	prefix := pathToURI(path)
+	c.mu.Lock()
+	var closed []string
+	for uri := range c.docs {
+		if uri == prefix || strings.HasPrefix(uri, prefix+"/") {
+			delete(c.docs, uri)
+			delete(c.diags, uri)
+			closed = append(closed, uri)
+		}
+	}
+	c.mu.Unlock()
+	for _, uri := range closed {
+		_ = c.conn.Notify("textDocument/didClose", map[string]any{
+			"textDocument": textDocumentIdentifier{URI: uri},
+		})
+	}
+}
+
+// waitDiagnostics blocks until diagnostics newer than after are published
+// for path, or ctx is done, and returns the current set.
+func (c *Client) waitDiagnostics(ctx context.Context, path string, after uint64) ([]Diagnostic, bool) {
+	uri := pathToURI(path)
+	for {
+		c.mu.Lock()
+		set, ok := c.diags[uri]
+		published := c.published
+		c.mu.Unlock()
+		if ok && set.seq > after {
+			return set.items, true
+		}
+		select {
+		case <-published:
+		case <-ctx.Done():
+			return set.items, false
+		case <-c.conn.Done():
+			return set.items, false
+		}
+	}
+}
+
+func (c *Client) diagnostics(path string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diags[pathToURI(path)].items
+}
+
+// allDiagnostics returns every non-empty diagnostic set keyed by file path.
+func (c *Client) allDiagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]Diagnostic)
+	for uri, set := range c.diags {
+		if len(set.items) > 0 {
+			out[uriToPath(uri)] = set.items
+		}
+	}
+	return out
+}
+
+// text returns the content last sent for path, falling back to disk.
+func (c *Client) text(path string) string {
+	c.mu.Lock()
+	doc, ok := c.docs[pathToURI(path)]
+	c.mu.Unlock()
+	if ok {
+		return doc.text
+	}
+	data, _ := os.ReadFile(path)
+	return string(data)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, pos Position, extra map[string]any, result any) error {
+	params := map[string]any{
+		"textDocument": textDocumentIdentifier{URI: pathToURI(path)},
+		"position":     pos,
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	return c.conn.Call(ctx, method, params, result)
+}
+
+func (c *Client) alive() bool {
+	select {
+	case <-c.conn.Done():
+		return false
+	case <-c.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// shutdown asks the server to exit, killing it if it does not.
+func (c *Client) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.conn.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.conn.Notify("exit", nil)
+	}
+	_ = c.conn.Close()
+	select {
+	case <-c.exited:
+	case <-ctx.Done():
+		c.kill()
+	}
+}
+
+func (c *Client) kill() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+	_ = c.cmd.Process.Kill()
+	<-c.exited
+}
+
+// limitedBuffer keeps the first max bytes of a server's stderr for error
+// messages.
+type limitedBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
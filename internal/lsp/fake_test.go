@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+)
+
+// envFakeServer makes the test binary act as a language server; see
+// TestMain.
+const envFakeServer = "MINI_AGENT_FAKE_LSP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envFakeServer) == "1" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer is a tiny language server. Every line containing BROKEN gets
+// an error diagnostic; hover, definition, references and rename work on
+// whole-word matches within the requested document only.
+type fakeServer struct {
+	conn *jsonrpc.Conn
+	mu   sync.Mutex
+	docs map[string]string
+	exit chan struct{}
+}
+
+func runFakeServer() {
+	s := &fakeServer{docs: make(map[string]string), exit: make(chan struct{})}
+	s.conn = jsonrpc.NewConn(jsonrpc.NewHeaderStream(os.Stdin, os.Stdout), s.handle)
+	select {
+	case <-s.exit:
+	case <-s.conn.Done():
+	}
+}
+
+func (s *fakeServer) handle(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+	var p struct {
+		TextDocument struct {
+			URI     string `json:"uri"`
+			Text    string `json:"text"`
+			Version int    `json:"version"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+		Position Position `json:"position"`
+		NewName  string   `json:"newName"`
+	}
+	_ = json.Unmarshal(params, &p)
+	uri := p.TextDocument.URI
+
+	switch method {
+	case "initialize":
+		return map[string]any{"capabilities": map[string]any{"textDocumentSync": 1}}, nil
+	case "initialized", "shutdown", "textDocument/didClose":
+		return nil, nil
+	case "exit":
+		close(s.exit)
+		return nil, nil
+	case "textDocument/didOpen":
+		s.setText(uri, p.TextDocument.Text, p.TextDocument.Version)
+		return nil, nil
+	case "textDocument/didChange":
+		s.setText(uri, p.ContentChanges[len(p.ContentChanges)-1].Text, p.TextDocument.Version)
+		return nil, nil
+	}
+
+	word, _ := s.wordAt(uri, p.Position)
+	switch method {
+	case "textDocument/hover":
+		if word == "" {
+			return nil, nil
+		}
+		return map[string]any{"contents": map[string]any{"kind": "markdown", "value": "```go\nfunc " + word + "()\n```"}}, nil
+	case "textDocument/definition":
+		locs := s.occurrences(uri, word)
+		if len(locs) == 0 {
+			return nil, nil
+		}
+		return locs[0], nil
+	case "textDocument/references":
+		return s.occurrences(uri, word), nil
+	case "textDocument/rename":
+		var edits []TextEdit
+		for _, loc := range s.occurrences(uri, word) {
+			edits = append(edits, TextEdit{Range: loc.Range, NewText: p.NewName})
+		}
+		return WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}}, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+func (s *fakeServer) setText(uri, text string, version int) {
+	s.mu.Lock()
+	s.docs[uri] = text
+	s.mu.Unlock()
+
+	diags := []Diagnostic{}
+	for i, line := range strings.Split(text, "\n") {
+		if col := strings.Index(line, "BROKEN"); col >= 0 {
+			diags = append(diags, Diagnostic{
+				Range:    Range{Start: Position{Line: i, Character: col}, End: Position{Line: i, Character: col + 6}},
+				Severity: SeverityError,
+				Source:   "fake",
+				Message:  "broken code",
+			})
+		}
+	}
+	_ = s.conn.Notify("textDocument/publishDiagnostics", map[string]any{
+		"uri": uri, "version": version, "diagnostics": diags,
+	})
+}
+
+func (s *fakeServer) wordAt(uri string, pos Position) (string, bool) {
+	s.mu.Lock()
+	text := s.docs[uri]
+	s.mu.Unlock()
+	line, ok := lineAt(text, pos.Line)
+	if !ok {
+		return "", false
+	}
+	runes := []rune(line)
+	start := min(pos.Character, len(runes))
+	end := start
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isWordRune(runes[end]) {
+		end++
+	}
+	return string(runes[start:end]), start < end
+}
+
+func (s *fakeServer) occurrences(uri, word string) []Location {
+	if word == "" {
+		return nil
+	}
+	s.mu.Lock()
+	text := s.docs[uri]
+	s.mu.Unlock()
+	w := []rune(word)
+	var locs []Location
+	for i, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for j := 0; j+len(w) <= len(runes); j++ {
+			end := j + len(w)
+			if string(runes[j:end]) != word || (j > 0 && isWordRune(runes[j-1])) || (end < len(runes) && isWordRune(runes[end])) {
+				continue
+			}
+			locs = append(locs, Location{URI: uri, Range: Range{
+				Start: Position{Line: i, Character: j},
+				End:   Position{Line: i, Character: end},
+			}})
+		}
+	}
+	return locs
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
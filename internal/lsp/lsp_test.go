@@ -0,0 +1,209 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const sampleSource = `package main
+
+func greet() string { return "hi" }
+
+func main() { greet() }
+`
+
+// newTestManager serves .go files in a fresh Workspace with the fake
+// language server.
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	root := t.TempDir()
+	tools.SetWorkspaceRootForTest(root)
+	tools.ResetSession()
+	t.Setenv(envFakeServer, "1")
+	m := NewManager(root, map[string]config.LanguageServer{
+		"go": {Command: []string{os.Args[0], "-test.run=^$"}, Extensions: []string{".go"}},
+	})
+	m.DiagnosticsWait = 5 * time.Second
+	t.Cleanup(m.Close)
+	return m, root
+}
+
+type toolResult struct {
+	Status string         `json:"status"`
+	Data   map[string]any `json:"data"`
+}
+
+func callTool(t *testing.T, tool tools.Tool, args map[string]any) toolResult {
+	t.Helper()
+	resp := tool.Call(context.Background(), inference.ToolCall{
+		ID:       "call-1",
+		Function: inference.Function{Name: tool.Name(), Arguments: args},
+	})
+	var res toolResult
+	if err := json.Unmarshal([]byte(resp.ModelText()), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func writeSource(t *testing.T, root, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilesChanged_reportsNewDiagnostics(t *testing.T) {
+	m, _ := newTestManager(t)
+	tools.AddMutationObserver(m)
+	t.Cleanup(tools.ResetMutationObservers)
+
+	res := callTool(t, &tools.WriteFile{}, map[string]any{"path": "main.go", "content": sampleSource})
+	if res.Status != "SUCCESS" || res.Data["diagnostics"] != nil {
+		t.Fatalf("clean write = %+v", res)
+	}
+
+	broken := strings.Replace(sampleSource, `"hi"`, `BROKEN`, 1)
+	res = callTool(t, &tools.WriteFile{}, map[string]any{"path": "main.go", "content": broken})
+	diags, _ := res.Data["diagnostics"].(string)
+	if !strings.Contains(diags, "main.go:3:30: error: broken code (fake)") {
+		t.Fatalf("diagnostics = %q", diags)
+	}
+
+	// The same problem on a shifted line is not new.
+	res = callTool(t, &tools.WriteFile{}, map[string]any{"path": "main.go", "content": "// moved\n" + broken})
+	if res.Data["diagnostics"] != nil {
+		t.Fatalf("repeated diagnostic reported again: %v", res.Data["diagnostics"])
+	}
+}
+
+func TestFilesChanged_ignoresOtherLanguages(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "notes.txt", "BROKEN")
+	if got := m.FilesChanged(context.Background(), []string{filepath.Join(root, "notes.txt")}); got != "" {
+		t.Fatalf("FilesChanged = %q", got)
+	}
+	if len(m.running()) != 0 {
+		t.Fatal("a server was started for a file nobody serves")
+	}
+}
+
+func TestDiagnostics_tool(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "main.go", "package main\n\nvar x = BROKEN\n")
+
+	res := callTool(t, &Diagnostics{m: m}, map[string]any{"path": "main.go"})
+	got, _ := json.Marshal(res.Data["diagnostics"])
+	if res.Status != "SUCCESS" || !strings.Contains(string(got), "main.go:3:9: error: broken code") {
+		t.Fatalf("diagnostics = %+v", res)
+	}
+
+	res = callTool(t, &Diagnostics{m: m}, nil)
+	got, _ = json.Marshal(res.Data["diagnostics"])
+	if !strings.Contains(string(got), "main.go:3:9") {
+		t.Fatalf("all diagnostics = %s", got)
+	}
+}
+
+func TestHoverDefinitionReferences(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "main.go", sampleSource)
+	at := map[string]any{"path": "main.go", "line": float64(5), "symbol": "greet"}
+
+	res := callTool(t, &Hover{m: m}, at)
+	if hover, _ := res.Data["hover"].(string); !strings.Contains(hover, "func greet()") {
+		t.Fatalf("hover = %+v", res)
+	}
+
+	res = callTool(t, &Definition{m: m}, at)
+	defs, _ := res.Data["definitions"].([]any)
+	if len(defs) != 1 {
+		t.Fatalf("definitions = %+v", res)
+	}
+	def := defs[0].(map[string]any)
+	if def["file"] != "main.go" || def["line"] != float64(3) || def["column"] != float64(6) {
+		t.Fatalf("definition = %v", def)
+	}
+
+	res = callTool(t, &References{m: m}, at)
+	if refs, _ := res.Data["references"].([]any); len(refs) != 2 {
+		t.Fatalf("references = %+v", res)
+	}
+}
+
+func TestResolveTarget_errors(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "main.go", sampleSource)
+	for name, args := range map[string]map[string]any{
+		"missing symbol": {"path": "main.go", "line": float64(3), "symbol": "nope"},
+		"past end":       {"path": "main.go", "line": float64(99), "symbol": "greet"},
+		"no position":    {"path": "main.go", "line": float64(3)},
+		"escape":         {"path": "../main.go", "line": float64(3), "symbol": "greet"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if res := callTool(t, &Hover{m: m}, args); res.Status != "FAILED" {
+				t.Fatalf("hover = %+v, want failure", res)
+			}
+		})
+	}
+}
+
+func TestRenameSymbol_writesEdits(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "main.go", sampleSource)
+
+	res := callTool(t, &RenameSymbol{m: m}, map[string]any{
+		"path": "main.go", "line": float64(3), "column": float64(7), "new_name": "welcome",
+	})
+	if res.Status != "SUCCESS" || res.Data["edits"] != float64(2) {
+		t.Fatalf("rename = %+v", res)
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "main.go"))
+	if want := strings.ReplaceAll(sampleSource, "greet", "welcome"); string(got) != want {
+		t.Fatalf("content = %q, want %q", got, want)
+	}
+}
+
+func TestRenameSymbol_respectsWritePolicy(t *testing.T) {
+	m, root := newTestManager(t)
+	writeSource(t, root, "main.go", sampleSource)
+	tools.SetWritePolicy(tools.WritePolicy{NewFiles: tools.WriteAllow, UnreadFiles: tools.WriteDeny})
+	t.Cleanup(func() { tools.SetWritePolicy(tools.DefaultWritePolicy) })
+
+	res := callTool(t, &RenameSymbol{m: m}, map[string]any{
+		"path": "main.go", "line": float64(3), "symbol": "greet", "new_name": "welcome",
+	})
+	if res.Status != "FAILED" {
+		t.Fatalf("rename of an unread file = %+v, want failure", res)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "main.go")); string(got) != sampleSource {
+		t.Fatal("file was modified")
+	}
+}
+
+func TestManager_startFailure(t *testing.T) {
+	root := t.TempDir()
+	tools.SetWorkspaceRootForTest(root)
+	writeSource(t, root, "main.go", sampleSource)
+	m := NewManager(root, map[string]config.LanguageServer{
+		"go": {Command: []string{filepath.Join(root, "no-such-server")}, Extensions: []string{".go"}},
+	})
+	t.Cleanup(m.Close)
+
+	res := callTool(t, &Diagnostics{m: m}, map[string]any{"path": "main.go"})
+	if res.Status != "FAILED" || !strings.Contains(res.Data["error"].(string), "start go language server") {
+		t.Fatalf("diagnostics = %+v", res)
+	}
+	if got := m.FilesChanged(context.Background(), []string{filepath.Join(root, "main.go")}); got != "" {
+		t.Fatalf("FilesChanged = %q", got)
+	}
+}
@@ -0,0 +1,286 @@
+// Package lsp runs language servers for the Workspace and exposes them to
+// the model as tools.
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+)
+
+const (
+	// defaultDiagnosticsWait bounds how long a File Mutation waits for the
+	// server to publish diagnostics for the files it changed.
+	defaultDiagnosticsWait = 3 * time.Second
+	// maxSyncFiles caps how many files under a changed directory are synced.
+	maxSyncFiles = 100
+	// maxReportedDiagnostics caps diagnostics listed in one result.
+	maxReportedDiagnostics = 50
+)
+
+var ErrNoServer = errors.New("no language server is configured for this file type")
+
+// Manager starts one language server per configured language on first use
+// and keeps it running for the rest of the process.
+type Manager struct {
+	root    string
+	servers map[string]config.LanguageServer
+	byExt   map[string]string
+
+	// DiagnosticsWait overrides defaultDiagnosticsWait when non-zero.
+	DiagnosticsWait time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*Client
+	failed  map[string]error
+}
+
+func NewManager(root string, servers map[string]config.LanguageServer) *Manager {
+	m := &Manager{
+		root:    root,
+		servers: make(map[string]config.LanguageServer),
+		byExt:   make(map[string]string),
+		clients: make(map[string]*Client),
+		failed:  make(map[string]error),
+	}
+	langs := make([]string, 0, len(servers))
+	for lang := range servers {
+		langs = append(langs, lang)
+	}
+	// The first language in name order wins an extension claimed twice.
+	sort.Strings(langs)
+	for _, lang := range langs {
+		s := servers[lang]
+		if s.Disabled || len(s.Command) == 0 {
+			continue
+		}
+		m.servers[lang] = s
+		for _, ext := range s.Extensions {
+			if _, taken := m.byExt[ext]; !taken {
+				m.byExt[ext] = lang
+			}
+		}
+	}
+	return m
+}
+
+// Enabled reports whether any language server is configured.
+func (m *Manager) Enabled() bool { return len(m.servers) > 0 }
+
+func (m *Manager) language(path string) (string, bool) {
+	lang, ok := m.byExt[filepath.Ext(path)]
+	return lang, ok
+}
+
+// clientFor returns the running server for path's language, starting it if
+// necessary. A server that failed to start is not retried.
+func (m *Manager) clientFor(ctx context.Context, path string) (*Client, error) {
+	lang, ok := m.language(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoServer, filepath.Base(path))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.clients[lang]; ok {
+		if c.alive() {
+			return c, nil
+		}
+		delete(m.clients, lang)
+	}
+	if err, ok := m.failed[lang]; ok {
+		return nil, err
+	}
+	c, err := startClient(ctx, lang, m.root, m.servers[lang])
+	if err != nil {
+		m.failed[lang] = err
+		return nil, err
+	}
+	m.clients[lang] = c
+	return c, nil
+}
+
+func (m *Manager) running() []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		out = append(out, c)
+	}
+	return out
+}
+
+// open makes sure the server has the current content of path and returns
+// its client.
+func (m *Manager) open(ctx context.Context, path string) (*Client, error) {
+	c, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := c.sync(path); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *Manager) diagnosticsWait() time.Duration {
+	if m.DiagnosticsWait > 0 {
+		return m.DiagnosticsWait
+	}
+	return defaultDiagnosticsWait
+}
+
+// pendingSync is a file sent to a server whose diagnostics are awaited.
+type pendingSync struct {
+	client *Client
+	path   string
+	before []Diagnostic
+	after  uint64
+}
+
+// FilesChanged implements tools.MutationObserver. It syncs every changed
+// file of a configured language and reports diagnostics that were not
+// present before the change.
+func (m *Manager) FilesChanged(ctx context.Context, paths []string) string {
+	var pending []pendingSync
+	for _, path := range m.expand(paths) {
+		c, err := m.clientFor(ctx, path)
+		if err != nil {
+			continue
+		}
+		before := c.diagnostics(path)
+		after, sent, err := c.sync(path)
+		if err != nil || !sent {
+			continue
+		}
+		pending = append(pending, pendingSync{client: c, path: path, before: before, after: after})
+	}
+	if len(pending) == 0 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.diagnosticsWait())
+	defer cancel()
+	var lines []string
+	for _, p := range pending {
+		now, _ := p.client.waitDiagnostics(ctx, p.path, p.after)
+		for _, d := range newDiagnostics(p.before, now) {
+			lines = append(lines, m.formatDiagnostic(p.client, p.path, d))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "New diagnostics:\n" + strings.Join(clipLines(lines), "\n")
+}
+
+// expand turns changed paths into files to sync. Directories contribute the
+// files beneath them; removed paths close any documents open under them.
+func (m *Manager) expand(paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			for _, c := range m.running() {
+				c.closeUnder(path)
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || len(files) >= maxSyncFiles {
+				return filepath.SkipAll
+			}
+			if d.IsDir() && p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if _, ok := m.language(p); ok && !d.IsDir() {
+				files = append(files, p)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// newDiagnostics returns the entries of now that were not in before,
+// comparing by severity and message so that shifted line numbers alone do
+// not make a diagnostic new.
+func newDiagnostics(before, now []Diagnostic) []Diagnostic {
+	seen := make(map[string]int)
+	for _, d := range before {
+		seen[diagnosticKey(d)]++
+	}
+	var out []Diagnostic
+	for _, d := range now {
+		k := diagnosticKey(d)
+		if seen[k] > 0 {
+			seen[k]--
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func diagnosticKey(d Diagnostic) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", d.Severity, d.Source, d.Message)
+}
+
+// formatDiagnostic renders d as file:line:column: severity: message.
+func (m *Manager) formatDiagnostic(c *Client, path string, d Diagnostic) string {
+	line, _ := lineAt(c.text(path), d.Range.Start.Line)
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", m.rel(path), d.Range.Start.Line+1,
+		runeColumn(line, d.Range.Start.Character), d.severityName(), d.Message)
+	if d.Source != "" {
+		s += " (" + d.Source + ")"
+	}
+	return s
+}
+
+func (m *Manager) rel(path string) string {
+	if rel, err := filepath.Rel(m.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func clipLines(lines []string) []string {
+	if len(lines) <= maxReportedDiagnostics {
+		return lines
+	}
+	more := len(lines) - maxReportedDiagnostics
+	return append(lines[:maxReportedDiagnostics:maxReportedDiagnostics], fmt.Sprintf("… and %d more", more))
+}
+
+// Close shuts down every running language server.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.shutdown()
+		}()
+	}
+	wg.Wait()
+}
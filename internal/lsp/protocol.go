@@ -0,0 +1,260 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The subset of the Language Server Protocol mini-agent speaks. Positions
+// are zero-based with UTF-16 character offsets, as the protocol requires.
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type locationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity int             `json:"severity,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+}
+
+func (d Diagnostic) severityName() string {
+	switch d.Severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type textDocumentEdit struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Edits []TextEdit `json:"edits"`
+	// Kind is set for create/rename/delete file operations, which are
+	// not supported.
+	Kind string `json:"kind,omitempty"`
+}
+
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []textDocumentEdit    `json:"documentChanges,omitempty"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// decodeLocations accepts every shape textDocument/definition and
+// textDocument/references may return: null, a Location, or an array of
+// Locations or LocationLinks.
+func decodeLocations(raw json.RawMessage) ([]Location, error) {
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		raw = append(append(json.RawMessage("["), raw...), ']')
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(items))
+	for _, item := range items {
+		var link locationLink
+		if err := json.Unmarshal(item, &link); err == nil && link.TargetURI != "" {
+			locs = append(locs, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+		var loc Location
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// decodeHover flattens the contents of a Hover result: MarkupContent, a
+// MarkedString, or an array of MarkedStrings.
+func decodeHover(raw json.RawMessage) string {
+	var hover struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := json.Unmarshal(raw, &hover); err != nil || len(hover.Contents) == 0 {
+		return ""
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(hover.Contents, &parts); err != nil {
+		parts = []json.RawMessage{hover.Contents}
+	}
+	var out []string
+	for _, p := range parts {
+		var s string
+		if err := json.Unmarshal(p, &s); err == nil {
+			out = append(out, s)
+			continue
+		}
+		var v struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(p, &v); err == nil {
+			out = append(out, v.Value)
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, "\n\n"))
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// lineAt returns line n (zero-based) of text without its terminator.
+func lineAt(text string, n int) (string, bool) {
+	for i := 0; i < n; i++ {
+		j := strings.IndexByte(text, '\n')
+		if j < 0 {
+			return "", false
+		}
+		text = text[j+1:]
+	}
+	if j := strings.IndexByte(text, '\n'); j >= 0 {
+		text = text[:j]
+	}
+	return strings.TrimSuffix(text, "\r"), true
+}
+
+// utf16Offset converts a byte offset within line to UTF-16 code units.
+func utf16Offset(line string, byteOff int) int {
+	n := 0
+	for _, r := range line[:min(byteOff, len(line))] {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// byteOffset converts a UTF-16 offset within line to a byte offset, clamped
+// to the line length.
+func byteOffset(line string, utf16Off int) int {
+	n := 0
+	for i, r := range line {
+		if n >= utf16Off {
+			return i
+		}
+		l := utf16.RuneLen(r)
+		if l < 0 {
+			l = 1
+		}
+		n += l
+	}
+	return len(line)
+}
+
+// offsetOf converts an LSP position to a byte offset into text.
+func offsetOf(text string, pos Position) int {
+	off := 0
+	for i := 0; i < pos.Line; i++ {
+		j := strings.IndexByte(text[off:], '\n')
+		if j < 0 {
+			return len(text)
+		}
+		off += j + 1
+	}
+	line, _ := lineAt(text[off:], 0)
+	return off + byteOffset(line, pos.Character)
+}
+
+// applyTextEdits applies non-overlapping edits to text.
+func applyTextEdits(text string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		spans = append(spans, span{offsetOf(text, e.Range.Start), offsetOf(text, e.Range.End), e.NewText})
+	}
+	// A stable sort keeps inserts at the same position in the order the
+	// server sent them.
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last || s.end < s.start {
+			return "", errOverlappingEdits
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// runeColumn converts a UTF-16 offset within line to a one-based column
+// counted in characters, which is what the tools report.
+func runeColumn(line string, utf16Off int) int {
+	return utf8.RuneCountInString(line[:byteOffset(line, utf16Off)]) + 1
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyTextEdits(t *testing.T) {
+	text := "héllo wörld\n𝄞 x = x\n"
+	edits := []TextEdit{
+		// UTF-16 offsets: 𝄞 is a surrogate pair, so x starts at 3.
+		{Range: Range{Start: Position{1, 3}, End: Position{1, 4}}, NewText: "y"},
+		{Range: Range{Start: Position{1, 7}, End: Position{1, 8}}, NewText: "y"},
+		{Range: Range{Start: Position{0, 6}, End: Position{0, 11}}, NewText: "there"},
+	}
+	got, err := applyTextEdits(text, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "héllo there\n𝄞 y = y\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	overlap := []TextEdit{
+		{Range: Range{Start: Position{0, 0}, End: Position{0, 5}}, NewText: "a"},
+		{Range: Range{Start: Position{0, 3}, End: Position{0, 6}}, NewText: "b"},
+	}
+	if _, err := applyTextEdits(text, overlap); err == nil {
+		t.Fatal("expected overlapping edits to fail")
+	}
+}
+
+func TestRuneColumn(t *testing.T) {
+	line := "𝄞é x"
+	if got := runeColumn(line, 4); got != 4 {
+		t.Fatalf("runeColumn = %d, want 4", got)
+	}
+	if got := utf16Offset(line, len("𝄞é ")); got != 4 {
+		t.Fatalf("utf16Offset = %d, want 4", got)
+	}
+}
+
+func TestDecodeLocations(t *testing.T) {
+	for name, raw := range map[string]string{
+		"single": `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}`,
+		"array":  `[{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}]`,
+		"links":  `[{"targetUri":"file:///a.go","targetRange":{},"targetSelectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			locs, err := decodeLocations(json.RawMessage(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(locs) != 1 || uriToPath(locs[0].URI) != "/a.go" || locs[0].Range.Start != (Position{1, 2}) {
+				t.Fatalf("locs = %+v", locs)
+			}
+		})
+	}
+	if locs, err := decodeLocations(json.RawMessage("null")); err != nil || locs != nil {
+		t.Fatalf("null = %v, %v", locs, err)
+	}
+}
+
+func TestDecodeHover(t *testing.T) {
+	for raw, want := range map[string]string{
+		`{"contents":{"kind":"markdown","value":"doc"}}`:          "doc",
+		`{"contents":"plain"}`:                                    "plain",
+		`{"contents":["a",{"language":"go","value":"func f()"}]}`: "a\n\nfunc f()",
+	} {
+		if got := decodeHover(json.RawMessage(raw)); got != want {
+			t.Errorf("decodeHover(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
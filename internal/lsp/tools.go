@@ -0,0 +1,397 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const maxLocationResults = 200
+
+// Tools returns the language server tools backed by m.
+func (m *Manager) Tools() []tools.Tool {
+	return []tools.Tool{
+		&Diagnostics{m: m},
+		&Hover{m: m},
+		&Definition{m: m},
+		&References{m: m},
+		&RenameSymbol{m: m},
+	}
+}
+
+// positionProperties describe how the position tools address a symbol: a
+// line plus the symbol text on it, which models get right far more often
+// than a column.
+func positionProperties() map[string]any {
+	return map[string]any{
+		"path": map[string]any{
+			"type":        "string",
+			"description": "Relative path of the source file.",
+		},
+		"line": map[string]any{
+			"type":        "integer",
+			"description": "1-based line number.",
+		},
+		"symbol": map[string]any{
+			"type":        "string",
+			"description": "The identifier on that line to look up; its first occurrence on the line is used.",
+		},
+		"column": map[string]any{
+			"type":        "integer",
+			"description": "1-based column in characters, used when symbol is omitted.",
+		},
+	}
+}
+
+func positionDefinition(name, description string, extra map[string]any, required ...string) map[string]any {
+	props := positionProperties()
+	for k, v := range extra {
+		props[k] = v
+	}
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        name,
+			"description": description,
+			"parameters": map[string]any{
+				"type":       "object",
+				"properties": props,
+				"required":   append([]string{"path", "line"}, required...),
+			},
+		},
+	}
+}
+
+// target is a resolved symbol position in an open document.
+type target struct {
+	client *Client
+	path   string
+	rel    string
+	pos    Position
+}
+
+func (m *Manager) resolveTarget(ctx context.Context, args inference.ToolCall) (target, error) {
+	rel, _ := args.Function.Arguments["path"].(string)
+	if rel == "" {
+		return target{}, errors.New("path is required")
+	}
+	path, err := tools.ResolveWorkspacePath(rel)
+	if err != nil {
+		return target{}, err
+	}
+	c, err := m.open(ctx, path)
+	if err != nil {
+		return target{}, err
+	}
+
+	line, _ := args.Function.Arguments["line"].(float64)
+	if line < 1 {
+		return target{}, errors.New("line must be a positive integer")
+	}
+	text, ok := lineAt(c.text(path), int(line)-1)
+	if !ok {
+		return target{}, fmt.Errorf("line %d is past the end of %s", int(line), rel)
+	}
+
+	var byteOff int
+	if symbol, _ := args.Function.Arguments["symbol"].(string); symbol != "" {
+		byteOff = strings.Index(text, symbol)
+		if byteOff < 0 {
+			return target{}, fmt.Errorf("symbol %q not found on line %d: %s", symbol, int(line), strings.TrimSpace(text))
+		}
+	} else if col, ok := args.Function.Arguments["column"].(float64); ok && col >= 1 {
+		byteOff = len(text)
+		for i := range text {
+			if col--; col < 1 {
+				byteOff = i
+				break
+			}
+		}
+	} else {
+		return target{}, errors.New("symbol or column is required")
+	}
+	return target{
+		client: c,
+		path:   path,
+		rel:    rel,
+		pos:    Position{Line: int(line) - 1, Character: utf16Offset(text, byteOff)},
+	}, nil
+}
+
+// locationResult is a Location as reported to the model.
+type locationResult struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+}
+
+func (m *Manager) locationResults(c *Client, locs []Location) ([]locationResult, bool) {
+	truncated := false
+	if len(locs) > maxLocationResults {
+		locs, truncated = locs[:maxLocationResults], true
+	}
+	texts := make(map[string]string)
+	out := make([]locationResult, 0, len(locs))
+	for _, loc := range locs {
+		path := uriToPath(loc.URI)
+		text, ok := texts[path]
+		if !ok {
+			text = c.text(path)
+			texts[path] = text
+		}
+		line, _ := lineAt(text, loc.Range.Start.Line)
+		out = append(out, locationResult{
+			File:   m.rel(path),
+			Line:   loc.Range.Start.Line + 1,
+			Column: runeColumn(line, loc.Range.Start.Character),
+			Text:   strings.TrimSpace(line),
+		})
+	}
+	return out, truncated
+}
+
+type Diagnostics struct{ m *Manager }
+
+func (d *Diagnostics) Name() string { return "diagnostics" }
+
+func (d *Diagnostics) ReadOnly(inference.ToolCall) bool { return true }
+
+func (d *Diagnostics) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        d.Name(),
+			"description": "Report compiler and linter diagnostics (errors, warnings) from the language server. With a path, the file is checked first; without one, every diagnostic the server has reported so far is listed.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{
+						"type":        "string",
+						"description": "Relative path of a source file. Omit to list all known diagnostics.",
+					},
+				},
+				"required": []string{},
+			},
+		},
+	}
+}
+
+func (d *Diagnostics) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	m := d.m
+	if rel, _ := args.Function.Arguments["path"].(string); rel != "" {
+		path, err := tools.ResolveWorkspacePath(rel)
+		if err != nil {
+			return tools.Failure(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return tools.Failure(err)
+		}
+		c, err := m.clientFor(ctx, path)
+		if err != nil {
+			return tools.Failure(err)
+		}
+		after, sent, err := c.sync(path)
+		if err != nil {
+			return tools.Failure(err)
+		}
+		items := c.diagnostics(path)
+		if sent {
+			wctx, cancel := context.WithTimeout(ctx, m.diagnosticsWait())
+			items, _ = c.waitDiagnostics(wctx, path, after)
+			cancel()
+		}
+		lines := make([]string, 0, len(items))
+		for _, diag := range items {
+			lines = append(lines, m.formatDiagnostic(c, path, diag))
+		}
+		return tools.Success("path", rel, "diagnostics", clipLines(lines)).WithSummary("%d 条诊断", len(lines))
+	}
+
+	var lines []string
+	for _, c := range m.running() {
+		for path, items := range c.allDiagnostics() {
+			for _, diag := range items {
+				lines = append(lines, m.formatDiagnostic(c, path, diag))
+			}
+		}
+	}
+	sort.Strings(lines)
+	return tools.Success("diagnostics", clipLines(lines)).WithSummary("%d 条诊断", len(lines))
+}
+
+type Hover struct{ m *Manager }
+
+func (h *Hover) Name() string { return "hover" }
+
+func (h *Hover) ReadOnly(inference.ToolCall) bool { return true }
+
+func (h *Hover) Definition() map[string]any {
+	return positionDefinition(h.Name(), "Show the language server's hover information for a symbol: its type, signature and documentation.", nil)
+}
+
+func (h *Hover) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	t, err := h.m.resolveTarget(ctx, args)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	var raw json.RawMessage
+	if err := t.client.call(ctx, "textDocument/hover", t.path, t.pos, nil, &raw); err != nil {
+		return tools.Failure(err)
+	}
+	text := decodeHover(raw)
+	if text == "" {
+		return tools.Failure(errors.New("no hover information at that position"))
+	}
+	return tools.Success("path", t.rel, "hover", text)
+}
+
+type Definition struct{ m *Manager }
+
+func (d *Definition) Name() string { return "definition" }
+
+func (d *Definition) ReadOnly(inference.ToolCall) bool { return true }
+
+func (d *Definition) Definition() map[string]any {
+	return positionDefinition(d.Name(), "Go to the definition of a symbol using the language server. Unlike go_symbols this resolves types, so it finds the exact declaration.", nil)
+}
+
+func (d *Definition) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	t, err := d.m.resolveTarget(ctx, args)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	var raw json.RawMessage
+	if err := t.client.call(ctx, "textDocument/definition", t.path, t.pos, nil, &raw); err != nil {
+		return tools.Failure(err)
+	}
+	locs, err := decodeLocations(raw)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	results, _ := d.m.locationResults(t.client, locs)
+	return tools.Success("definitions", results).WithSummary("%d 处定义", len(results))
+}
+
+type References struct{ m *Manager }
+
+func (r *References) Name() string { return "references" }
+
+func (r *References) ReadOnly(inference.ToolCall) bool { return true }
+
+func (r *References) Definition() map[string]any {
+	return positionDefinition(r.Name(), "Find every reference to a symbol using the language server, including its declaration.", nil)
+}
+
+func (r *References) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	t, err := r.m.resolveTarget(ctx, args)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	var raw json.RawMessage
+	extra := map[string]any{"context": map[string]any{"includeDeclaration": true}}
+	if err := t.client.call(ctx, "textDocument/references", t.path, t.pos, extra, &raw); err != nil {
+		return tools.Failure(err)
+	}
+	locs, err := decodeLocations(raw)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	results, truncated := r.m.locationResults(t.client, locs)
+	return tools.Success("references", results, "truncated", truncated).WithSummary("%d 处引用", len(results))
+}
+
+type RenameSymbol struct{ m *Manager }
+
+func (rs *RenameSymbol) Name() string { return "rename_symbol" }
+
+func (rs *RenameSymbol) Definition() map[string]any {
+	return positionDefinition(rs.Name(), "Rename a symbol and every reference to it across the workspace using the language server. The edits are written to disk.",
+		map[string]any{
+			"new_name": map[string]any{
+				"type":        "string",
+				"description": "The new identifier.",
+			},
+		}, "new_name")
+}
+
+func (rs *RenameSymbol) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	newName, _ := args.Function.Arguments["new_name"].(string)
+	if newName == "" {
+		return tools.Failure(errors.New("new_name is required"))
+	}
+	t, err := rs.m.resolveTarget(ctx, args)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	var edit WorkspaceEdit
+	extra := map[string]any{"newName": newName}
+	if err := t.client.call(ctx, "textDocument/rename", t.path, t.pos, extra, &edit); err != nil {
+		return tools.Failure(err)
+	}
+
+	changes, err := editsByFile(edit)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	if len(changes) == 0 {
+		return tools.Failure(errors.New("the language server returned no edits"))
+	}
+	var (
+		fileEdits []tools.FileEdit
+		files     []string
+		paths     []string
+		count     int
+	)
+	for path, edits := range changes {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return tools.Failure(err)
+		}
+		if !utf8.Valid(data) {
+			return tools.Failure(fmt.Errorf("%s is not valid UTF-8", rs.m.rel(path)))
+		}
+		updated, err := applyTextEdits(string(data), edits)
+		if err != nil {
+			return tools.Failure(fmt.Errorf("%s: %w", rs.m.rel(path), err))
+		}
+		fileEdits = append(fileEdits, tools.FileEdit{Path: path, Content: []byte(updated)})
+		files = append(files, rs.m.rel(path))
+		paths = append(paths, path)
+		count += len(edits)
+	}
+	if err := tools.ApplyEdits(fileEdits); err != nil {
+		return tools.Failure(err)
+	}
+	sort.Strings(files)
+
+	kv := []any{"new_name", newName, "files", files, "edits", count}
+	if diags := rs.m.FilesChanged(ctx, paths); diags != "" {
+		kv = append(kv, "diagnostics", diags)
+	}
+	return tools.Success(kv...).WithSummary("%d 个文件，%d 处修改", len(files), count)
+}
+
+// editsByFile collects the text edits of a WorkspaceEdit by file path.
+func editsByFile(edit WorkspaceEdit) (map[string][]TextEdit, error) {
+	out := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		out[uriToPath(uri)] = append(out[uriToPath(uri)], edits...)
+	}
+	for _, dc := range edit.DocumentChanges {
+		if dc.Kind != "" {
+			return nil, fmt.Errorf("the language server requested a %s file operation, which is not supported", dc.Kind)
+		}
+		path := uriToPath(dc.TextDocument.URI)
+		out[path] = append(out[path], dc.Edits...)
+	}
+	return out, nil
+}
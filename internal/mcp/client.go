@@ -0,0 +1,265 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+)
+
+const (
+	// ProtocolVersion is the MCP revision mini-agent implements.
+	ProtocolVersion   = "2025-06-18"
+	initializeTimeout = 30 * time.Second
+	shutdownTimeout   = 2 * time.Second
+	maxStderrBytes    = 4096
+	// maxToolPages bounds tools/list pagination against a misbehaving
+	// server.
+	maxToolPages = 20
+)
+
+// supportedVersions are the revisions a server may answer initialize with.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// Client is an initialized connection to one MCP server.
+type Client struct {
+	Name string
+	// ServerName and ServerVersion are what the server reported about
+	// itself.
+	ServerName    string
+	ServerVersion string
+	Instructions  string
+
+	conn   *jsonrpc.Conn
+	stream jsonrpc.Stream
+	root   string
+	// stop releases the transport after conn is closed.
+	stop func()
+}
+
+// ToolInfo describes a tool as listed by the server.
+type ToolInfo struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Annotations struct {
+		ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
+	} `json:"annotations"`
+}
+
+// Content is one item of a tool result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Resource *struct {
+		URI  string `json:"uri"`
+		Text string `json:"text,omitempty"`
+	} `json:"resource,omitempty"`
+}
+
+// CallResult is the result of tools/call.
+type CallResult struct {
+	Content           []Content      `json:"content"`
+	StructuredContent map[string]any `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+}
+
+// Connect starts or dials the server and performs the initialize
+// handshake. root is offered to the server as the only root.
+func Connect(ctx context.Context, name, root string, server config.MCPServer) (*Client, error) {
+	if server.URL != "" {
+		stream := newHTTPStream(server.URL, expandAll(server.Headers))
+		return newClient(ctx, name, root, stream, func() {})
+	}
+	return startStdio(ctx, name, root, server)
+}
+
+func startStdio(ctx context.Context, name, root string, server config.MCPServer) (*Client, error) {
+	cmd := exec.Command(server.Command[0], server.Command[1:]...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	for k, v := range expandAll(server.Env) {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &limitedBuffer{max: maxStderrBytes}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	// The server should exit once its stdin is closed.
+	stop := func() {
+		select {
+		case <-exited:
+		case <-time.After(shutdownTimeout):
+			_ = cmd.Process.Kill()
+			<-exited
+		}
+	}
+	c, err := newClient(ctx, name, root, jsonrpc.NewLineStream(stdout, stdin), stop)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient initializes a server reachable over stream.
+func newClient(ctx context.Context, name, root string, stream jsonrpc.Stream, stop func()) (*Client, error) {
+	c := &Client{Name: name, root: root, stream: stream, stop: stop}
+	c.conn = jsonrpc.NewConn(stream, c.handle)
+
+	ctx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities": map[string]any{
+			"roots": map[string]any{"listChanged": false},
+		},
+		"clientInfo": map[string]any{"name": "mini-agent", "version": "dev"},
+	}
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+		Instructions string `json:"instructions"`
+	}
+	if err := c.conn.Call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	if !slices.Contains(supportedVersions, result.ProtocolVersion) {
+		return fmt.Errorf("unsupported protocol version %q", result.ProtocolVersion)
+	}
+	if hs, ok := c.stream.(*httpStream); ok {
+		hs.setProtocolVersion(result.ProtocolVersion)
+	}
+	c.ServerName, c.ServerVersion = result.ServerInfo.Name, result.ServerInfo.Version
+	c.Instructions = result.Instructions
+	return c.conn.Notify("notifications/initialized", nil)
+}
+
+// handle answers the requests a server may send its client.
+func (c *Client) handle(_ context.Context, method string, _ json.RawMessage, isNotify bool) (any, error) {
+	if isNotify {
+		return nil, nil
+	}
+	switch method {
+	case "ping":
+		return map[string]any{}, nil
+	case "roots/list":
+		root := url.URL{Scheme: "file", Path: filepath.ToSlash(c.root)}
+		return map[string]any{"roots": []map[string]any{{"uri": root.String(), "name": "workspace"}}}, nil
+	default:
+		return nil, jsonrpc.MethodNotFound(method)
+	}
+}
+
+// ListTools returns every tool the server offers.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for range maxToolPages {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := c.conn.Call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Tools...)
+		if cursor = page.NextCursor; cursor == "" {
+			return all, nil
+		}
+	}
+	return all, nil
+}
+
+// CallTool runs a tool on the server. A tool that ran but failed reports
+// IsError rather than an error.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var result CallResult
+	if err := c.conn.Call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the session and stops the server.
+func (c *Client) Close() {
+	_ = c.conn.Close()
+	c.stop()
+}
+
+func expandAll(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = os.ExpandEnv(v)
+	}
+	return out
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+)
+
+// envFakeServer makes the test binary act as a stdio MCP server; see
+// TestMain.
+const envFakeServer = "MINI_AGENT_FAKE_MCP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envFakeServer) == "1" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeTools are served over two tools/list pages.
+var fakeTools = []map[string]any{
+	{"name": "echo", "description": "Echo text.", "inputSchema": map[string]any{
+		"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}},
+	}},
+	{"name": "fail", "description": "Always fails."},
+	{"name": "read.roots", "description": "Report the client's roots.", "annotations": map[string]any{"readOnlyHint": true}},
+}
+
+// fakeCall serves tools/call; ask sends a request back to the client.
+func fakeCall(ctx context.Context, params json.RawMessage, ask func(ctx context.Context, method string, result any) error) (any, error) {
+	var p struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	text := func(s string) []map[string]any { return []map[string]any{{"type": "text", "text": s}} }
+	switch p.Name {
+	case "echo":
+		return map[string]any{
+			"content":           append(text(fmt.Sprint(p.Arguments["text"])), map[string]any{"type": "image", "mimeType": "image/png", "data": "AA=="}),
+			"structuredContent": map[string]any{"length": len(fmt.Sprint(p.Arguments["text"]))},
+		}, nil
+	case "fail":
+		return map[string]any{"content": text("disk on fire"), "isError": true}, nil
+	case "read.roots":
+		var roots struct {
+			Roots []struct {
+				URI string `json:"uri"`
+			} `json:"roots"`
+		}
+		if err := ask(ctx, "roots/list", &roots); err != nil {
+			return nil, err
+		}
+		if len(roots.Roots) != 1 {
+			return nil, errors.New("want one root")
+		}
+		// Some servers send an explicit null for a missing structuredContent.
+		return map[string]any{"content": text(roots.Roots[0].URI), "structuredContent": nil}, nil
+	}
+	return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "unknown tool " + p.Name}
+}
+
+func fakeHandle(ctx context.Context, method string, params json.RawMessage, ask func(context.Context, string, any) error) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "1.0"},
+			"instructions":    "Be nice.",
+		}, nil
+	case "tools/list":
+		var p struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(params, &p)
+		if p.Cursor == "" {
+			return map[string]any{"tools": fakeTools[:1], "nextCursor": "2"}, nil
+		}
+		return map[string]any{"tools": fakeTools[1:]}, nil
+	case "tools/call":
+		return fakeCall(ctx, params, ask)
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+func runFakeServer() {
+	var conn *jsonrpc.Conn
+	ask := func(ctx context.Context, method string, result any) error {
+		return conn.Call(ctx, method, nil, result)
+	}
+	conn = jsonrpc.NewConn(jsonrpc.NewLineStream(os.Stdin, os.Stdout), func(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+		if isNotify {
+			return nil, nil
+		}
+		return fakeHandle(ctx, method, params, ask)
+	})
+	<-conn.Done()
+}
+
+// fakeHTTPServer serves the fake tools over the streamable HTTP transport.
+// tools/call is answered with an event stream, everything else with JSON.
+type fakeHTTPServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	deleted  bool
+	versions []string
+}
+
+const fakeSessionID = "session-1"
+
+func newFakeHTTPServer(t *testing.T) *fakeHTTPServer {
+	s := &fakeHTTPServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeHTTPServer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.mu.Lock()
+		s.deleted = true
+		s.mu.Unlock()
+		return
+	}
+	var msg jsonrpc.Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg.Method == "initialize" {
+		w.Header().Set(headerSession, fakeSessionID)
+	} else if r.Header.Get(headerSession) != fakeSessionID {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	} else {
+		s.mu.Lock()
+		s.versions = append(s.versions, r.Header.Get(headerProtocol))
+		s.mu.Unlock()
+	}
+	if len(msg.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// The transport cannot carry requests back to the client here.
+	noAsk := func(context.Context, string, any) error { return errors.New("unsupported") }
+	result, err := fakeHandle(r.Context(), msg.Method, msg.Params, noAsk)
+	resp := jsonrpc.Message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInternalError, Message: err.Error()}
+	} else {
+		resp.Result, _ = json.Marshal(result)
+	}
+	data, _ := json.Marshal(resp)
+	if msg.Method != "tools/call" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{}}\n\n")
+	fmt.Fprintf(w, ": keep-alive\n\nid: 7\ndata: %s\n\n", data)
+}
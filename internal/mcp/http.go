@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerSession  = "Mcp-Session-Id"
+	headerProtocol = "MCP-Protocol-Version"
+	maxErrorBody   = 512
+)
+
+var errSessionExpired = errors.New("the server ended the MCP session")
+
+// httpStream is the streamable HTTP transport as a jsonrpc.Stream: every
+// written message is POSTed to the endpoint, and the messages in each reply,
+// a JSON body or a server-sent event stream, are queued for ReadMessage.
+type httpStream struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	sessionID string
+	version   string
+
+	incoming chan []byte
+}
+
+func newHTTPStream(url string, headers map[string]string) *httpStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &httpStream{
+		url:      url,
+		headers:  headers,
+		client:   &http.Client{},
+		ctx:      ctx,
+		cancel:   cancel,
+		incoming: make(chan []byte),
+	}
+}
+
+func (s *httpStream) setProtocolVersion(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = v
+}
+
+func (s *httpStream) newRequest(method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(s.ctx, method, s.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessionID != "" {
+		req.Header.Set(headerSession, s.sessionID)
+	}
+	if s.version != "" {
+		req.Header.Set(headerProtocol, s.version)
+	}
+	return req, nil
+}
+
+// WriteMessage posts data and returns once the reply's headers arrived; its
+// body is read in the background, so a slow tool call does not hold up
+// other messages.
+func (s *httpStream) WriteMessage(data []byte) error {
+	req, err := s.newRequest(http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if id := resp.Header.Get(headerSession); id != "" {
+		s.mu.Lock()
+		s.sessionID = id
+		s.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		return nil
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get(headerSession) != "":
+		resp.Body.Close()
+		return errSessionExpired
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		return fmt.Errorf("%s: %s %s", s.url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	go s.readReply(resp)
+	return nil
+}
+
+func (s *httpStream) readReply(resp *http.Response) {
+	defer resp.Body.Close()
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		s.readEvents(resp.Body)
+		return
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+	s.deliver(data)
+}
+
+// readEvents queues the data of each "message" event.
+func (s *httpStream) readEvents(body io.Reader) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	var data []string
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 && (event == "" || event == "message") {
+				s.deliver([]byte(strings.Join(data, "\n")))
+			}
+			data, event = nil, ""
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		}
+	}
+	if len(data) > 0 && (event == "" || event == "message") {
+		s.deliver([]byte(strings.Join(data, "\n")))
+	}
+}
+
+// deliver queues a message, splitting a JSON-RPC batch.
+func (s *httpStream) deliver(data []byte) {
+	data = bytes.TrimSpace(data)
+	msgs := [][]byte{data}
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(data, &batch) == nil {
+			msgs = msgs[:0]
+			for _, m := range batch {
+				msgs = append(msgs, m)
+			}
+		}
+	}
+	for _, m := range msgs {
+		select {
+		case s.incoming <- m:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *httpStream) ReadMessage() ([]byte, error) {
+	select {
+	case m := <-s.incoming:
+		return m, nil
+	case <-s.ctx.Done():
+		return nil, io.EOF
+	}
+}
+
+// Close ends the session on the server, best effort, and aborts replies
+// still being read.
+func (s *httpStream) Close() error {
+	s.mu.Lock()
+	id := s.sessionID
+	s.mu.Unlock()
+	if id != "" {
+		if req, err := s.newRequest(http.MethodDelete, nil); err == nil {
+			ctx, cancel := context.WithTimeout(req.Context(), time.Second)
+			if resp, err := s.client.Do(req.WithContext(ctx)); err == nil {
+				resp.Body.Close()
+			}
+			cancel()
+		}
+	}
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package mcp connects to Model Context Protocol servers and offers their
+// tools to the model through the tools.Tool interface.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+const (
+	// maxToolName is the longest tool name inference APIs accept.
+	maxToolName    = 64
+	maxResultBytes = 64 * 1024
+	maxSummaryArgs = 200
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ToolName is the name a server's tool is registered under: mcp__ followed
+// by the server and tool names, so tools of different servers never clash.
+func ToolName(server, tool string) string {
+	name := "mcp__" + server + "__" + unsafeNameChars.ReplaceAllString(tool, "_")
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return name
+}
+
+// Manager holds the connected servers of a Session.
+type Manager struct {
+	clients []*Client
+	tools   []tools.Tool
+	policy  map[string]config.ApprovalRule
+	// Errors describes the servers that could not be used.
+	Errors []error
+}
+
+// Start connects to every enabled server concurrently and lists its tools.
+// A server that fails is reported in Errors and skipped.
+func Start(ctx context.Context, root string, servers map[string]config.MCPServer) *Manager {
+	names := make([]string, 0, len(servers))
+	for name, s := range servers {
+		if !s.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	type started struct {
+		client *Client
+		tools  []ToolInfo
+		err    error
+	}
+	results := make([]started, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := Connect(ctx, name, root, servers[name])
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			infos, err := c.ListTools(ctx)
+			if err != nil {
+				c.Close()
+				results[i].err = fmt.Errorf("list tools: %w", err)
+				return
+			}
+			results[i] = started{client: c, tools: infos}
+		}()
+	}
+	wg.Wait()
+
+	m := &Manager{policy: make(map[string]config.ApprovalRule)}
+	seen := make(map[string]bool)
+	for i, r := range results {
+		name := names[i]
+		if r.err != nil {
+			m.Errors = append(m.Errors, fmt.Errorf("MCP server %s: %w", name, r.err))
+			continue
+		}
+		m.clients = append(m.clients, r.client)
+		for _, info := range r.tools {
+			t := newTool(r.client, info)
+			if seen[t.name] {
+				m.Errors = append(m.Errors, fmt.Errorf("MCP server %s: tool %q clashes with another tool named %s", name, info.Name, t.name))
+				continue
+			}
+			seen[t.name] = true
+			m.tools = append(m.tools, t)
+			if rule := servers[name].Approval; rule != "" {
+				m.policy[t.name] = rule
+			}
+		}
+	}
+	return m
+}
+
+// Tools returns the adapters for every tool of the connected servers.
+func (m *Manager) Tools() []tools.Tool { return m.tools }
+
+// ApprovalPolicy maps tool names to the approval rule of their server, for
+// servers that set one.
+func (m *Manager) ApprovalPolicy() map[string]config.ApprovalRule { return m.policy }
+
+// Close disconnects from every server.
+func (m *Manager) Close() {
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
+
+// Tool adapts one tool of an MCP server. Every call passes the Approval
+// Gate, since the server may do anything.
+type Tool struct {
+	client *Client
+	info   ToolInfo
+	name   string
+	schema map[string]any
+}
+
+func newTool(c *Client, info ToolInfo) *Tool {
+	var schema map[string]any
+	if json.Unmarshal(info.InputSchema, &schema) != nil || schema == nil {
+		schema = map[string]any{}
+	}
+	schema["type"] = "object"
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]any{}
+	}
+	return &Tool{client: c, info: info, name: ToolName(c.Name, info.Name), schema: schema}
+}
+
+func (t *Tool) Name() string { return t.name }
+
+func (t *Tool) Definition() map[string]any {
+	desc := strings.TrimSpace(t.info.Description)
+	if desc == "" {
+		desc = t.info.Title
+	}
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        t.name,
+			"description": strings.TrimSpace(fmt.Sprintf("%s (tool %q of MCP server %s)", desc, t.info.Name, t.client.Name)),
+			"parameters":  t.schema,
+		},
+	}
+}
+
+func (t *Tool) ApprovalSummary(args inference.ToolCall) string {
+	data, _ := json.Marshal(args.Function.Arguments)
+	summary := string(data)
+	if len(summary) > maxSummaryArgs {
+		summary = summary[:maxSummaryArgs] + "…"
+	}
+	return fmt.Sprintf("%s: %s %s", t.client.Name, t.info.Name, summary)
+}
+
+func (t *Tool) Call(ctx context.Context, args inference.ToolCall) tools.ToolResult {
+	result, err := t.client.CallTool(ctx, t.info.Name, args.Function.Arguments)
+	if err != nil {
+		return tools.Failure(err)
+	}
+	text := resultText(result)
+	if result.IsError {
+		if text == "" {
+			text = "the tool reported an error"
+		}
+		return tools.Failure(errors.New(text))
+	}
+	kv := []any{"content", text}
+	if result.StructuredContent != nil {
+		kv = append(kv, "structured", result.StructuredContent)
+	}
+	return tools.Success(kv...)
+}
+
+// resultText flattens the content items of a result; items the model
+// cannot read, such as images, are described instead.
+func resultText(r *CallResult) string {
+	var parts []string
+	for _, c := range r.Content {
+		switch {
+		case c.Type == "text":
+			parts = append(parts, c.Text)
+		case c.Type == "resource" && c.Resource != nil && c.Resource.Text != "":
+			parts = append(parts, c.Resource.Text)
+		case c.Type == "resource" && c.Resource != nil:
+			parts = append(parts, fmt.Sprintf("[resource %s]", c.Resource.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content %s omitted]", c.Type, c.MimeType))
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if len(text) > maxResultBytes {
+		text = strings.ToValidUTF8(text[:maxResultBytes], "") + "\n… (truncated)"
+	}
+	return text
+}
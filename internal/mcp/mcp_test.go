@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+func fakeStdioServer() config.MCPServer {
+	return config.MCPServer{
+		Command: []string{os.Args[0], "-test.run=^$"},
+		Env:     map[string]string{envFakeServer: "1"},
+	}
+}
+
+type toolResult struct {
+	Status string         `json:"status"`
+	Data   map[string]any `json:"data"`
+}
+
+func callTool(t *testing.T, tool tools.Tool, args map[string]any) toolResult {
+	t.Helper()
+	resp := tool.Call(context.Background(), inference.ToolCall{
+		ID:       "call-1",
+		Function: inference.Function{Name: tool.Name(), Arguments: args},
+	})
+	var res toolResult
+	if err := json.Unmarshal([]byte(resp.ModelText()), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func toolsByName(m *Manager) map[string]tools.Tool {
+	byName := make(map[string]tools.Tool)
+	for _, tool := range m.Tools() {
+		byName[tool.Name()] = tool
+	}
+	return byName
+}
+
+func TestStart_stdio(t *testing.T) {
+	root := t.TempDir()
+	m := Start(context.Background(), root, map[string]config.MCPServer{"fake": fakeStdioServer()})
+	t.Cleanup(m.Close)
+	if len(m.Errors) > 0 {
+		t.Fatal(m.Errors)
+	}
+	byName := toolsByName(m)
+	if len(byName) != 3 || byName["mcp__fake__echo"] == nil || byName["mcp__fake__fail"] == nil || byName["mcp__fake__read_roots"] == nil {
+		t.Fatalf("tools = %v", byName)
+	}
+	if c := m.clients[0]; c.ServerName != "fake" || c.ServerVersion != "1.0" || c.Instructions != "Be nice." {
+		t.Fatalf("server info = %q %q %q", c.ServerName, c.ServerVersion, c.Instructions)
+	}
+
+	res := callTool(t, byName["mcp__fake__echo"], map[string]any{"text": "hello"})
+	if res.Status != "SUCCESS" || res.Data["content"] != "hello\n[image content image/png omitted]" {
+		t.Fatalf("echo = %+v", res)
+	}
+	if structured, _ := res.Data["structured"].(map[string]any); structured["length"] != 5.0 {
+		t.Fatalf("structured = %v", res.Data["structured"])
+	}
+
+	res = callTool(t, byName["mcp__fake__fail"], nil)
+	if res.Status != "FAILED" || res.Data["error"] != "disk on fire" {
+		t.Fatalf("fail = %+v", res)
+	}
+
+	// The server asks the client for its roots while serving the call.
+	res = callTool(t, byName["mcp__fake__read_roots"], nil)
+	if want := "file://" + filepath.ToSlash(root); res.Status != "SUCCESS" || res.Data["content"] != want {
+		t.Fatalf("read.roots = %+v, want %s", res, want)
+	}
+	if _, ok := res.Data["structured"]; ok {
+		t.Fatalf("null structuredContent = %v, want it left out", res.Data["structured"])
+	}
+}
+
+func TestStart_http(t *testing.T) {
+	s := newFakeHTTPServer(t)
+	t.Setenv("FAKE_MCP_TOKEN", "secret")
+	m := Start(context.Background(), t.TempDir(), map[string]config.MCPServer{
+		"remote": {URL: s.URL, Headers: map[string]string{"Authorization": "Bearer ${FAKE_MCP_TOKEN}"}},
+	})
+	if len(m.Errors) > 0 {
+		t.Fatal(m.Errors)
+	}
+	res := callTool(t, toolsByName(m)["mcp__remote__echo"], map[string]any{"text": "over http"})
+	if res.Status != "SUCCESS" || !strings.HasPrefix(res.Data["content"].(string), "over http\n") {
+		t.Fatalf("echo = %+v", res)
+	}
+	res = callTool(t, toolsByName(m)["mcp__remote__fail"], nil)
+	if res.Status != "FAILED" || res.Data["error"] != "disk on fire" {
+		t.Fatalf("fail = %+v", res)
+	}
+	m.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.deleted {
+		t.Fatal("session was not deleted on Close")
+	}
+	for _, v := range s.versions {
+		if v != ProtocolVersion {
+			t.Fatalf("%s header = %v", headerProtocol, s.versions)
+		}
+	}
+}
+
+func TestStart_failures(t *testing.T) {
+	s := newFakeHTTPServer(t)
+	root := t.TempDir()
+	disabled := fakeStdioServer()
+	disabled.Disabled = true
+	m := Start(context.Background(), root, map[string]config.MCPServer{
+		"missing":  {Command: []string{filepath.Join(root, "no-such-server")}},
+		"denied":   {URL: s.URL},
+		"disabled": disabled,
+		"fake":     fakeStdioServer(),
+	})
+	t.Cleanup(m.Close)
+	if len(m.Errors) != 2 {
+		t.Fatalf("errors = %v", m.Errors)
+	}
+	if msg := m.Errors[0].Error(); !strings.Contains(msg, "MCP server denied") || !strings.Contains(msg, "401") {
+		t.Fatalf("errors[0] = %s", msg)
+	}
+	if msg := m.Errors[1].Error(); !strings.Contains(msg, "MCP server missing") {
+		t.Fatalf("errors[1] = %s", msg)
+	}
+	if len(m.Tools()) != 3 {
+		t.Fatalf("tools of the working server = %d, want 3", len(m.Tools()))
+	}
+}
+
+func TestStart_approvalPolicy(t *testing.T) {
+	allowed := fakeStdioServer()
+	allowed.Approval = config.ApprovalAllow
+	m := Start(context.Background(), t.TempDir(), map[string]config.MCPServer{
+		"fake":  fakeStdioServer(),
+		"trust": allowed,
+	})
+	t.Cleanup(m.Close)
+	policy := m.ApprovalPolicy()
+	if len(policy) != 3 || policy["mcp__trust__echo"] != config.ApprovalAllow {
+		t.Fatalf("policy = %v", policy)
+	}
+	for _, tool := range m.Tools() {
+		if _, gated := tool.(tools.GatedTool); !gated {
+			t.Fatalf("%s is not gated", tool.Name())
+		}
+	}
+	summary := toolsByName(m)["mcp__fake__echo"].(tools.GatedTool).ApprovalSummary(inference.ToolCall{
+		Function: inference.Function{Arguments: map[string]any{"text": "hi"}},
+	})
+	if summary != `fake: echo {"text":"hi"}` {
+		t.Fatalf("summary = %q", summary)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	if got := ToolName("gh", "issues/list v2"); got != "mcp__gh__issues_list_v2" {
+		t.Fatalf("ToolName = %q", got)
+	}
+	if got := ToolName("gh", strings.Repeat("x", 100)); len(got) != maxToolName {
+		t.Fatalf("len(ToolName) = %d", len(got))
+	}
+}
+
+func TestTool_definition(t *testing.T) {
+	c := &Client{Name: "fake"}
+	def := newTool(c, ToolInfo{Name: "fail", Title: "Fail"}).Definition()["function"].(map[string]any)
+	params := def["parameters"].(map[string]any)
+	if params["type"] != "object" || params["properties"] == nil {
+		t.Fatalf("parameters = %v", params)
+	}
+	if def["name"] != "mcp__fake__fail" || !strings.HasPrefix(def["description"].(string), "Fail (tool") {
+		t.Fatalf("definition = %v", def)
+	}
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// errCannotAsk refuses a call that needs approval when the client cannot
+// put the question to its user.
+var errCannotAsk = errors.New("tool call needs approval, but the MCP client cannot ask the user; allow the tool in the approval section of the mini-agent configuration")
+
+// Server offers tools to an MCP client. Calls pass the same checks as in a
+// Session: the tools confine themselves to the Workspace, and gated calls
+// follow the approval policy. Calls the policy leaves to the developer are
+// put to the client's user through elicitation, or refused if the client
+// does not support it.
+type Server struct {
+	tools  []tools.Tool
+	policy map[string]config.ApprovalRule
+
+	nextID atomic.Int64
+
+	mu        sync.Mutex
+	conn      *jsonrpc.Conn
+	canElicit bool
+}
+
+func NewServer(ts []tools.Tool, policy map[string]config.ApprovalRule) *Server {
+	return &Server{tools: ts, policy: policy}
+}
+
+// Serve answers the client on stream until it disconnects.
+func (s *Server) Serve(stream jsonrpc.Stream) error {
+	s.mu.Lock()
+	conn := jsonrpc.NewConn(stream, s.handle)
+	s.conn = conn
+	s.mu.Unlock()
+	<-conn.Done()
+	if err := conn.Err(); err != nil && !errors.Is(err, jsonrpc.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+	if isNotify {
+		return nil, nil
+	}
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(ctx, params)
+	default:
+		return nil, jsonrpc.MethodNotFound(method)
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		Capabilities    struct {
+			Elicitation json.RawMessage `json:"elicitation"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: err.Error()}
+	}
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	s.mu.Lock()
+	s.canElicit = p.Capabilities.Elicitation != nil
+	s.mu.Unlock()
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+		"serverInfo":      map[string]any{"name": "mini-agent", "version": "dev"},
+		"instructions":    fmt.Sprintf("Tools of the mini-agent coding agent, confined to the Workspace at %s.", tools.WorkspaceRoot()),
+	}, nil
+}
+
+func (s *Server) listTools() map[string]any {
+	list := make([]map[string]any, 0, len(s.tools))
+	for _, tool := range s.tools {
+		fn, _ := tool.Definition()["function"].(map[string]any)
+		_, readOnly := tool.(tools.ReadOnlyTool)
+		_, gated := tool.(tools.GatedTool)
+		list = append(list, map[string]any{
+			"name":        tool.Name(),
+			"description": fn["description"],
+			"inputSchema": fn["parameters"],
+			"annotations": map[string]any{"readOnlyHint": readOnly && !gated},
+		})
+	}
+	return map[string]any{"tools": list}
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: err.Error()}
+	}
+	i := slices.IndexFunc(s.tools, func(t tools.Tool) bool { return t.Name() == p.Name })
+	if i < 0 {
+		return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+	tool := s.tools[i]
+	tc := inference.ToolCall{
+		ID:       "mcp-" + strconv.FormatInt(s.nextID.Add(1), 10),
+		Function: inference.Function{Name: p.Name, Arguments: p.Arguments},
+	}
+
+	var result tools.ToolResult
+	if err := tools.ValidateArguments(tool, tc.Function.Arguments); err != nil {
+		result = tools.Failure(err)
+	} else if gt, ok := tool.(tools.GatedTool); ok && tools.NeedsApproval(tool, tc) {
+		allowed, err := s.approve(ctx, gt, tc)
+		switch {
+		case err != nil:
+			result = tools.Failure(err)
+		case !allowed:
+			result = tools.Failure(tools.ErrToolDenied)
+		default:
+			result = tool.Call(ctx, tc)
+		}
+	} else {
+		result = tool.Call(ctx, tc)
+	}
+	reply := map[string]any{
+		"content": []map[string]any{{"type": "text", "text": result.ModelText()}},
+		"isError": result.Failed(),
+	}
+	// Clients prefer structuredContent over the text, so a result without
+	// Data must not send it as null.
+	if result.Data != nil {
+		reply["structuredContent"] = result.Data
+	}
+	return reply, nil
+}
+
+// approve applies the approval policy, like the Agent does, asking the
+// client's user unless it says allow or deny.
+func (s *Server) approve(ctx context.Context, gt tools.GatedTool, tc inference.ToolCall) (bool, error) {
+	switch s.policy[tc.Function.Name] {
+	case config.ApprovalAllow:
+		return true, nil
+	case config.ApprovalDeny:
+		return false, tools.ErrPolicyDenied
+	}
+	s.mu.Lock()
+	conn, canElicit := s.conn, s.canElicit
+	s.mu.Unlock()
+	if !canElicit {
+		return false, errCannotAsk
+	}
+	params := map[string]any{
+		"message":         "mini-agent wants to run " + gt.ApprovalSummary(tc) + ". Allow?",
+		"requestedSchema": map[string]any{"type": "object", "properties": map[string]any{}},
+	}
+	var result struct {
+		Action string `json:"action"`
+	}
+	if err := conn.Call(ctx, "elicitation/create", params, &result); err != nil {
+		return false, fmt.Errorf("ask for approval: %w", err)
+	}
+	return result.Action == "accept", nil
+}
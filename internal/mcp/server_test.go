@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/config"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/jsonrpc"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+// serveBuiltin serves the Built-in Tools for a fresh Workspace in-process
+// and returns the client's end of the connection.
+func serveBuiltin(t *testing.T, policy map[string]config.ApprovalRule) (jsonrpc.Stream, string) {
+	t.Helper()
+	root := t.TempDir()
+	tools.SetWorkspaceRootForTest(root)
+	tools.ResetSession()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello from the workspace\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(tools.Builtin(), policy).Serve(jsonrpc.NewLineStream(toServer, fromServer))
+	}()
+	t.Cleanup(func() {
+		fromClient.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return jsonrpc.NewLineStream(toClient, fromClient), root
+}
+
+func TestServer_builtinTools(t *testing.T) {
+	stream, root := serveBuiltin(t, nil)
+	c, err := newClient(context.Background(), "self", root, stream, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.ServerName != "mini-agent" || !strings.Contains(c.Instructions, root) {
+		t.Fatalf("server info = %q %q", c.ServerName, c.Instructions)
+	}
+
+	infos, err := c.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints := make(map[string]bool)
+	for _, info := range infos {
+		hints[info.Name] = info.Annotations.ReadOnlyHint
+	}
+	if len(infos) != len(tools.Builtin()) || !hints["read_file"] || hints["run_shell"] || hints["git"] {
+		t.Fatalf("tools = %v", hints)
+	}
+
+	ctx := context.Background()
+	res, err := c.CallTool(ctx, "read_file", map[string]any{"path": "notes.txt"})
+	if err != nil || res.IsError || !strings.Contains(res.Content[0].Text, "hello from the workspace") {
+		t.Fatalf("read_file = %+v, %v", res, err)
+	}
+
+	// Workspace confinement holds for MCP clients too.
+	res, err = c.CallTool(ctx, "read_file", map[string]any{"path": "../outside.txt"})
+	if err != nil || !res.IsError {
+		t.Fatalf("read_file outside the Workspace = %+v, %v", res, err)
+	}
+
+	// The client cannot ask its user, so gated calls are refused.
+	res, err = c.CallTool(ctx, "run_shell", map[string]any{"command": "touch ran"})
+	if err != nil || !res.IsError || !strings.Contains(res.Content[0].Text, "cannot ask the user") {
+		t.Fatalf("run_shell = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ran")); err == nil {
+		t.Fatal("refused command ran")
+	}
+
+	if _, err := c.CallTool(ctx, "no_such_tool", nil); err == nil {
+		t.Fatal("unknown tool did not fail")
+	}
+}
+
+func TestServer_approvalPolicy(t *testing.T) {
+	stream, root := serveBuiltin(t, map[string]config.ApprovalRule{
+		"run_shell": config.ApprovalAllow,
+		"go":        config.ApprovalDeny,
+	})
+	c, err := newClient(context.Background(), "self", root, stream, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	res, err := c.CallTool(ctx, "run_shell", map[string]any{"command": "echo allowed"})
+	if err != nil || res.IsError || !strings.Contains(res.Content[0].Text, "allowed") {
+		t.Fatalf("run_shell = %+v, %v", res, err)
+	}
+	res, err = c.CallTool(ctx, "go", map[string]any{"subcommand": "build"})
+	if err != nil || !res.IsError || !strings.Contains(res.Content[0].Text, tools.ErrPolicyDenied.Error()) {
+		t.Fatalf("go build = %+v, %v", res, err)
+	}
+}
+
+func TestServer_elicitsApproval(t *testing.T) {
+	stream, root := serveBuiltin(t, nil)
+	var messages []string
+	answer := "decline"
+	conn := jsonrpc.NewConn(stream, func(_ context.Context, method string, params json.RawMessage, isNotify bool) (any, error) {
+		if method != "elicitation/create" {
+			return nil, jsonrpc.MethodNotFound(method)
+		}
+		var p struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(params, &p)
+		messages = append(messages, p.Message)
+		return map[string]any{"action": answer}, nil
+	})
+	defer conn.Close()
+
+	ctx := context.Background()
+	var init map[string]any
+	if err := conn.Call(ctx, "initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]any{"elicitation": map[string]any{}},
+	}, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init["protocolVersion"] != "2025-03-26" {
+		t.Fatalf("negotiated version = %v", init["protocolVersion"])
+	}
+
+	call := func() CallResult {
+		var res CallResult
+		if err := conn.Call(ctx, "tools/call", map[string]any{
+			"name": "run_shell", "arguments": map[string]any{"command": "touch ran"},
+		}, &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := call(); !res.IsError || !strings.Contains(res.Content[0].Text, tools.ErrToolDenied.Error()) {
+		t.Fatalf("declined call = %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ran")); err == nil {
+		t.Fatal("declined command ran")
+	}
+	answer = "accept"
+	if res := call(); res.IsError {
+		t.Fatalf("accepted call = %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ran")); err != nil {
+		t.Fatal("accepted command did not run")
+	}
+	if len(messages) != 2 || !strings.Contains(messages[0], "touch ran") {
+		t.Fatalf("elicitation messages = %q", messages)
+	}
+}
+
+// textTool returns a result with Text and no Data.
+type textTool struct{}
+
+func (textTool) Name() string { return "plain" }
+
+func (textTool) Definition() map[string]any {
+	return map[string]any{"type": "function", "function": map[string]any{
+		"name": "plain", "parameters": map[string]any{"type": "object", "properties": map[string]any{}},
+	}}
+}
+
+func (textTool) Call(context.Context, inference.ToolCall) tools.ToolResult {
+	return tools.ToolResult{Status: tools.StatusSuccess, Text: "plain text"}
+}
+
+func TestServer_omitsNullStructuredContent(t *testing.T) {
+	s := NewServer([]tools.Tool{textTool{}}, nil)
+	reply, err := s.callTool(context.Background(), json.RawMessage(`{"name": "plain"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(reply)
+	if strings.Contains(string(raw), "structuredContent") || !strings.Contains(string(raw), "plain text") {
+		t.Fatalf("reply = %s", raw)
+	}
+}
@@ -9,6 +9,11 @@ const (
 	Quit
 	Clear
 	Help
+	Commit
+	Review
+	Fix
+	Worktree
+	Mode
 	Unknown
 )
 
@@ -29,6 +34,17 @@ func Parse(text string) (Result, string) {
 		return Clear, ""
 	case "help":
 		return Help, ""
+	case "commit":
+		return Commit, strings.ToLower(strings.Join(fields[1:], " "))
+	case "review":
+		// Refs are case-sensitive.
+		return Review, strings.Join(fields[1:], " ")
+	case "fix":
+		return Fix, strings.Join(fields[1:], " ")
+	case "worktree":
+		return Worktree, ""
+	case "mode":
+		return Mode, strings.ToLower(strings.Join(fields[1:], " "))
 	default:
 		return Unknown, cmd
 	}
@@ -40,12 +56,19 @@ Slash Command：
   /quit   退出 TUI
   /clear  清空 Session 与 Transcript
   /help   显示此帮助
+  /commit [all]  根据暂存区（all：全部改动）生成提交信息，编辑确认后提交
+  /review [ref]  审查工作区相对 ref（默认 HEAD）的改动
+  /fix [N...]    让 Agent 应用上次审查的第 N 条建议（省略则全部）
+  /worktree      显示 worktree 相对原分支的改动（需以 --worktree 启动）
+  /mode [name]   切换 Agent 模式（code / plan / ask），省略则列出全部模式
 
 Transcript 快捷键：
   鼠标拖拽     选中文本，松开后自动复制
   PgUp/PgDn    滚动对话记录
   Ctrl+T       进入对话区（j/k 选块，Ctrl+Y 复制块）
   G            跳至最新
+  Shift+Tab    切换到下一个 Agent 模式
+  Ctrl+R       批准 plan 模式给出的计划，切换到 code 模式执行
   Y  允许执行 Shell 命令
   N  拒绝执行
 
@@ -58,5 +81,12 @@ Transcript 快捷键：
   System Prompt
     MINI_AGENT_SYSTEM_PROMPT       直接覆盖系统提示词
     MINI_AGENT_SYSTEM_PROMPT_FILE  从文件读取系统提示词（优先于上者）
+
+  文件写入保护（allow / deny）
+    MINI_AGENT_NEW_FILE_WRITES     是否允许创建新文件
+    MINI_AGENT_UNREAD_FILE_WRITES  是否允许覆盖未读过的已有文件
+
+  Workspace 配置文件
+    MINI_AGENT_CONFIG  配置文件路径（默认 .mini-agent/config.json）
 `)
 }
@@ -17,6 +17,14 @@ func TestParseSlashCommand(t *testing.T) {
 		{"/QUIT", Quit, ""},
 		{"/clear", Clear, ""},
 		{"/help", Help, ""},
+		{"/commit", Commit, ""},
+		{"/commit ALL", Commit, "all"},
+		{"/review", Review, ""},
+		{"/review Feature/X", Review, "Feature/X"},
+		{"/fix 1 3", Fix, "1 3"},
+		{"/worktree", Worktree, ""},
+		{"/mode", Mode, ""},
+		{"/mode Plan", Mode, "plan"},
 		{"/unknown", Unknown, "unknown"},
 		{"/foo bar", Unknown, "foo"},
 		{"/", Unknown, ""},
@@ -34,7 +42,7 @@ func TestParseSlashCommand(t *testing.T) {
 func TestSlashHelpText(t *testing.T) {
 	text := HelpText()
 	for _, want := range []string{
-		"/quit", "/clear", "/help", "Y", "N",
+		"/quit", "/clear", "/help", "/commit", "/review", "/fix", "/worktree", "/mode", "Y", "N",
 		"LLM_API_URL", "MINI_AGENT_SYSTEM_PROMPT",
 	} {
 		if !strings.Contains(text, want) {
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const (
+	ReadArtifactName        = "read_artifact"
+	defaultArtifactReadSize = 16 * 1024
+	maxArtifactReadSize     = 64 * 1024
+
+	// A Session keeps at most this many artifacts, and this many bytes of
+	// them; the oldest are dropped first.
+	maxArtifacts     = 100
+	maxArtifactBytes = 64 * 1024 * 1024
+)
+
+// artifact is the full text of a tool result that was too large to hand to
+// the model.
+type artifact struct {
+	tool    string
+	content string
+}
+
+// artifactStore holds the artifacts of a Session in the order they were
+// stored.
+type artifactStore struct {
+	byID  map[string]artifact
+	order []string
+	bytes int
+	seq   int
+}
+
+// StoreArtifact keeps content for the rest of the Session, or until newer
+// artifacts push it out, and returns its id.
+func StoreArtifact(tool, content string) string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	s := &session.artifacts
+	if s.byID == nil {
+		s.byID = make(map[string]artifact)
+	}
+	for len(s.order) > 0 && (len(s.order) >= maxArtifacts || s.bytes+len(content) > maxArtifactBytes) {
+		s.bytes -= len(s.byID[s.order[0]].content)
+		delete(s.byID, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.seq++
+	id := fmt.Sprintf("artifact-%d", s.seq)
+	s.byID[id] = artifact{tool: tool, content: content}
+	s.order = append(s.order, id)
+	s.bytes += len(content)
+	return id
+}
+
+// lookupArtifact returns the artifact stored as id. stored reports whether
+// the id was handed out in this Session, even if the artifact was dropped.
+func lookupArtifact(id string) (a artifact, ok, stored bool) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	a, ok = session.artifacts.byID[id]
+	var n int
+	if _, err := fmt.Sscanf(id, "artifact-%d", &n); err == nil {
+		stored = n >= 1 && n <= session.artifacts.seq
+	}
+	return a, ok, stored
+}
+
+// Truncate fits the model-facing text of r into budget bytes. A longer
+// text is stored as an artifact and replaced by its head and tail and a
+// notice that points to read_artifact. When the notice alone does not fit,
+// a shorter one without head and tail takes its place.
+func Truncate(tool string, r ToolResult, budget int) ToolResult {
+	text := r.ModelText()
+	if len(text) <= budget {
+		return r
+	}
+	id := StoreArtifact(tool, text)
+	notice := fmt.Sprintf("[The result of %s is %d bytes, over its budget of %d. The full result is stored as %s; "+
+		"call %s with artifact_id=%q and an offset to read the omitted part.]",
+		tool, len(text), budget, id, ReadArtifactName, id)
+	const gap = "%s\n%s\n… [%d bytes omitted] …\n%s"
+	// The omitted count never has more digits than len(text).
+	if keep := budget - len(fmt.Sprintf(gap, notice, "", len(text), "")); keep >= 0 {
+		head := clipUTF8(text, keep/2)
+		tail := text[utf8Start(text, len(text)-keep/2):]
+		r.Text = fmt.Sprintf(gap, notice, head, len(text)-len(head)-len(tail), tail)
+	} else {
+		r.Text = fmt.Sprintf("[Result stored as %s; read it with %s.]", id, ReadArtifactName)
+	}
+	if r.Summary != "" {
+		r.Summary += "，"
+	}
+	r.Summary += fmt.Sprintf("已截断，完整结果见 %s", id)
+	return r
+}
+
+// utf8Start moves i forward to the start of a rune.
+func utf8Start(s string, i int) int {
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
+
+// ReadArtifact pages through the artifacts of truncated tool results.
+type ReadArtifact struct{}
+
+func (ra *ReadArtifact) Name() string { return ReadArtifactName }
+
+func (ra *ReadArtifact) ReadOnly(inference.ToolCall) bool { return true }
+
+func (ra *ReadArtifact) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        ra.Name(),
+			"description": "Read part of the full result of an earlier tool call that was truncated because it was too large. Page with offset until the notice says the end is reached.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"artifact_id": map[string]any{
+						"type":        "string",
+						"description": "The artifact id given in the truncated result, e.g. artifact-1.",
+					},
+					"offset": map[string]any{
+						"type":        "integer",
+						"minimum":     0,
+						"description": "Byte offset to start reading from. Defaults to 0.",
+					},
+					"limit": map[string]any{
+						"type":        "integer",
+						"minimum":     1,
+						"maximum":     maxArtifactReadSize,
+						"description": fmt.Sprintf("Maximum number of bytes to return. Defaults to %d.", defaultArtifactReadSize),
+					},
+				},
+				"required": []string{"artifact_id"},
+			},
+		},
+	}
+}
+
+func (ra *ReadArtifact) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	id, _ := args.Function.Arguments["artifact_id"].(string)
+	if id == "" {
+		return Failure(errors.New("artifact_id is required"))
+	}
+	a, ok, stored := lookupArtifact(id)
+	if !ok && stored {
+		return Failure(fmt.Errorf("artifact %q was dropped to make room for newer ones", id))
+	}
+	if !ok {
+		return Failure(fmt.Errorf("no artifact %q in this session", id))
+	}
+	offset, limit := 0, defaultArtifactReadSize
+	if n, ok := args.Function.Arguments["offset"].(float64); ok && n > 0 {
+		offset = int(n)
+	}
+	if n, ok := args.Function.Arguments["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), maxArtifactReadSize)
+	}
+	if offset >= len(a.content) {
+		return Failure(fmt.Errorf("offset %d is past the end of the artifact (%d bytes)", offset, len(a.content)))
+	}
+	start := utf8Start(a.content, offset)
+	chunk := clipUTF8(a.content[start:], limit)
+	if chunk == "" {
+		_, size := utf8.DecodeRuneInString(a.content[start:])
+		chunk = a.content[start : start+size]
+	}
+	end := start + len(chunk)
+
+	kv := []any{"artifact_id", id, "tool", a.tool, "content", chunk, "offset", start, "end", end, "total_bytes", len(a.content)}
+	if end < len(a.content) {
+		kv = append(kv, "notice", fmt.Sprintf("Showing bytes %d-%d of %d. Call %s with offset=%d to continue.",
+			start, end, len(a.content), ReadArtifactName, end))
+	} else {
+		kv = append(kv, "notice", "This is the end of the artifact.")
+	}
+	return Success(kv...).WithSummary("%s 第 %d-%d 字节，共 %d 字节", id, start, end, len(a.content))
+}
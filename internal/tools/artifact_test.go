@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func readArtifact(t *testing.T, args map[string]any) ToolResult {
+	t.Helper()
+	return (&ReadArtifact{}).Call(context.Background(), inference.ToolCall{
+		Function: inference.Function{Name: ReadArtifactName, Arguments: args},
+	})
+}
+
+func TestTruncate_storesArtifact(t *testing.T) {
+	t.Cleanup(ResetSession)
+	small := Success("stdout", "ok")
+	if got := Truncate("run_shell", small, 1000); got.Text != "" {
+		t.Fatalf("small result was truncated: %q", got.Text)
+	}
+
+	// Multi-byte runes must not be split at either cut.
+	full := Success("stdout", strings.Repeat("日志行\n", 2000)).WithSummary("退出码 0")
+	got := Truncate("run_shell", full, 2000)
+	if len(got.Text) > 2000 || !strings.Contains(got.Text, `artifact_id="artifact-1"`) || !strings.HasSuffix(got.Text, `日志行\n"}}`) {
+		t.Fatalf("truncated text (%d bytes):\n%s", len(got.Text), got.Text)
+	}
+	if !strings.Contains(got.Text, "bytes omitted") || strings.ContainsRune(got.Text, '�') {
+		t.Fatalf("truncated text:\n%s", got.Text)
+	}
+	if got.Summary != "退出码 0，已截断，完整结果见 artifact-1" || got.Data["stdout"] != full.Data["stdout"] {
+		t.Fatalf("summary = %q", got.Summary)
+	}
+
+	// Paging through the artifact yields the full model-facing text.
+	var pages strings.Builder
+	for offset := 0.0; ; {
+		page := readArtifact(t, map[string]any{"artifact_id": "artifact-1", "offset": offset, "limit": 5000.0})
+		if page.Failed() {
+			t.Fatal(page.Error())
+		}
+		pages.WriteString(page.Data["content"].(string))
+		end := page.Data["end"].(int)
+		if end == page.Data["total_bytes"].(int) {
+			break
+		}
+		offset = float64(end)
+	}
+	if pages.String() != full.ModelText() {
+		t.Fatal("pages do not add up to the full result")
+	}
+
+	if page := readArtifact(t, map[string]any{"artifact_id": "artifact-9"}); !page.Failed() {
+		t.Fatal("unknown artifact was read")
+	}
+	ResetSession()
+	if page := readArtifact(t, map[string]any{"artifact_id": "artifact-1"}); !page.Failed() {
+		t.Fatal("artifact survived ResetSession")
+	}
+}
+
+func TestTruncate_tinyBudget(t *testing.T) {
+	t.Cleanup(ResetSession)
+	got := Truncate("run_shell", Success("stdout", strings.Repeat("x", 1000)), 80)
+	if len(got.Text) > 80 || !strings.Contains(got.Text, "artifact-1") {
+		t.Fatalf("truncated text (%d bytes): %s", len(got.Text), got.Text)
+	}
+}
+
+func TestStoreArtifact_dropsOldest(t *testing.T) {
+	t.Cleanup(ResetSession)
+	for range maxArtifacts + 1 {
+		StoreArtifact("run_shell", "output")
+	}
+	page := readArtifact(t, map[string]any{"artifact_id": "artifact-1"})
+	if !page.Failed() || !strings.Contains(page.Error(), "dropped") {
+		t.Fatalf("oldest artifact: %+v", page)
+	}
+	if page := readArtifact(t, map[string]any{"artifact_id": fmt.Sprintf("artifact-%d", maxArtifacts+1)}); page.Failed() {
+		t.Fatal(page.Error())
+	}
+
+	id := StoreArtifact("run_shell", strings.Repeat("x", maxArtifactBytes))
+	if n := len(session.artifacts.order); n != 1 || session.artifacts.order[0] != id {
+		t.Fatalf("artifacts after a large one = %v", session.artifacts.order)
+	}
+}
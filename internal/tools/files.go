@@ -2,13 +2,16 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/loveRyujin/mini-agent/internal/inference"
 )
@@ -17,12 +20,14 @@ type ReadFile struct{}
 
 func (rf *ReadFile) Name() string { return "read_file" }
 
+func (rf *ReadFile) ReadOnly(inference.ToolCall) bool { return true }
+
 func (rf *ReadFile) Definition() map[string]any {
 	return map[string]any{
 		"type": "function",
 		"function": map[string]any{
 			"name":        rf.Name(),
-			"description": "Read the full contents of a single file in the workspace. Use workspace_search to find text or files by pattern.",
+			"description": "Read a text file in the workspace. Large files are returned in line ranges; follow the notice to page with offset/limit. Use workspace_search to find text or files by pattern.",
 			"parameters": map[string]any{
 				"type": "object",
 				"properties": map[string]any{
@@ -30,6 +35,18 @@ func (rf *ReadFile) Definition() map[string]any {
 						"type":        "string",
 						"description": "Relative path to the file in the workspace.",
 					},
+					"offset": map[string]any{
+						"type":        "integer",
+						"description": "1-based line number to start reading from. Defaults to 1.",
+					},
+					"limit": map[string]any{
+						"type":        "integer",
+						"description": "Maximum number of lines to return. Defaults to as many as fit in the size cap.",
+					},
+					"line_numbers": map[string]any{
+						"type":        "boolean",
+						"description": "Prefix each line with its line number.",
+					},
 				},
 				"required": []string{"path"},
 			},
@@ -49,20 +66,142 @@ func toolPathArg(args inference.ToolCall) (string, error) {
 	return path, nil
 }
 
-func (rf *ReadFile) Call(ctx context.Context, args inference.ToolCall) map[string]any {
+func (rf *ReadFile) Call(ctx context.Context, args inference.ToolCall) ToolResult {
 	path, err := toolPathArg(args)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	resolved, err := ResolveWorkspacePath(path)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
+	}
+	opts := readOptions{Offset: 1, MaxBytes: defaultReadMaxBytes}
+	if n, ok := args.Function.Arguments["offset"].(float64); ok && n > 0 {
+		opts.Offset = int(n)
+	}
+	if n, ok := args.Function.Arguments["limit"].(float64); ok && n > 0 {
+		opts.Limit = int(n)
+	}
+	opts.LineNumbers, _ = args.Function.Arguments["line_numbers"].(bool)
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return Failure(err)
 	}
-	content, err := os.ReadFile(resolved)
+	defer f.Close()
+	h := sha256.New()
+	res, err := readTextLines(io.TeeReader(f, h), opts)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(fmt.Errorf("%s: %w", path, err))
+	}
+	if tracksReads(ctx) {
+		recordFile(resolved, [sha256.Size]byte(h.Sum(nil)))
 	}
-	return successResp(args.ID, "file_content", string(content))
+
+	kv := []any{
+		"file_content", res.Content,
+		"total_lines", res.TotalLines,
+	}
+	if res.Truncated {
+		kv = append(kv, "start_line", res.StartLine, "end_line", res.EndLine)
+		if res.EndLine < res.TotalLines {
+			kv = append(kv, "notice", fmt.Sprintf("Showing lines %d-%d of %d. Call read_file with offset=%d to continue.",
+				res.StartLine, res.EndLine, res.TotalLines, res.EndLine+1))
+		}
+		return Success(kv...).WithSummary("第 %d-%d 行，共 %d 行", res.StartLine, res.EndLine, res.TotalLines)
+	}
+	return Success(kv...).WithSummary("%d 行", res.TotalLines)
+}
+
+const defaultReadMaxBytes = 64 * 1024
+
+var (
+	errBinaryFile = errors.New("binary file; contents not shown")
+	errNotUTF8    = errors.New("file is not valid UTF-8 text; contents not shown")
+)
+
+type readOptions struct {
+	Offset      int
+	Limit       int
+	MaxBytes    int
+	LineNumbers bool
+}
+
+type readResult struct {
+	Content    string
+	StartLine  int
+	EndLine    int
+	TotalLines int
+	Truncated  bool
+}
+
+// readTextLines returns the requested line window of a text file, stopping
+// at opts.Limit lines or opts.MaxBytes of output, whichever comes first.
+// The rest of the file is still scanned so TotalLines is exact.
+func readTextLines(r io.Reader, opts readOptions) (readResult, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	head, _ := br.Peek(binarySniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return readResult{}, errBinaryFile
+	}
+
+	res := readResult{StartLine: opts.Offset, EndLine: opts.Offset - 1}
+	var (
+		out  strings.Builder
+		line []byte
+		full bool
+	)
+	for {
+		var err error
+		line, err = readLine(br, line)
+		if len(line) > 0 {
+			res.TotalLines++
+			if !utf8.Valid(line) {
+				return readResult{}, errNotUTF8
+			}
+			if res.TotalLines >= opts.Offset && !full {
+				text := string(line)
+				if opts.LineNumbers {
+					text = fmt.Sprintf("%6d\t%s", res.TotalLines, text)
+				}
+				switch {
+				case opts.Limit > 0 && res.EndLine-res.StartLine+1 >= opts.Limit:
+					full = true
+				case out.Len()+len(text) > opts.MaxBytes && out.Len() > 0:
+					full = true
+				default:
+					if len(text) > opts.MaxBytes {
+						text = clipUTF8(text, opts.MaxBytes) + "…\n"
+					}
+					out.WriteString(text)
+					res.EndLine = res.TotalLines
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return readResult{}, err
+		}
+	}
+
+	if opts.Offset > 1 && opts.Offset > res.TotalLines {
+		return readResult{}, fmt.Errorf("offset %d is past the end of the file (%d lines)", opts.Offset, res.TotalLines)
+	}
+	res.Content = out.String()
+	res.Truncated = res.StartLine > 1 || res.EndLine < res.TotalLines
+	return res, nil
+}
+
+func clipUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
 
 type WriteFile struct{}
@@ -93,32 +232,43 @@ func (wf *WriteFile) Definition() map[string]any {
 	}
 }
 
-func (wf *WriteFile) Call(ctx context.Context, args inference.ToolCall) map[string]any {
+func (wf *WriteFile) Call(ctx context.Context, args inference.ToolCall) ToolResult {
 	path, ok := args.Function.Arguments["path"].(string)
 	if !ok || path == "" {
-		return failResp(args.ID, errors.New("path is required"))
+		return Failure(errors.New("path is required"))
 	}
 	content, ok := args.Function.Arguments["content"].(string)
 	if !ok {
-		return failResp(args.ID, errors.New("content must be a string"))
+		return Failure(errors.New("content must be a string"))
 	}
 	resolved, err := ResolveWorkspacePath(path)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
+	}
+	if err := checkWrite(resolved); err != nil {
+		return Failure(fmt.Errorf("%s: %w", path, err))
+	}
+	report, err := writeWorkspaceFile(resolved, []byte(content))
+	if err != nil {
+		return Failure(err)
 	}
-	if err := os.MkdirAll(filepath.Dir(resolved), 0o755); err != nil {
-		return failResp(args.ID, err)
+	kv := []any{"path", path, "created", report.Created}
+	if len(report.Normalized) > 0 {
+		kv = append(kv, "normalized", report.Normalized)
 	}
-	if err := os.WriteFile(resolved, []byte(content), 0o644); err != nil {
-		return failResp(args.ID, err)
+	verb := "写入"
+	if report.Created {
+		verb = "创建"
 	}
-	return successResp(args.ID, "path", path)
+	return Success(notifyMutation(ctx, kv, resolved)...).WithSummary("%s %d 字节", verb, len(content))
 }
 
 type ListFile struct{}
 
 func (lf *ListFile) Name() string { return "list_file" }
 
+func (lf *ListFile) ReadOnly(inference.ToolCall) bool { return true }
+
 func (lf *ListFile) Definition() map[string]any {
 	return map[string]any{
 		"type": "function",
@@ -139,14 +289,14 @@ func (lf *ListFile) Definition() map[string]any {
 	}
 }
 
-func (lf *ListFile) Call(ctx context.Context, args inference.ToolCall) map[string]any {
+func (lf *ListFile) Call(ctx context.Context, args inference.ToolCall) ToolResult {
 	dir, err := toolPathArg(args)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	resolved, err := ResolveWorkspacePath(dir)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	var files []string
 	err = filepath.Walk(resolved, func(path string, info os.FileInfo, err error) error {
@@ -167,19 +317,17 @@ func (lf *ListFile) Call(ctx context.Context, args inference.ToolCall) map[strin
 		return nil
 	})
 	if err != nil {
-		return failResp(args.ID, err)
-	}
-	result, err := json.Marshal(files)
-	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
-	return successResp(args.ID, "files", string(result))
+	return Success("files", files).WithSummary("%d 项", len(files))
 }
 
 type WorkspaceSearch struct{}
 
 func (ws *WorkspaceSearch) Name() string { return "workspace_search" }
 
+func (ws *WorkspaceSearch) ReadOnly(inference.ToolCall) bool { return true }
+
 func (ws *WorkspaceSearch) Definition() map[string]any {
 	return map[string]any{
 		"type": "function",
@@ -202,6 +350,10 @@ func (ws *WorkspaceSearch) Definition() map[string]any {
 						"enum":        []string{"content", "filename"},
 						"description": "Search file contents (default) or match filenames by glob.",
 					},
+					"max_results": map[string]any{
+						"type":        "integer",
+						"description": "Stop after this many matches. Defaults to 200.",
+					},
 				},
 				"required": []string{"pattern"},
 			},
@@ -215,14 +367,14 @@ type searchMatch struct {
 	Content string `json:"content,omitempty"`
 }
 
-func (ws *WorkspaceSearch) Call(ctx context.Context, args inference.ToolCall) map[string]any {
+func (ws *WorkspaceSearch) Call(ctx context.Context, args inference.ToolCall) ToolResult {
 	pattern, ok := args.Function.Arguments["pattern"].(string)
 	if !ok || pattern == "" {
-		return failResp(args.ID, errors.New("pattern is required"))
+		return Failure(errors.New("pattern is required"))
 	}
 	searchPath, err := toolPathArg(args)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	mode := "content"
 	if m, ok := args.Function.Arguments["mode"].(string); ok && m != "" {
@@ -230,91 +382,34 @@ func (ws *WorkspaceSearch) Call(ctx context.Context, args inference.ToolCall) ma
 	}
 	resolved, err := ResolveWorkspacePath(searchPath)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	info, err := os.Stat(resolved)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
 	if !info.IsDir() {
-		return failResp(args.ID, errors.New("path must be a directory"))
+		return Failure(errors.New("path must be a directory"))
 	}
-	var matches []searchMatch
-	switch mode {
-	case "filename":
-		matches, err = searchFilenames(resolved, pattern)
-	case "content":
-		matches, err = searchFileContents(resolved, pattern)
-	default:
-		return failResp(args.ID, fmt.Errorf("unsupported mode %q", mode))
+	if mode != "content" && mode != "filename" {
+		return Failure(fmt.Errorf("unsupported mode %q", mode))
 	}
-	if err != nil {
-		return failResp(args.ID, err)
+	maxResults := defaultSearchMaxResults
+	if n, ok := args.Function.Arguments["max_results"].(float64); ok && n > 0 {
+		maxResults = int(n)
 	}
-	result, err := json.Marshal(matches)
+	res, err := runSearch(ctx, searchRequest{
+		Root:       resolved,
+		Pattern:    pattern,
+		Mode:       mode,
+		MaxResults: maxResults,
+	})
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
-	return successResp(args.ID, "matches", string(result))
-}
-
-func searchFilenames(root, pattern string) ([]searchMatch, error) {
-	var matches []searchMatch
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		rel, err := filepath.Rel(root, path)
-		if err != nil {
-			return err
-		}
-		rel = filepath.ToSlash(rel)
-		matched, err := filepath.Match(pattern, filepath.Base(path))
-		if err != nil {
-			return err
-		}
-		if matched {
-			matches = append(matches, searchMatch{File: rel})
-		}
-		return nil
-	})
-	return matches, err
-}
-
-func searchFileContents(root, pattern string) ([]searchMatch, error) {
-	var matches []searchMatch
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		rel, err := filepath.Rel(root, path)
-		if err != nil {
-			return err
-		}
-		rel = filepath.ToSlash(rel)
-		f, err := os.Open(path)
-		if err != nil {
-			return nil
-		}
-		defer f.Close()
-		scanner := bufio.NewScanner(f)
-		lineNum := 0
-		for scanner.Scan() {
-			lineNum++
-			line := scanner.Text()
-			if strings.Contains(line, pattern) {
-				matches = append(matches, searchMatch{
-					File: rel, Line: lineNum, Content: line,
-				})
-			}
-		}
-		return scanner.Err()
-	})
-	return matches, err
+	summary := fmt.Sprintf("%d 处匹配", len(res.Matches))
+	if res.Truncated {
+		summary += "（已截断）"
+	}
+	return Success("matches", res.Matches, "truncated", res.Truncated).WithSummary("%s", summary)
 }
@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const (
+	maxGitDiffBytes   = 32 * 1024
+	maxGitFiles       = 300
+	defaultGitLog     = 20
+	maxGitLog         = 100
+	maxGitBlameLines  = 400
+	maxGitStashListed = 50
+)
+
+// GitTool gives the model a structured view of the Workspace's repository.
+// Inspecting subcommands run without the Approval Gate; the ones that change
+// the index, history or working tree pass it.
+type GitTool struct{}
+
+func (g *GitTool) Name() string { return "git" }
+
+func (g *GitTool) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        g.Name(),
+			"description": "Use git in the workspace repository. status lists changed files; diff shows changes (working tree, staged with staged=true, or against ref) with per-file line counts; log lists commits; show displays one commit with its patch; blame attributes lines to commits. add stages paths, commit records staged changes with message, stash pushes, pops or lists stashes, and checkout restores paths from the index or from ref, discarding their changes. Paths are relative to the workspace.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"subcommand": map[string]any{
+						"type": "string",
+						"enum": []string{"status", "diff", "log", "show", "blame", "add", "commit", "stash", "checkout"},
+					},
+					"paths": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Files or directories. Filters status, diff, log and show; required for add and checkout.",
+					},
+					"ref": map[string]any{
+						"type":        "string",
+						"description": "A revision such as HEAD~1, a branch or a commit hash. diff: compare against it; log: start from it; show: the commit (default HEAD); checkout: restore from it instead of the index.",
+					},
+					"staged": map[string]any{
+						"type":        "boolean",
+						"description": "For diff: show staged changes instead of unstaged ones.",
+					},
+					"stat_only": map[string]any{
+						"type":        "boolean",
+						"description": "For diff: return only per-file line counts, without the patch.",
+					},
+					"max_count": map[string]any{
+						"type":        "integer",
+						"description": fmt.Sprintf("For log: number of commits (default %d, max %d).", defaultGitLog, maxGitLog),
+					},
+					"path": map[string]any{
+						"type":        "string",
+						"description": "For blame: the file.",
+					},
+					"start_line": map[string]any{
+						"type":        "integer",
+						"description": "For blame: first line (1-based).",
+					},
+					"end_line": map[string]any{
+						"type":        "integer",
+						"description": "For blame: last line (inclusive).",
+					},
+					"message": map[string]any{
+						"type":        "string",
+						"description": "For commit: the commit message. For stash push: an optional description.",
+					},
+					"stash_action": map[string]any{
+						"type":        "string",
+						"enum":        []string{"push", "pop", "list"},
+						"description": "For stash: what to do (default push).",
+					},
+				},
+				"required": []string{"subcommand"},
+			},
+		},
+	}
+}
+
+func (g *GitTool) NeedsApproval(args inference.ToolCall) bool {
+	switch sub, _ := args.Function.Arguments["subcommand"].(string); sub {
+	case "status", "diff", "log", "show", "blame":
+		return false
+	case "stash":
+		action, _ := args.Function.Arguments["stash_action"].(string)
+		return action != "list"
+	default:
+		return true
+	}
+}
+
+// ReadOnly holds for the subcommands that skip the Approval Gate.
+func (g *GitTool) ReadOnly(args inference.ToolCall) bool { return !g.NeedsApproval(args) }
+
+func (g *GitTool) ApprovalSummary(args inference.ToolCall) string {
+	a := args.Function.Arguments
+	sub, _ := a["subcommand"].(string)
+	ref, _ := a["ref"].(string)
+	paths := strings.Join(stringList(a["paths"]), " ")
+	switch sub {
+	case "add":
+		return "git add " + paths
+	case "commit":
+		msg, _ := a["message"].(string)
+		subject, _, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+		return "git commit: " + subject
+	case "stash":
+		action, _ := a["stash_action"].(string)
+		if action == "" {
+			action = "push"
+		}
+		return "git stash " + action
+	case "checkout":
+		if ref != "" {
+			return "git checkout " + ref + " -- " + paths
+		}
+		return "git checkout -- " + paths
+	default:
+		return "git " + sub
+	}
+}
+
+func (g *GitTool) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	a := args.Function.Arguments
+	sub, _ := a["subcommand"].(string)
+	repo, err := git.Open(ctx, WorkspaceRoot())
+	if err != nil {
+		return Failure(err)
+	}
+	ref, err := gitRefArg(a)
+	if err != nil {
+		return Failure(err)
+	}
+	paths, resolved, err := gitPathsArg(a)
+	if err != nil {
+		return Failure(err)
+	}
+
+	switch sub {
+	case "status":
+		st, err := repo.Status(ctx, paths...)
+		if err != nil {
+			return Failure(err)
+		}
+		for i := range st.Files {
+			st.Files[i].Path = repo.Rel(st.Files[i].Path)
+			if st.Files[i].OrigPath != "" {
+				st.Files[i].OrigPath = repo.Rel(st.Files[i].OrigPath)
+			}
+		}
+		files, truncated := capList(st.Files, maxGitFiles)
+		return Success("branch", st.Branch, "upstream", st.Upstream, "ahead", st.Ahead, "behind", st.Behind,
+			"files", files, "truncated", truncated).WithSummary("%s，%d 个文件有变更", st.Branch, len(st.Files))
+
+	case "diff":
+		staged, _ := a["staged"].(bool)
+		opts := git.DiffOptions{Staged: staged, Base: ref, Paths: paths}
+		stat, err := repo.DiffStat(ctx, opts)
+		if err != nil {
+			return Failure(err)
+		}
+		files, truncated := capList(relChanges(repo, stat), maxGitFiles)
+		if statOnly, _ := a["stat_only"].(bool); statOnly {
+			return Success("files", files, "truncated", truncated).WithSummary("%d 个文件", len(stat))
+		}
+		diff, err := repo.Diff(ctx, opts)
+		if err != nil {
+			return Failure(err)
+		}
+		diff, clipped := clipGitOutput(diff)
+		return Success("files", files, "diff", diff, "truncated", truncated || clipped).WithSummary("%d 个文件", len(stat))
+
+	case "log":
+		n := defaultGitLog
+		if v, ok := a["max_count"].(float64); ok && v > 0 {
+			n = min(int(v), maxGitLog)
+		}
+		commits, err := repo.Log(ctx, ref, n, paths...)
+		if err != nil {
+			return Failure(err)
+		}
+		for i := range commits {
+			commits[i].Body = ""
+		}
+		return Success("commits", commits).WithSummary("%d 个提交", len(commits))
+
+	case "show":
+		if ref == "" {
+			ref = "HEAD"
+		}
+		commit, err := repo.ShowCommit(ctx, ref)
+		if err != nil {
+			return Failure(err)
+		}
+		patch, err := repo.ShowPatch(ctx, ref, paths...)
+		if err != nil {
+			return Failure(err)
+		}
+		patch, clipped := clipGitOutput(patch)
+		return Success("commit", commit, "diff", patch, "truncated", clipped).WithSummary("%s", commit.Subject)
+
+	case "blame":
+		path, _ := a["path"].(string)
+		if path == "" {
+			return Failure(errors.New("path is required for blame"))
+		}
+		abs, err := ResolveWorkspacePath(path)
+		if err != nil {
+			return Failure(err)
+		}
+		start, _ := a["start_line"].(float64)
+		end, _ := a["end_line"].(float64)
+		lines, err := repo.Blame(ctx, gitRelPath(abs), int(start), int(end))
+		if err != nil {
+			return Failure(err)
+		}
+		lines, truncated := capList(lines, maxGitBlameLines)
+		return Success("path", workspaceRel(abs), "lines", lines, "truncated", truncated).WithSummary("%d 行", len(lines))
+
+	case "add":
+		if len(paths) == 0 {
+			return Failure(errors.New("paths are required for add"))
+		}
+		if err := repo.Add(ctx, paths...); err != nil {
+			return Failure(err)
+		}
+		staged, err := repo.DiffStat(ctx, git.DiffOptions{Staged: true})
+		if err != nil {
+			return Failure(err)
+		}
+		files, truncated := capList(relChanges(repo, staged), maxGitFiles)
+		return Success("staged", files, "truncated", truncated).WithSummary("暂存区 %d 个文件", len(staged))
+
+	case "commit":
+		message, _ := a["message"].(string)
+		if strings.TrimSpace(message) == "" {
+			return Failure(errors.New("message is required for commit"))
+		}
+		if has, err := repo.HasStagedChanges(ctx); err != nil {
+			return Failure(err)
+		} else if !has {
+			return Failure(errors.New("nothing is staged; stage changes with add first"))
+		}
+		commit, err := repo.Commit(ctx, message)
+		if err != nil {
+			return Failure(err)
+		}
+		return Success("commit", commit).WithSummary("%s", commit.Subject)
+
+	case "stash":
+		action, _ := a["stash_action"].(string)
+		if action == "" {
+			action = "push"
+		}
+		switch action {
+		case "list":
+			entries, err := repo.StashList(ctx)
+			if err != nil {
+				return Failure(err)
+			}
+			entries, truncated := capList(entries, maxGitStashListed)
+			return Success("stashes", entries, "truncated", truncated).WithSummary("%d 个 stash", len(entries))
+		case "push":
+			message, _ := a["message"].(string)
+			err = repo.StashPush(ctx, message)
+		case "pop":
+			err = repo.StashPop(ctx)
+		default:
+			return Failure(fmt.Errorf("unsupported stash_action %q", action))
+		}
+		if err != nil {
+			return Failure(err)
+		}
+		return Success(notifyMutation(ctx, []any{"stash", action}, WorkspaceRoot())...).WithSummary("stash %s", action)
+
+	case "checkout":
+		if len(paths) == 0 {
+			return Failure(errors.New("paths are required for checkout"))
+		}
+		if err := repo.CheckoutFiles(ctx, ref, paths...); err != nil {
+			return Failure(err)
+		}
+		restored := make([]string, len(resolved))
+		for i, abs := range resolved {
+			restored[i] = workspaceRel(abs)
+		}
+		return Success(notifyMutation(ctx, []any{"restored", restored}, resolved...)...).WithSummary("已恢复 %d 个路径", len(restored))
+
+	default:
+		return Failure(fmt.Errorf("unsupported subcommand %q", sub))
+	}
+}
+
+// gitRefArg returns the ref argument, refusing values git would parse as
+// options.
+func gitRefArg(a map[string]any) (string, error) {
+	ref, _ := a["ref"].(string)
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	return ref, nil
+}
+
+// gitPathsArg resolves the paths argument inside the Workspace. It returns
+// the pathspecs to hand to git, relative to the Workspace root, and the
+// absolute paths.
+func gitPathsArg(a map[string]any) (specs, resolved []string, err error) {
+	raw, ok := a["paths"].([]any)
+	if !ok && a["paths"] != nil {
+		return nil, nil, errors.New("paths must be an array of strings")
+	}
+	for _, p := range raw {
+		s, ok := p.(string)
+		if !ok || s == "" {
+			return nil, nil, errors.New("paths must be non-empty strings")
+		}
+		abs, err := ResolveWorkspacePath(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		specs = append(specs, gitRelPath(abs))
+		resolved = append(resolved, abs)
+	}
+	return specs, resolved, nil
+}
+
+func gitRelPath(abs string) string {
+	rel, err := filepath.Rel(WorkspaceRoot(), abs)
+	if err != nil {
+		return abs
+	}
+	return filepath.ToSlash(rel)
+}
+
+// relChanges makes the paths of a diff stat relative to the Workspace.
+func relChanges(repo *git.Repo, changes []git.FileChange) []git.FileChange {
+	for i := range changes {
+		changes[i].Path = repo.Rel(changes[i].Path)
+		if changes[i].OrigPath != "" {
+			changes[i].OrigPath = repo.Rel(changes[i].OrigPath)
+		}
+	}
+	return changes
+}
+
+func stringList(v any) []string {
+	raw, _ := v.([]any)
+	var out []string
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func capList[T any](items []T, max int) ([]T, bool) {
+	if len(items) > max {
+		return items[:max], true
+	}
+	return items, false
+}
+
+func clipGitOutput(s string) (string, bool) {
+	if len(s) <= maxGitDiffBytes {
+		return s, false
+	}
+	return clipUTF8(s, maxGitDiffBytes) + "\n… (truncated; narrow with paths or use stat_only)", true
+}
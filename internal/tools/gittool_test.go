@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+// setupGitWorkspace makes a Workspace that is a repository with one commit
+// of a.txt.
+func setupGitWorkspace(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Dev")
+	t.Setenv("GIT_AUTHOR_EMAIL", "dev@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Dev")
+	t.Setenv("GIT_COMMITTER_EMAIL", "dev@example.com")
+
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"add", "a.txt"}, {"commit", "-q", "-m", "Add a.txt"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+type gitToolResp struct {
+	Status string `json:"status"`
+	Data   struct {
+		Branch string `json:"branch"`
+		Files  []struct {
+			Path      string `json:"path"`
+			Staged    string `json:"staged"`
+			Unstaged  string `json:"unstaged"`
+			Untracked bool   `json:"untracked"`
+			Added     int    `json:"added"`
+			Deleted   int    `json:"deleted"`
+		} `json:"files"`
+		Staged []struct {
+			Path string `json:"path"`
+		} `json:"staged"`
+		Diff    string `json:"diff"`
+		Commits []struct {
+			Hash    string `json:"hash"`
+			Subject string `json:"subject"`
+		} `json:"commits"`
+		Commit struct {
+			Hash    string `json:"hash"`
+			Subject string `json:"subject"`
+		} `json:"commit"`
+		Lines []struct {
+			Line int    `json:"line"`
+			Text string `json:"text"`
+		} `json:"lines"`
+		Restored []string `json:"restored"`
+	} `json:"data"`
+}
+
+func gitCall(t *testing.T, args map[string]any) gitToolResp {
+	t.Helper()
+	out := callTool(t, &GitTool{}, args)
+	var resp gitToolResp
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("unmarshal %q: %v", out, err)
+	}
+	if resp.Status != "SUCCESS" {
+		t.Fatalf("git %v = %s", args, out)
+	}
+	return resp
+}
+
+func TestGitTool_statusAndDiff(t *testing.T) {
+	dir := setupGitWorkspace(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := gitCall(t, map[string]any{"subcommand": "status"})
+	if st.Data.Branch != "main" || len(st.Data.Files) != 2 || st.Data.Files[0].Unstaged != "M" || !st.Data.Files[1].Untracked {
+		t.Fatalf("status = %+v", st.Data)
+	}
+
+	diff := gitCall(t, map[string]any{"subcommand": "diff", "paths": []any{"a.txt"}})
+	if len(diff.Data.Files) != 1 || diff.Data.Files[0].Added != 1 || diff.Data.Files[0].Deleted != 1 || !strings.Contains(diff.Data.Diff, "+2") {
+		t.Fatalf("diff = %+v", diff.Data)
+	}
+}
+
+func TestGitTool_addCommitLogBlame(t *testing.T) {
+	dir := setupGitWorkspace(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\nTWO\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	added := gitCall(t, map[string]any{"subcommand": "add", "paths": []any{"a.txt"}})
+	if len(added.Data.Staged) != 1 || added.Data.Staged[0].Path != "a.txt" {
+		t.Fatalf("add = %+v", added.Data)
+	}
+	committed := gitCall(t, map[string]any{"subcommand": "commit", "message": "Shout two"})
+	if committed.Data.Commit.Subject != "Shout two" || committed.Data.Commit.Hash == "" {
+		t.Fatalf("commit = %+v", committed.Data)
+	}
+
+	log := gitCall(t, map[string]any{"subcommand": "log", "max_count": float64(1)})
+	if len(log.Data.Commits) != 1 || log.Data.Commits[0].Hash != committed.Data.Commit.Hash {
+		t.Fatalf("log = %+v", log.Data)
+	}
+	blame := gitCall(t, map[string]any{"subcommand": "blame", "path": "a.txt", "start_line": float64(2), "end_line": float64(2)})
+	if len(blame.Data.Lines) != 1 || blame.Data.Lines[0].Text != "TWO" {
+		t.Fatalf("blame = %+v", blame.Data)
+	}
+
+	out := callTool(t, &GitTool{}, map[string]any{"subcommand": "commit", "message": "Empty"})
+	if !strings.Contains(out, "nothing is staged") {
+		t.Fatalf("empty commit = %s", out)
+	}
+}
+
+func TestGitTool_checkoutNotifiesObservers(t *testing.T) {
+	dir := setupGitWorkspace(t)
+	obs := withObserver(t, "")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("scratch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := gitCall(t, map[string]any{"subcommand": "checkout", "paths": []any{"a.txt"}})
+	if len(resp.Data.Restored) != 1 || resp.Data.Restored[0] != "a.txt" {
+		t.Fatalf("checkout = %+v", resp.Data)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(got) != "one\ntwo\n" {
+		t.Fatalf("a.txt = %q", got)
+	}
+	if len(obs.paths) != 1 || len(obs.paths[0]) != 1 || obs.paths[0][0] != filepath.Join(dir, "a.txt") {
+		t.Fatalf("observed = %v", obs.paths)
+	}
+}
+
+func TestGitTool_rejectsOptionsAndEscapes(t *testing.T) {
+	setupGitWorkspace(t)
+	for _, args := range []map[string]any{
+		{"subcommand": "log", "ref": "--output=/tmp/x"},
+		{"subcommand": "diff", "paths": []any{"../outside"}},
+		{"subcommand": "blame", "path": "/etc/passwd"},
+	} {
+		if out := callTool(t, &GitTool{}, args); !strings.Contains(out, "FAILED") {
+			t.Errorf("git %v = %s", args, out)
+		}
+	}
+}
+
+func TestGitTool_needsApproval(t *testing.T) {
+	tool := &GitTool{}
+	cases := []struct {
+		args map[string]any
+		want bool
+	}{
+		{map[string]any{"subcommand": "status"}, false},
+		{map[string]any{"subcommand": "diff"}, false},
+		{map[string]any{"subcommand": "blame"}, false},
+		{map[string]any{"subcommand": "stash", "stash_action": "list"}, false},
+		{map[string]any{"subcommand": "stash"}, true},
+		{map[string]any{"subcommand": "add"}, true},
+		{map[string]any{"subcommand": "commit"}, true},
+		{map[string]any{"subcommand": "checkout"}, true},
+	}
+	for _, c := range cases {
+		call := inference.ToolCall{Function: inference.Function{Arguments: c.args}}
+		if got := NeedsApproval(tool, call); got != c.want {
+			t.Errorf("NeedsApproval(%v) = %v, want %v", c.args, got, c.want)
+		}
+	}
+}
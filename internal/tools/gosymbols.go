@@ -0,0 +1,369 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const (
+	maxGoSymbolResults  = 200
+	maxGoSignatureBytes = 200
+)
+
+type GoSymbols struct{}
+
+func (gs *GoSymbols) Name() string { return "go_symbols" }
+
+func (gs *GoSymbols) ReadOnly(inference.ToolCall) bool { return true }
+
+func (gs *GoSymbols) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        gs.Name(),
+			"description": "Inspect Go source with the Go parser. outline lists the declarations (funcs, methods, types with fields, consts, vars) of a file or package directory with signatures and line ranges. definition finds where a symbol is declared; references finds identifiers with that name. Prefer this over workspace_search for Go code.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"action": map[string]any{
+						"type":        "string",
+						"enum":        []string{"outline", "definition", "references"},
+						"description": "What to look up.",
+					},
+					"path": map[string]any{
+						"type":        "string",
+						"description": "For outline: a .go file or a package directory. For definition/references: the directory to search recursively. Defaults to \".\".",
+					},
+					"name": map[string]any{
+						"type":        "string",
+						"description": "Symbol name for definition/references. Use Type.Method or Type.Field to select a member.",
+					},
+				},
+				"required": []string{"action"},
+			},
+		},
+	}
+}
+
+type goSymbol struct {
+	File      string     `json:"file"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Receiver  string     `json:"receiver,omitempty"`
+	Signature string     `json:"signature,omitempty"`
+	StartLine int        `json:"start_line"`
+	EndLine   int        `json:"end_line"`
+	Members   []goSymbol `json:"members,omitempty"`
+}
+
+// qualifiedName is how definition lookups address the symbol.
+func (s goSymbol) qualifiedName() string {
+	if s.Receiver != "" {
+		return s.Receiver + "." + s.Name
+	}
+	return s.Name
+}
+
+type goReference struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+}
+
+func (gs *GoSymbols) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	action, _ := args.Function.Arguments["action"].(string)
+	path, err := toolPathArg(args)
+	if err != nil {
+		return Failure(err)
+	}
+	resolved, err := ResolveWorkspacePath(path)
+	if err != nil {
+		return Failure(err)
+	}
+	name, _ := args.Function.Arguments["name"].(string)
+
+	switch action {
+	case "outline":
+		symbols, err := goOutline(resolved)
+		if err != nil {
+			return Failure(err)
+		}
+		return Success("symbols", symbols).WithSummary("%d 个符号", len(symbols))
+	case "definition":
+		if name == "" {
+			return Failure(errors.New("name is required for definition"))
+		}
+		defs, err := goDefinitions(ctx, resolved, name)
+		if err != nil {
+			return Failure(err)
+		}
+		return Success("definitions", defs).WithSummary("%d 处定义", len(defs))
+	case "references":
+		if name == "" {
+			return Failure(errors.New("name is required for references"))
+		}
+		refs, truncated, err := goReferences(ctx, resolved, name)
+		if err != nil {
+			return Failure(err)
+		}
+		return Success("references", refs, "truncated", truncated).WithSummary("%d 处引用", len(refs))
+	default:
+		return Failure(fmt.Errorf("unsupported action %q", action))
+	}
+}
+
+// goOutline lists declarations of one file, or of every non-test file of
+// the package in a directory.
+func goOutline(path string) ([]goSymbol, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") && !strings.HasSuffix(e.Name(), "_test.go") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, errors.New("no Go files in directory")
+		}
+	}
+
+	var symbols []goSymbol
+	for _, file := range files {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, fileSymbols(fset, f, workspaceRel(file))...)
+	}
+	return symbols, nil
+}
+
+func goDefinitions(ctx context.Context, root, name string) ([]goSymbol, error) {
+	var defs []goSymbol
+	err := walkGoFiles(ctx, root, func(fset *token.FileSet, f *ast.File, rel string) bool {
+		for _, s := range fileSymbols(fset, f, rel) {
+			if s.qualifiedName() == name || s.Name == name {
+				s.Members = nil
+				defs = append(defs, s)
+			}
+			for _, m := range s.Members {
+				if m.qualifiedName() == name || m.Name == name {
+					defs = append(defs, m)
+				}
+			}
+		}
+		return len(defs) < maxGoSymbolResults
+	})
+	return defs, err
+}
+
+// goReferences reports identifiers spelled like the last element of name.
+// Matching is syntactic: without type information, unrelated symbols that
+// share a name are included.
+func goReferences(ctx context.Context, root, name string) ([]goReference, bool, error) {
+	ident := name[strings.LastIndex(name, ".")+1:]
+	var (
+		refs      []goReference
+		truncated bool
+	)
+	err := walkGoFiles(ctx, root, func(fset *token.FileSet, f *ast.File, rel string) bool {
+		var src []string
+		ast.Inspect(f, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok || id.Name != ident || truncated {
+				return !truncated
+			}
+			if len(refs) >= maxGoSymbolResults {
+				truncated = true
+				return false
+			}
+			pos := fset.Position(id.Pos())
+			if src == nil {
+				data, _ := os.ReadFile(pos.Filename)
+				src = strings.Split(string(data), "\n")
+			}
+			text := ""
+			if pos.Line-1 < len(src) {
+				text = strings.TrimSpace(src[pos.Line-1])
+			}
+			refs = append(refs, goReference{File: rel, Line: pos.Line, Column: pos.Column, Text: text})
+			return true
+		})
+		return !truncated
+	})
+	return refs, truncated, err
+}
+
+// walkGoFiles parses every .go file under root in lexical order, skipping
+// vendor, testdata and hidden directories, until visit returns false.
+func walkGoFiles(ctx context.Context, root string, visit func(*token.FileSet, *ast.File, string) bool) error {
+	errStop := errors.New("stop")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		if !visit(fset, f, workspaceRel(path)) {
+			return errStop
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+func fileSymbols(fset *token.FileSet, f *ast.File, rel string) []goSymbol {
+	var symbols []goSymbol
+	span := func(n ast.Node) (int, int) {
+		return fset.Position(n.Pos()).Line, fset.Position(n.End()).Line
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := goSymbol{File: rel, Kind: "func", Name: d.Name.Name}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind = "method"
+				s.Receiver = receiverType(d.Recv.List[0].Type)
+			}
+			s.StartLine, s.EndLine = span(d)
+			s.Signature = nodeString(fset, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+			symbols = append(symbols, s)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					s := goSymbol{File: rel, Kind: "type", Name: sp.Name.Name}
+					s.StartLine, s.EndLine = span(sp)
+					s.Signature, s.Members = typeSummary(fset, rel, sp)
+					symbols = append(symbols, s)
+				case *ast.ValueSpec:
+					for _, n := range sp.Names {
+						s := goSymbol{File: rel, Kind: d.Tok.String(), Name: n.Name}
+						s.StartLine, s.EndLine = span(sp)
+						s.Signature = d.Tok.String() + " " + nodeString(fset, sp)
+						symbols = append(symbols, s)
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+func typeSummary(fset *token.FileSet, rel string, sp *ast.TypeSpec) (string, []goSymbol) {
+	var (
+		list  *ast.FieldList
+		kind  string
+		empty ast.Expr
+	)
+	switch t := sp.Type.(type) {
+	case *ast.StructType:
+		list, kind = t.Fields, "field"
+		empty = &ast.StructType{Fields: &ast.FieldList{}}
+	case *ast.InterfaceType:
+		list, kind = t.Methods, "method"
+		empty = &ast.InterfaceType{Methods: &ast.FieldList{}}
+	default:
+		return "type " + nodeString(fset, sp), nil
+	}
+	header := nodeString(fset, &ast.TypeSpec{Name: sp.Name, TypeParams: sp.TypeParams, Type: empty})
+	header = "type " + strings.TrimRight(header, "{ }")
+
+	var members []goSymbol
+	for _, field := range list.List {
+		start, end := fset.Position(field.Pos()).Line, fset.Position(field.End()).Line
+		typ := nodeString(fset, field.Type)
+		if len(field.Names) == 0 {
+			members = append(members, goSymbol{File: rel, Kind: "embedded", Name: typ, StartLine: start, EndLine: end})
+			continue
+		}
+		for _, n := range field.Names {
+			sig := n.Name + " " + typ
+			if kind == "method" {
+				sig = n.Name + strings.TrimPrefix(typ, "func")
+			}
+			members = append(members, goSymbol{
+				File: rel, Kind: kind, Name: n.Name, Receiver: sp.Name.Name,
+				Signature: sig, StartLine: start, EndLine: end,
+			})
+		}
+	}
+	return header, members
+}
+
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+func nodeString(fset *token.FileSet, n any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, n); err != nil {
+		return ""
+	}
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	if len(s) > maxGoSignatureBytes {
+		s = clipUTF8(s, maxGoSignatureBytes) + "…"
+	}
+	return s
+}
+
+// workspaceRel renders an absolute path relative to the Workspace root.
+func workspaceRel(path string) string {
+	if rel, err := filepath.Rel(WorkspaceRoot(), path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const goSymbolsFixture = `package shapes
+
+import "fmt"
+
+const Pi = 3.14
+
+var registry = map[string]Shape{}
+
+// Shape is anything with an area.
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64
+	fmt.Stringer
+}
+
+func (c *Circle) Area() float64 {
+	return Pi * c.Radius * c.Radius
+}
+
+func NewCircle(r float64) *Circle {
+	return &Circle{Radius: r}
+}
+`
+
+type goSymbolsResp struct {
+	Status string `json:"status"`
+	Data   struct {
+		Symbols     []goSymbol    `json:"symbols"`
+		Definitions []goSymbol    `json:"definitions"`
+		References  []goReference `json:"references"`
+	} `json:"data"`
+}
+
+func goSymbolsCall(t *testing.T, args map[string]any) goSymbolsResp {
+	t.Helper()
+	out := callTool(t, &GoSymbols{}, args)
+	var resp goSymbolsResp
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("unmarshal %q: %v", out, err)
+	}
+	if resp.Status != "SUCCESS" {
+		t.Fatalf("go_symbols(%v) = %s", args, out)
+	}
+	return resp
+}
+
+func setupGoSymbols(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	pkg := filepath.Join(dir, "shapes")
+	if err := os.MkdirAll(pkg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "shapes.go"), []byte(goSymbolsFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	use := "package main\n\nimport \"example/shapes\"\n\nfunc main() { _ = shapes.NewCircle(1).Area() }\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(use), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGoSymbols_outline(t *testing.T) {
+	setupGoSymbols(t)
+
+	resp := goSymbolsCall(t, map[string]any{"action": "outline", "path": "shapes"})
+	var got []string
+	for _, s := range resp.Data.Symbols {
+		got = append(got, s.Kind+" "+s.qualifiedName())
+	}
+	want := "const Pi|var registry|type Shape|type Circle|method Circle.Area|func NewCircle"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("outline = %v, want %s", got, want)
+	}
+
+	circle := resp.Data.Symbols[3]
+	if circle.Signature != "type Circle struct" || circle.StartLine != 14 || circle.EndLine != 17 {
+		t.Fatalf("Circle = %+v", circle)
+	}
+	if len(circle.Members) != 2 || circle.Members[0].Signature != "Radius float64" || circle.Members[1].Kind != "embedded" {
+		t.Fatalf("Circle members = %+v", circle.Members)
+	}
+	area := resp.Data.Symbols[4]
+	if area.Signature != "func (c *Circle) Area() float64" || area.File != "shapes/shapes.go" {
+		t.Fatalf("Area = %+v", area)
+	}
+}
+
+func TestGoSymbols_definition(t *testing.T) {
+	setupGoSymbols(t)
+
+	resp := goSymbolsCall(t, map[string]any{"action": "definition", "name": "Circle.Area"})
+	if len(resp.Data.Definitions) != 1 || resp.Data.Definitions[0].StartLine != 19 {
+		t.Fatalf("definitions = %+v", resp.Data.Definitions)
+	}
+	resp = goSymbolsCall(t, map[string]any{"action": "definition", "name": "Circle.Radius"})
+	if len(resp.Data.Definitions) != 1 || resp.Data.Definitions[0].Kind != "field" {
+		t.Fatalf("field definitions = %+v", resp.Data.Definitions)
+	}
+}
+
+func TestGoSymbols_references(t *testing.T) {
+	setupGoSymbols(t)
+
+	resp := goSymbolsCall(t, map[string]any{"action": "references", "name": "NewCircle"})
+	if len(resp.Data.References) != 2 {
+		t.Fatalf("references = %+v, want declaration and call", resp.Data.References)
+	}
+	if ref := resp.Data.References[0]; ref.File != "main.go" || ref.Line != 5 || !strings.Contains(ref.Text, "shapes.NewCircle(1)") {
+		t.Fatalf("first reference = %+v", ref)
+	}
+}
+
+func TestGoSymbols_rejectsEscape(t *testing.T) {
+	setupGoSymbols(t)
+
+	if out := callTool(t, &GoSymbols{}, map[string]any{"action": "outline", "path": "../x"}); !strings.Contains(out, "path escapes workspace") {
+		t.Fatalf("outline outside = %q", out)
+	}
+}
@@ -0,0 +1,486 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const (
+	maxGoDiagnostics    = 100
+	maxGoTestFailures   = 20
+	maxGoFailureLines   = 40
+	maxGoFailureBytes   = 4000
+	maxGoOutputBytes    = 8000
+	maxGoDocBytes       = 32 * 1024
+	maxGoListedPackages = 200
+)
+
+// GoTool runs the Go toolchain and condenses its output. test, vet and build
+// compile and may execute Workspace code, so they pass the Approval Gate;
+// doc and list do not.
+type GoTool struct{}
+
+func (g *GoTool) Name() string { return "go" }
+
+func (g *GoTool) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        g.Name(),
+			"description": "Run the Go toolchain in the workspace with compact, structured results. test reports pass/fail counts, failing tests with their output and build errors; vet and build report diagnostics with file and line; doc shows documentation for a package or symbol offline; list describes packages. Prefer this over run_shell for Go commands.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"subcommand": map[string]any{
+						"type": "string",
+						"enum": []string{"test", "vet", "build", "doc", "list"},
+					},
+					"packages": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Package patterns for test, vet, build and list. Defaults to [\"./...\"].",
+					},
+					"run": map[string]any{
+						"type":        "string",
+						"description": "For test: only run tests matching this regular expression (go test -run).",
+					},
+					"query": map[string]any{
+						"type":        "string",
+						"description": "For doc: what to document, e.g. \"net/http\", \"strings.Cut\" or \"./internal/tools WriteFile\".",
+					},
+					"all": map[string]any{
+						"type":        "boolean",
+						"description": "For doc: show all documentation for the package (go doc -all).",
+					},
+				},
+				"required": []string{"subcommand"},
+			},
+		},
+	}
+}
+
+func (g *GoTool) NeedsApproval(args inference.ToolCall) bool {
+	switch sub, _ := args.Function.Arguments["subcommand"].(string); sub {
+	case "doc", "list":
+		return false
+	default:
+		return true
+	}
+}
+
+// ReadOnly holds for doc and list; test, vet and build write to the build
+// cache and run Workspace code.
+func (g *GoTool) ReadOnly(args inference.ToolCall) bool { return !g.NeedsApproval(args) }
+
+func (g *GoTool) ApprovalSummary(args inference.ToolCall) string {
+	argv, err := goArgs(args)
+	if err != nil {
+		return "go " + fmt.Sprint(args.Function.Arguments["subcommand"])
+	}
+	return "go " + strings.Join(argv, " ")
+}
+
+func (g *GoTool) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	argv, err := goArgs(args)
+	if err != nil {
+		return Failure(err)
+	}
+	stdout, stderr, exitCode, err := runGo(ctx, argv...)
+	if err != nil {
+		return Failure(err)
+	}
+
+	switch argv[0] {
+	case "test":
+		r := Success(parseGoTest(stdout, stderr, exitCode)...)
+		return r.WithSummary("%d 通过，%d 失败，%d 跳过", r.Data["passed"], r.Data["failed"], r.Data["skipped"])
+	case "vet":
+		return goSummary(Success(parseGoVet(stdout, stderr, exitCode)...))
+	case "build":
+		diags, rest := parseGoDiagnostics(stderr)
+		return goSummary(Success(goResult(exitCode, "diagnostics", diags, rest)...))
+	case "doc":
+		if exitCode != 0 {
+			return Failure(errors.New(strings.TrimSpace(string(stderr))))
+		}
+		doc := string(stdout)
+		if len(doc) > maxGoDocBytes {
+			doc = clipUTF8(doc, maxGoDocBytes) + "\n… (truncated)"
+		}
+		return Success("doc", doc).WithSummary("%d 行", strings.Count(doc, "\n"))
+	default:
+		pkgs, err := parseGoList(stdout)
+		if err != nil || exitCode != 0 {
+			return Failure(fmt.Errorf("go list: %s", strings.TrimSpace(string(stderr))))
+		}
+		return Success("packages", pkgs).WithSummary("%d 个包", len(pkgs))
+	}
+}
+
+// goArgs builds the go command line for a call, refusing arguments that
+// would be parsed as flags.
+func goArgs(args inference.ToolCall) ([]string, error) {
+	a := args.Function.Arguments
+	sub, _ := a["subcommand"].(string)
+
+	var pkgs []string
+	if raw, ok := a["packages"].([]any); ok {
+		for _, p := range raw {
+			s, ok := p.(string)
+			if !ok || s == "" {
+				return nil, errors.New("packages must be non-empty strings")
+			}
+			pkgs = append(pkgs, s)
+		}
+	}
+	if len(pkgs) == 0 {
+		pkgs = []string{"./..."}
+	}
+	for _, p := range pkgs {
+		if strings.HasPrefix(p, "-") {
+			return nil, fmt.Errorf("invalid package pattern %q", p)
+		}
+		if err := checkGoPath(p); err != nil {
+			return nil, err
+		}
+	}
+
+	switch sub {
+	case "test":
+		argv := []string{"test", "-json"}
+		if run, _ := a["run"].(string); run != "" {
+			argv = append(argv, "-run", run)
+		}
+		return append(argv, pkgs...), nil
+	case "vet":
+		return append([]string{"vet", "-json"}, pkgs...), nil
+	case "build":
+		return append([]string{"build", "-o", os.DevNull}, pkgs...), nil
+	case "list":
+		return append([]string{"list", "-e", "-json=ImportPath,Name,Dir,Doc,GoFiles,TestGoFiles,XTestGoFiles,Imports,Error"}, pkgs...), nil
+	case "doc":
+		query := strings.Fields(fmt.Sprint(a["query"]))
+		if _, ok := a["query"].(string); !ok || len(query) == 0 {
+			return nil, errors.New("query is required for doc")
+		}
+		for _, q := range query {
+			if strings.HasPrefix(q, "-") {
+				return nil, fmt.Errorf("invalid query %q", q)
+			}
+			if err := checkGoPath(q); err != nil {
+				return nil, err
+			}
+		}
+		argv := []string{"doc"}
+		if all, _ := a["all"].(bool); all {
+			argv = append(argv, "-all")
+		}
+		return append(argv, query...), nil
+	default:
+		return nil, fmt.Errorf("unsupported subcommand %q", sub)
+	}
+}
+
+// checkGoPath refuses a directory pattern such as ../x or /abs/... that
+// points outside the Workspace. Import paths are left to the go command.
+func checkGoPath(p string) error {
+	if !filepath.IsAbs(p) && !strings.HasPrefix(p, ".") {
+		return nil
+	}
+	if _, err := ResolveWorkspacePath(strings.TrimSuffix(p, "/...")); err != nil {
+		return fmt.Errorf("package %q: %w", p, err)
+	}
+	return nil
+}
+
+func runGo(ctx context.Context, args ...string) (stdout, stderr []byte, exitCode int, err error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = WorkspaceRoot()
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	runErr := cmd.Run()
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return nil, nil, -1, runErr
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	return outBuf.Bytes(), errBuf.Bytes(), exitCode, nil
+}
+
+// goResult assembles the common result fields: ok, the structured items and
+// any output that could not be parsed.
+func goResult(exitCode int, key string, items any, rest string) []any {
+	kv := []any{"ok", exitCode == 0, key, items}
+	if rest != "" {
+		kv = append(kv, "output", tailBytes(rest, maxGoOutputBytes))
+	}
+	return kv
+}
+
+// goSummary describes a build or vet result by its diagnostics.
+func goSummary(r ToolResult) ToolResult {
+	if ok, _ := r.Data["ok"].(bool); ok {
+		return r.WithSummary("通过")
+	}
+	diags, _ := r.Data["diagnostics"].([]goDiagnostic)
+	return r.WithSummary("%d 条诊断", len(diags))
+}
+
+type goDiagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Check   string `json:"check,omitempty"`
+}
+
+var (
+	goDiagnosticLine = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+	goVetPosn        = regexp.MustCompile(`^(.*\.go):(\d+)(?::(\d+))?$`)
+)
+
+// parseGoDiagnostics extracts file:line:col: message lines from compiler
+// output. Package headers are dropped; other lines are returned as rest.
+func parseGoDiagnostics(out []byte) ([]goDiagnostic, string) {
+	diags := []goDiagnostic{}
+	var rest []string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "# ") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := goDiagnosticLine.FindStringSubmatch(line)
+		if m == nil {
+			rest = append(rest, line)
+			continue
+		}
+		if len(diags) < maxGoDiagnostics {
+			diags = append(diags, newGoDiagnostic(m[1], m[2], m[3], m[4]))
+		}
+	}
+	return diags, strings.Join(rest, "\n")
+}
+
+func newGoDiagnostic(file, line, col, msg string) goDiagnostic {
+	d := goDiagnostic{File: workspaceRel(absWorkspacePath(file)), Message: msg}
+	d.Line, _ = strconv.Atoi(line)
+	d.Column, _ = strconv.Atoi(col)
+	return d
+}
+
+func absWorkspacePath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(WorkspaceRoot(), file)
+}
+
+// parseGoVet reads the analyzer JSON go vet -json prints on stdout. Type
+// errors still arrive as plain text on stderr.
+func parseGoVet(stdout, stderr []byte, exitCode int) []any {
+	diags, rest := parseGoDiagnostics(stderr)
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var report map[string]map[string]json.RawMessage
+		if err := dec.Decode(&report); err != nil {
+			if !errors.Is(err, io.EOF) {
+				rest = strings.TrimSpace(rest + "\n" + string(stdout))
+			}
+			break
+		}
+		for _, analyzers := range report {
+			for check, raw := range analyzers {
+				var found []struct {
+					Posn    string `json:"posn"`
+					Message string `json:"message"`
+				}
+				if json.Unmarshal(raw, &found) != nil {
+					continue
+				}
+				for _, f := range found {
+					if len(diags) >= maxGoDiagnostics {
+						break
+					}
+					d := goDiagnostic{Message: f.Message, Check: check}
+					if m := goVetPosn.FindStringSubmatch(f.Posn); m != nil {
+						d = newGoDiagnostic(m[1], m[2], m[3], f.Message)
+						d.Check = check
+					}
+					diags = append(diags, d)
+				}
+			}
+		}
+	}
+	kv := goResult(exitCode, "diagnostics", diags, rest)
+	kv[1] = exitCode == 0 && len(diags) == 0
+	return kv
+}
+
+type goTestEvent struct {
+	ImportPath  string
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	FailedBuild string
+}
+
+type goTestFailure struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	Output  string `json:"output"`
+}
+
+// parseGoTest condenses the go test -json event stream into counts, the
+// failing tests with their output, and build errors.
+func parseGoTest(stdout, stderr []byte, exitCode int) []any {
+	var (
+		passed, failed, skipped int
+		failures                = []goTestFailure{}
+		outputs                 = make(map[string][]string)
+		failedTests             = make(map[string]bool)
+		buildOutput             bytes.Buffer
+		extra                   []string
+	)
+	sc := bufio.NewScanner(bytes.NewReader(stdout))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var ev goTestEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			extra = append(extra, sc.Text())
+			continue
+		}
+		key := ev.Package + "\x00" + ev.Test
+		switch ev.Action {
+		case "build-output":
+			buildOutput.WriteString(ev.Output)
+		case "output":
+			outputs[key] = append(outputs[key], ev.Output)
+		case "pass":
+			if ev.Test != "" {
+				passed++
+			}
+			delete(outputs, key)
+		case "skip":
+			if ev.Test != "" {
+				skipped++
+			}
+			delete(outputs, key)
+		case "fail":
+			if ev.Test != "" {
+				failed++
+				failedTests[ev.Package] = true
+			}
+			// A failed build is reported through build_errors, and a package
+			// failure only matters when none of its tests explain it, as
+			// with a panic in TestMain or a timeout.
+			report := ev.FailedBuild == "" && (ev.Test != "" || !failedTests[ev.Package])
+			if report && len(failures) < maxGoTestFailures {
+				failures = append(failures, goTestFailure{
+					Package: ev.Package,
+					Test:    ev.Test,
+					Output:  testFailureOutput(outputs[key]),
+				})
+			}
+			delete(outputs, key)
+		}
+	}
+
+	buildErrors, rest := parseGoDiagnostics(buildOutput.Bytes())
+	if s := strings.TrimSpace(string(stderr)); s != "" {
+		rest = strings.TrimSpace(rest + "\n" + s)
+	}
+	if len(extra) > 0 {
+		rest = strings.TrimSpace(rest + "\n" + strings.Join(extra, "\n"))
+	}
+	kv := []any{"ok", exitCode == 0, "passed", passed, "failed", failed, "skipped", skipped,
+		"failures", failures, "build_errors", buildErrors}
+	if rest != "" && exitCode != 0 {
+		kv = append(kv, "output", tailBytes(rest, maxGoOutputBytes))
+	}
+	return kv
+}
+
+// testFailureOutput keeps the tail of a failing test's output without the
+// === RUN style framing lines.
+func testFailureOutput(chunks []string) string {
+	var lines []string
+	for _, l := range strings.Split(strings.Join(chunks, ""), "\n") {
+		if strings.HasPrefix(l, "=== ") || strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) > maxGoFailureLines {
+		lines = append([]string{fmt.Sprintf("… (%d earlier lines)", len(lines)-maxGoFailureLines)}, lines[len(lines)-maxGoFailureLines:]...)
+	}
+	return tailBytes(strings.Join(lines, "\n"), maxGoFailureBytes)
+}
+
+func tailBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return "…" + strings.ToValidUTF8(s[len(s)-max:], "")
+}
+
+type goPackage struct {
+	ImportPath   string   `json:"import_path"`
+	Name         string   `json:"name,omitempty"`
+	Dir          string   `json:"dir,omitempty"`
+	Doc          string   `json:"doc,omitempty"`
+	GoFiles      []string `json:"go_files,omitempty"`
+	TestGoFiles  []string `json:"test_go_files,omitempty"`
+	XTestGoFiles []string `json:"xtest_go_files,omitempty"`
+	Imports      []string `json:"imports,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+func parseGoList(stdout []byte) ([]goPackage, error) {
+	pkgs := []goPackage{}
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for len(pkgs) < maxGoListedPackages {
+		var p struct {
+			ImportPath, Name, Dir, Doc         string
+			GoFiles, TestGoFiles, XTestGoFiles []string
+			Imports                            []string
+			Error                              *struct{ Err string }
+		}
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		pkg := goPackage{
+			ImportPath: p.ImportPath, Name: p.Name, Doc: p.Doc,
+			GoFiles: p.GoFiles, TestGoFiles: p.TestGoFiles, XTestGoFiles: p.XTestGoFiles,
+			Imports: p.Imports,
+		}
+		if p.Dir != "" {
+			pkg.Dir = workspaceRel(p.Dir)
+		}
+		if p.Error != nil {
+			pkg.Error = p.Error.Err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
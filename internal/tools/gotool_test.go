@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const goTestJSONFixture = `{"Action":"start","Package":"example/a"}
+{"Action":"run","Package":"example/a","Test":"TestOK"}
+{"Action":"output","Package":"example/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"pass","Package":"example/a","Test":"TestOK"}
+{"Action":"run","Package":"example/a","Test":"TestBad"}
+{"Action":"output","Package":"example/a","Test":"TestBad","Output":"=== RUN   TestBad\n"}
+{"Action":"output","Package":"example/a","Test":"TestBad","Output":"    a_test.go:9: got 1, want 2\n"}
+{"Action":"output","Package":"example/a","Test":"TestBad","Output":"--- FAIL: TestBad (0.00s)\n"}
+{"Action":"fail","Package":"example/a","Test":"TestBad"}
+{"Action":"run","Package":"example/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example/a","Test":"TestSkip"}
+{"Action":"fail","Package":"example/a"}
+{"ImportPath":"example/b","Action":"build-output","Output":"# example/b\n"}
+{"ImportPath":"example/b","Action":"build-output","Output":"b/b.go:3:9: undefined: missing\n"}
+{"ImportPath":"example/b","Action":"build-fail"}
+{"Action":"fail","Package":"example/b","FailedBuild":"example/b"}
+`
+
+type goToolResp struct {
+	Status string `json:"status"`
+	Data   struct {
+		OK          bool            `json:"ok"`
+		Passed      int             `json:"passed"`
+		Failed      int             `json:"failed"`
+		Skipped     int             `json:"skipped"`
+		Failures    []goTestFailure `json:"failures"`
+		BuildErrors []goDiagnostic  `json:"build_errors"`
+		Diagnostics []goDiagnostic  `json:"diagnostics"`
+		Doc         string          `json:"doc"`
+		Packages    []goPackage     `json:"packages"`
+		Output      string          `json:"output"`
+	} `json:"data"`
+}
+
+func decodeGoTool(t *testing.T, kv []any) goToolResp {
+	t.Helper()
+	content := Success(kv...).ModelText()
+	var resp goToolResp
+	if err := json.Unmarshal([]byte(content), &resp); err != nil {
+		t.Fatalf("unmarshal %q: %v", content, err)
+	}
+	return resp
+}
+
+func TestParseGoTest_failuresAndBuildErrors(t *testing.T) {
+	chdirWorkspace(t, t.TempDir())
+	resp := decodeGoTool(t, parseGoTest([]byte(goTestJSONFixture), nil, 1))
+
+	if resp.Data.OK || resp.Data.Passed != 1 || resp.Data.Failed != 1 || resp.Data.Skipped != 1 {
+		t.Fatalf("counts = %+v", resp.Data)
+	}
+	// Neither the package-level failure of example/a nor the failed build
+	// of example/b is reported as a test failure.
+	if len(resp.Data.Failures) != 1 {
+		t.Fatalf("failures = %+v", resp.Data.Failures)
+	}
+	bad := resp.Data.Failures[0]
+	if bad.Test != "TestBad" || !strings.Contains(bad.Output, "got 1, want 2") || strings.Contains(bad.Output, "=== RUN") {
+		t.Fatalf("failure = %+v", bad)
+	}
+	if len(resp.Data.BuildErrors) != 1 {
+		t.Fatalf("build errors = %+v", resp.Data.BuildErrors)
+	}
+	be := resp.Data.BuildErrors[0]
+	if be.File != "b/b.go" || be.Line != 3 || be.Column != 9 || be.Message != "undefined: missing" {
+		t.Fatalf("build error = %+v", be)
+	}
+}
+
+func TestParseGoVet_jsonAndTypeErrors(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	stdout := `{"example/a":{"printf":[{"posn":"` + filepath.Join(dir, "a", "a.go") + `:7:2","end":"","message":"fmt.Printf format %d has arg s of wrong type string"}]}}`
+	stderr := "# example/b\nvet: b/b.go:4:2: undefined: nope\n"
+
+	resp := decodeGoTool(t, parseGoVet([]byte(stdout), []byte(stderr), 1))
+	if resp.Data.OK || len(resp.Data.Diagnostics) != 2 {
+		t.Fatalf("vet = %+v", resp.Data)
+	}
+	typeErr, printf := resp.Data.Diagnostics[0], resp.Data.Diagnostics[1]
+	if typeErr.File != "b/b.go" || typeErr.Line != 4 || typeErr.Message != "undefined: nope" {
+		t.Fatalf("type error = %+v", typeErr)
+	}
+	if printf.File != "a/a.go" || printf.Line != 7 || printf.Column != 2 || printf.Check != "printf" {
+		t.Fatalf("printf = %+v", printf)
+	}
+}
+
+func TestGoArgs_rejectsFlags(t *testing.T) {
+	cases := []map[string]any{
+		{"subcommand": "test", "packages": []any{"-exec=evil"}},
+		{"subcommand": "doc", "query": "-u fmt"},
+		{"subcommand": "doc"},
+		{"subcommand": "run"},
+	}
+	for _, args := range cases {
+		if _, err := goArgs(inference.ToolCall{Function: inference.Function{Arguments: args}}); err == nil {
+			t.Errorf("goArgs(%v) succeeded", args)
+		}
+	}
+}
+
+func TestGoArgs_restrictsPathsToWorkspace(t *testing.T) {
+	SetWorkspaceRootForTest(t.TempDir())
+	outside := filepath.Dir(WorkspaceRoot())
+	for _, args := range []map[string]any{
+		{"subcommand": "list", "packages": []any{"../../.."}},
+		{"subcommand": "list", "packages": []any{outside + "/..."}},
+		{"subcommand": "doc", "query": "../other Foo"},
+		{"subcommand": "doc", "query": outside},
+	} {
+		if _, err := goArgs(inference.ToolCall{Function: inference.Function{Arguments: args}}); err == nil {
+			t.Errorf("goArgs(%v) succeeded", args)
+		}
+	}
+	for _, args := range []map[string]any{
+		{"subcommand": "list", "packages": []any{"./...", "net/http", "example.com/mod/..."}},
+		{"subcommand": "list", "packages": []any{filepath.Join(WorkspaceRoot(), "internal") + "/..."}},
+		{"subcommand": "doc", "query": "./internal/tools WriteFile"},
+		{"subcommand": "doc", "query": "strings.Cut"},
+	} {
+		if _, err := goArgs(inference.ToolCall{Function: inference.Function{Arguments: args}}); err != nil {
+			t.Errorf("goArgs(%v): %v", args, err)
+		}
+	}
+}
+
+func TestGoTool_needsApproval(t *testing.T) {
+	tool := &GoTool{}
+	for sub, want := range map[string]bool{"test": true, "vet": true, "build": true, "doc": false, "list": false} {
+		call := inference.ToolCall{Function: inference.Function{Arguments: map[string]any{"subcommand": sub}}}
+		if got := NeedsApproval(tool, call); got != want {
+			t.Errorf("NeedsApproval(%s) = %v, want %v", sub, got, want)
+		}
+	}
+	call := inference.ToolCall{Function: inference.Function{Arguments: map[string]any{"subcommand": "test", "run": "TestX"}}}
+	if got := tool.ApprovalSummary(call); got != "go test -json -run TestX ./..." {
+		t.Errorf("summary = %q", got)
+	}
+}
+
+func TestGoTool_runsToolchain(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	t.Setenv("GOFLAGS", "-mod=mod")
+	files := map[string]string{
+		"go.mod":       "module example\n\ngo 1.21\n",
+		"calc.go":      "// Package calc adds.\npackage calc\n\n// Add returns a+b.\nfunc Add(a, b int) int { return a - b }\n",
+		"calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"Add(1, 2) != 3\")\n\t}\n}\n",
+		"broken/b.go":  "package broken\n\nfunc F() int { return nope }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	decode := func(args map[string]any) goToolResp {
+		var resp goToolResp
+		out := callTool(t, &GoTool{}, args)
+		if err := json.Unmarshal([]byte(out), &resp); err != nil || resp.Status != "SUCCESS" {
+			t.Fatalf("go %v = %s", args, out)
+		}
+		return resp
+	}
+
+	test := decode(map[string]any{"subcommand": "test", "packages": []any{"./..."}})
+	if test.Data.OK || test.Data.Failed != 1 || len(test.Data.Failures) != 1 || !strings.Contains(test.Data.Failures[0].Output, "Add(1, 2) != 3") {
+		t.Fatalf("test = %+v", test.Data)
+	}
+	if len(test.Data.BuildErrors) != 1 || test.Data.BuildErrors[0].File != "broken/b.go" {
+		t.Fatalf("build errors = %+v", test.Data.BuildErrors)
+	}
+
+	build := decode(map[string]any{"subcommand": "build", "packages": []any{"./broken"}})
+	if build.Data.OK || len(build.Data.Diagnostics) != 1 || build.Data.Diagnostics[0].Line != 3 {
+		t.Fatalf("build = %+v", build.Data)
+	}
+
+	doc := decode(map[string]any{"subcommand": "doc", "query": ". Add"})
+	if !strings.Contains(doc.Data.Doc, "Add returns a+b.") {
+		t.Fatalf("doc = %q", doc.Data.Doc)
+	}
+
+	list := decode(map[string]any{"subcommand": "list"})
+	if len(list.Data.Packages) != 2 || list.Data.Packages[0].Dir != "." || list.Data.Packages[1].Dir != "broken" {
+		t.Fatalf("list = %+v", list.Data.Packages)
+	}
+}
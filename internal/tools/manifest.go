@@ -0,0 +1,361 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+// ManifestDir holds the tool manifests, relative to the Workspace root.
+const ManifestDir = ".mini-agent/tools"
+
+const (
+	defaultManifestTimeout  = time.Minute
+	maxManifestOutputBytes  = 32 * 1024
+	maxManifestOutputLines  = 1000
+	maxManifestStderrBytes  = 4000
+	maxManifestSummaryBytes = 300
+)
+
+// Output formats of a Manifest.
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputLines = "lines"
+)
+
+var (
+	manifestName        = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	manifestPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+)
+
+// Manifest declares a tool that runs a command, so teams can add tools
+// without changing the Built-in Tools.
+type Manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is the JSON Schema of the arguments; it must describe an
+	// object.
+	Parameters map[string]any `json:"parameters,omitempty"`
+	// Command is run without a shell in the Workspace root. An element that
+	// is exactly "{{param}}" becomes the argument, one element per item of an
+	// array, and is dropped when the argument is missing. Placeholders inside
+	// other elements are replaced by the argument's text.
+	Command        []string `json:"command"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	// Approval defaults to true: every call passes the Approval Gate.
+	Approval *bool `json:"approval,omitempty"`
+	// Output is how stdout is returned: OutputText (the default),
+	// OutputJSON or OutputLines.
+	Output string `json:"output,omitempty"`
+	// OptionArgs lets arguments that start with "-" begin an element of
+	// Command. By default they are refused, so the model cannot pass
+	// options to the program.
+	OptionArgs bool `json:"option_args,omitempty"`
+}
+
+// ManifestTool is a Tool declared by a Manifest.
+type ManifestTool struct {
+	m    Manifest
+	path string
+}
+
+// LoadManifests reads the *.json manifests in dir, in name order. A
+// manifest that cannot be used is reported in errs and skipped; a missing
+// dir is not an error.
+func LoadManifests(dir string) (tools []*ManifestTool, errs []error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	sort.Strings(paths)
+	seen := make(map[string]string)
+	for _, path := range paths {
+		t, err := loadManifest(path)
+		if err == nil && seen[t.Name()] != "" {
+			err = fmt.Errorf("tool %q is already defined in %s", t.Name(), seen[t.Name()])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		seen[t.Name()] = path
+		tools = append(tools, t)
+	}
+	return tools, errs
+}
+
+func loadManifest(path string) (*ManifestTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if m.Parameters == nil {
+		m.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return &ManifestTool{m: m, path: path}, nil
+}
+
+func (m Manifest) validate() error {
+	switch {
+	case !manifestName.MatchString(m.Name):
+		return errors.New("name must be 1 to 64 letters, digits, '_' or '-'")
+	case strings.TrimSpace(m.Description) == "":
+		return errors.New("description is required")
+	case len(m.Command) == 0 || m.Command[0] == "":
+		return errors.New("command is required")
+	case manifestPlaceholder.MatchString(m.Command[0]):
+		return errors.New("the program in command must not be templated")
+	case m.TimeoutSeconds < 0:
+		return errors.New("timeout_seconds must not be negative")
+	}
+	switch m.Output {
+	case "", OutputText, OutputJSON, OutputLines:
+	default:
+		return fmt.Errorf("output must be %q, %q or %q, got %q", OutputText, OutputJSON, OutputLines, m.Output)
+	}
+	if m.Parameters == nil {
+		if len(m.placeholders()) > 0 {
+			return errors.New("command uses placeholders but parameters is missing")
+		}
+		return nil
+	}
+	if typ, _ := m.Parameters["type"].(string); typ != "object" {
+		return errors.New(`parameters must have "type": "object"`)
+	}
+	props, _ := m.Parameters["properties"].(map[string]any)
+	for _, name := range m.placeholders() {
+		if _, ok := props[name]; !ok {
+			return fmt.Errorf("command uses {{%s}}, which is not a parameter", name)
+		}
+	}
+	return nil
+}
+
+func (m Manifest) placeholders() []string {
+	var names []string
+	for _, arg := range m.Command {
+		for _, match := range manifestPlaceholder.FindAllStringSubmatch(arg, -1) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+func (t *ManifestTool) Name() string { return t.m.Name }
+
+// Path is the manifest file the tool was loaded from.
+func (t *ManifestTool) Path() string { return t.path }
+
+func (t *ManifestTool) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        t.m.Name,
+			"description": t.m.Description,
+			"parameters":  t.m.Parameters,
+		},
+	}
+}
+
+func (t *ManifestTool) NeedsApproval(inference.ToolCall) bool {
+	return t.m.Approval == nil || *t.m.Approval
+}
+
+// Launch describes the command of a manifest that skips the Approval Gate,
+// for the Workspace trust prompt. It is empty for a gated manifest.
+func (t *ManifestTool) Launch() string {
+	if t.NeedsApproval(inference.ToolCall{}) {
+		return ""
+	}
+	return fmt.Sprintf("tools.%s: %s", t.m.Name, strings.Join(t.m.Command, " "))
+}
+
+// RequireApproval puts every call through the Approval Gate, whatever the
+// manifest says.
+func (t *ManifestTool) RequireApproval() { t.m.Approval = nil }
+
+func (t *ManifestTool) ApprovalSummary(args inference.ToolCall) string {
+	argv, err := t.argv(args.Function.Arguments)
+	if err != nil {
+		return t.m.Name + ": " + err.Error()
+	}
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = a
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$") {
+			quoted[i] = strconv.Quote(a)
+		}
+	}
+	summary := strings.Join(quoted, " ")
+	if len(summary) > maxManifestSummaryBytes {
+		summary = clipUTF8(summary, maxManifestSummaryBytes) + "…"
+	}
+	return summary
+}
+
+func (t *ManifestTool) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	argv, err := t.argv(args.Function.Arguments)
+	if err != nil {
+		return Failure(err)
+	}
+	timeout := defaultManifestTimeout
+	if t.m.TimeoutSeconds > 0 {
+		timeout = time.Duration(t.m.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = WorkspaceRoot()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return Failure(fmt.Errorf("%s timed out after %s", t.m.Name, timeout))
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return Failure(runErr)
+		}
+		detail := strings.TrimSpace(stderr.String())
+		if detail == "" {
+			detail = strings.TrimSpace(stdout.String())
+		}
+		return Failure(fmt.Errorf("exit status %d: %s", exitErr.ExitCode(), tailBytes(detail, maxManifestStderrBytes)))
+	}
+
+	kv, err := t.parseOutput(stdout.String())
+	if err != nil {
+		return Failure(err)
+	}
+	if s := strings.TrimSpace(stderr.String()); s != "" {
+		kv = append(kv, "stderr", tailBytes(s, maxManifestStderrBytes))
+	}
+	return Success(kv...).WithSummary("%s 完成", t.m.Name)
+}
+
+func (t *ManifestTool) parseOutput(out string) ([]any, error) {
+	switch t.m.Output {
+	case OutputJSON:
+		if len(out) > maxManifestOutputBytes {
+			return nil, fmt.Errorf("JSON output of %d bytes exceeds the limit of %d", len(out), maxManifestOutputBytes)
+		}
+		var v any
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			return nil, fmt.Errorf("output is not valid JSON: %w", err)
+		}
+		return []any{"output", v}, nil
+	case OutputLines:
+		var lines []string
+		for line := range strings.SplitSeq(out, "\n") {
+			if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		kv := []any{"total", len(lines)}
+		if len(lines) > maxManifestOutputLines {
+			lines = lines[:maxManifestOutputLines]
+			kv = append(kv, "truncated", true)
+		}
+		return append(kv, "output", lines), nil
+	default:
+		if len(out) > maxManifestOutputBytes {
+			return []any{"output", clipUTF8(out, maxManifestOutputBytes), "truncated", true}, nil
+		}
+		return []any{"output", out}, nil
+	}
+}
+
+// argv renders the command for the call's arguments.
+func (t *ManifestTool) argv(args map[string]any) ([]string, error) {
+	if required, ok := t.m.Parameters["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := args[name]; !ok {
+				return nil, fmt.Errorf("missing required argument %q", name)
+			}
+		}
+	}
+	argv := make([]string, 0, len(t.m.Command))
+	for _, elem := range t.m.Command {
+		if m := manifestPlaceholder.FindStringSubmatch(elem); m != nil && m[0] == elem {
+			var values []any
+			switch v := args[m[1]].(type) {
+			case nil:
+			case []any:
+				values = v
+			default:
+				values = []any{v}
+			}
+			for _, v := range values {
+				arg := manifestArgText(v)
+				if err := t.checkOption(m[1], elem, arg); err != nil {
+					return nil, err
+				}
+				argv = append(argv, arg)
+			}
+			continue
+		}
+		arg := manifestPlaceholder.ReplaceAllStringFunc(elem, func(p string) string {
+			name := manifestPlaceholder.FindStringSubmatch(p)[1]
+			if v, ok := args[name]; ok && v != nil {
+				return manifestArgText(v)
+			}
+			return ""
+		})
+		if m := manifestPlaceholder.FindStringSubmatchIndex(elem); m != nil && m[0] == 0 {
+			if err := t.checkOption(elem[m[2]:m[3]], elem, arg); err != nil {
+				return nil, err
+			}
+		}
+		argv = append(argv, arg)
+	}
+	return argv, nil
+}
+
+// checkOption refuses arg, rendered from elem, when the argument param
+// turned it into an option of the program.
+func (t *ManifestTool) checkOption(param, elem, arg string) error {
+	if t.m.OptionArgs || strings.HasPrefix(elem, "-") || !strings.HasPrefix(arg, "-") {
+		return nil
+	}
+	return fmt.Errorf("argument %q must not start with \"-\"", param)
+}
+
+func manifestArgText(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func writeManifest(t *testing.T, dir, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type manifestResp struct {
+	Status string         `json:"status"`
+	Data   map[string]any `json:"data"`
+}
+
+func callManifest(t *testing.T, tool Tool, args map[string]any) manifestResp {
+	t.Helper()
+	var resp manifestResp
+	if err := json.Unmarshal([]byte(callTool(t, tool, args)), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// printArgs prints each argument after the program on its own line.
+const printArgs = `"sh", "-c", "printf '%s\\n' \"$@\"", "sh"`
+
+func TestLoadManifests_validates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ManifestDir)
+	writeManifest(t, dir, "a.json", `{"name": "ok", "description": "Fine.", "command": ["true"]}`)
+	writeManifest(t, dir, "b.json", `{"name": "ok", "description": "Same name.", "command": ["true"]}`)
+	writeManifest(t, dir, "c.json", `{"name": "typo", "description": "Unknown field.", "comand": ["true"]}`)
+	writeManifest(t, dir, "d.json", `{"name": "undeclared", "description": "x", "command": ["echo", "{{path}}"],
+		"parameters": {"type": "object", "properties": {}}}`)
+	writeManifest(t, dir, "e.json", `{"name": "bad output", "description": "x", "command": ["true"]}`)
+	writeManifest(t, dir, "f.json", `{"name": "fmt", "description": "x", "command": ["true"], "output": "xml"}`)
+	writeManifest(t, dir, "notes.txt", `not a manifest`)
+
+	tools, errs := LoadManifests(dir)
+	if len(tools) != 1 || tools[0].Name() != "ok" || tools[0].Path() != filepath.Join(dir, "a.json") {
+		t.Fatalf("tools = %v", tools)
+	}
+	want := []string{"already defined", "unknown field", "{{path}}", "name must be", "output must be"}
+	if len(errs) != len(want) {
+		t.Fatalf("errors = %v", errs)
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("errors[%d] = %v, want it to mention %q", i, err, want[i])
+		}
+	}
+
+	if tools, errs := LoadManifests(filepath.Join(dir, "missing")); len(tools) != 0 || len(errs) != 0 {
+		t.Fatalf("missing dir = %v, %v", tools, errs)
+	}
+}
+
+func TestManifestTool_ungatedNeedsTrust(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ManifestDir)
+	writeManifest(t, dir, "a.json", `{"name": "gated", "description": "x", "command": ["true"]}`)
+	writeManifest(t, dir, "b.json", `{"name": "quick", "description": "x", "command": ["make", "lint"], "approval": false}`)
+	tools, errs := LoadManifests(dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	gated, quick := tools[0], tools[1]
+	if gated.Launch() != "" || quick.Launch() != "tools.quick: make lint" {
+		t.Fatalf("launches = %q, %q", gated.Launch(), quick.Launch())
+	}
+	quick.RequireApproval()
+	if !NeedsApproval(quick, inference.ToolCall{}) || quick.Launch() != "" {
+		t.Fatal("untrusted manifest still skips the Approval Gate")
+	}
+}
+
+func TestManifestTool_templatesArguments(t *testing.T) {
+	root := t.TempDir()
+	SetWorkspaceRootForTest(root)
+	dir := filepath.Join(root, ManifestDir)
+	writeManifest(t, dir, "args.json", `{
+		"name": "args",
+		"description": "Print arguments.",
+		"parameters": {
+			"type": "object",
+			"properties": {
+				"words": {"type": "array", "items": {"type": "string"}},
+				"count": {"type": "integer"},
+				"flag": {"type": "string"},
+				"label": {"type": "string"}
+			},
+			"required": ["count"]
+		},
+		"command": [`+printArgs+`, "{{words}}", "-n={{ count }}", "{{flag}}", "label:{{label}}"],
+		"output": "lines",
+		"approval": false
+	}`)
+	tools, errs := LoadManifests(dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	tool := tools[0]
+	if NeedsApproval(tool, inference.ToolCall{}) {
+		t.Fatal("approval: false still needs approval")
+	}
+
+	resp := callManifest(t, tool, map[string]any{"words": []any{"a b", "$HOME"}, "count": 3.0})
+	lines, _ := resp.Data["output"].([]any)
+	got := make([]string, len(lines))
+	for i, l := range lines {
+		got[i] = l.(string)
+	}
+	// The missing flag is dropped; the missing label leaves its prefix.
+	if want := "a b|$HOME|-n=3|label:"; resp.Status != "SUCCESS" || strings.Join(got, "|") != want {
+		t.Fatalf("output = %+v, want %s", resp, want)
+	}
+
+	if resp := callManifest(t, tool, map[string]any{}); resp.Status != "FAILED" || !strings.Contains(resp.Data["error"].(string), `"count"`) {
+		t.Fatalf("missing required argument = %+v", resp)
+	}
+}
+
+func TestManifestTool_refusesOptionArguments(t *testing.T) {
+	root := t.TempDir()
+	SetWorkspaceRootForTest(root)
+	dir := filepath.Join(root, ManifestDir)
+	for name, optionArgs := range map[string]bool{"strict": false, "options": true} {
+		writeManifest(t, dir, name+".json", `{
+			"name": "`+name+`",
+			"description": "Print arguments.",
+			"parameters": {"type": "object", "properties": {
+				"words": {"type": "array", "items": {"type": "string"}},
+				"file": {"type": "string"},
+				"label": {"type": "string"}
+			}},
+			"command": [`+printArgs+`, "{{words}}", "{{file}}.txt", "--label={{label}}"],
+			"output": "lines",
+			"approval": false,
+			"option_args": `+strconv.FormatBool(optionArgs)+`
+		}`)
+	}
+	tools, errs := LoadManifests(dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	options, strict := tools[0], tools[1]
+
+	for _, args := range []map[string]any{
+		{"words": []any{"ok", "--output=/etc/passwd"}},
+		{"file": "-exec"},
+	} {
+		if resp := callManifest(t, strict, args); resp.Status != "FAILED" || !strings.Contains(resp.Data["error"].(string), `must not start with "-"`) {
+			t.Fatalf("%v = %+v", args, resp)
+		}
+		if resp := callManifest(t, options, args); resp.Status != "SUCCESS" {
+			t.Fatalf("with option_args, %v = %+v", args, resp)
+		}
+	}
+	// The element already is an option; its value may start with "-".
+	if resp := callManifest(t, strict, map[string]any{"label": "-x"}); resp.Status != "SUCCESS" {
+		t.Fatalf("label = %+v", resp)
+	}
+}
+
+func TestManifestTool_outputAndFailures(t *testing.T) {
+	root := t.TempDir()
+	SetWorkspaceRootForTest(root)
+	dir := filepath.Join(root, ManifestDir)
+	writeManifest(t, dir, "json.json", `{"name": "json", "description": "x", "output": "json",
+		"command": ["sh", "-c", "echo '{\"files\": 2}'; echo warning >&2"]}`)
+	writeManifest(t, dir, "notjson.json", `{"name": "notjson", "description": "x", "output": "json", "command": ["echo", "plain"]}`)
+	writeManifest(t, dir, "text.json", `{"name": "text", "description": "x", "command": ["pwd"]}`)
+	writeManifest(t, dir, "fails.json", `{"name": "fails", "description": "x", "command": ["sh", "-c", "echo broken >&2; exit 3"]}`)
+	writeManifest(t, dir, "slow.json", `{"name": "slow", "description": "x", "command": ["sleep", "5"], "timeout_seconds": 1}`)
+	loaded, errs := LoadManifests(dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	byName := make(map[string]*ManifestTool)
+	for _, tool := range loaded {
+		byName[tool.Name()] = tool
+	}
+
+	resp := callManifest(t, byName["json"], nil)
+	if out, _ := resp.Data["output"].(map[string]any); out["files"] != 2.0 || resp.Data["stderr"] != "warning" {
+		t.Fatalf("json = %+v", resp)
+	}
+	if resp := callManifest(t, byName["notjson"], nil); resp.Status != "FAILED" || !strings.Contains(resp.Data["error"].(string), "not valid JSON") {
+		t.Fatalf("notjson = %+v", resp)
+	}
+	if resp := callManifest(t, byName["text"], nil); resp.Data["output"] != root+"\n" {
+		t.Fatalf("text = %+v, want the Workspace root", resp)
+	}
+	if resp := callManifest(t, byName["fails"], nil); resp.Status != "FAILED" || resp.Data["error"] != "exit status 3: broken" {
+		t.Fatalf("fails = %+v", resp)
+	}
+	if resp := callManifest(t, byName["slow"], nil); resp.Status != "FAILED" || !strings.Contains(resp.Data["error"].(string), "timed out") {
+		t.Fatalf("slow = %+v", resp)
+	}
+
+	// Manifests need approval unless they opt out.
+	summary := byName["fails"].ApprovalSummary(inference.ToolCall{})
+	if !NeedsApproval(byName["fails"], inference.ToolCall{}) || summary != `sh -c "echo broken >&2; exit 3"` {
+		t.Fatalf("fails: needs approval %v, summary %q", NeedsApproval(byName["fails"], inference.ToolCall{}), summary)
+	}
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// MutationObserver is told which paths a File Mutation changed, created or
+// removed; paths may be directories. Any text it returns, such as new
+// diagnostics, is attached to the tool result.
+type MutationObserver interface {
+	FilesChanged(ctx context.Context, paths []string) string
+}
+
+// mutations counts File Mutations in this process.
+var mutations atomic.Uint64
+
+// MutationCount increases with every File Mutation; callers compare two
+// readings to learn whether files changed in between.
+func MutationCount() uint64 { return mutations.Load() }
+
+var observers struct {
+	mu   sync.Mutex
+	list []MutationObserver
+}
+
+func AddMutationObserver(o MutationObserver) {
+	observers.mu.Lock()
+	defer observers.mu.Unlock()
+	observers.list = append(observers.list, o)
+}
+
+// ResetMutationObservers removes every registered observer.
+func ResetMutationObservers() {
+	observers.mu.Lock()
+	defer observers.mu.Unlock()
+	observers.list = nil
+}
+
+// notifyMutation reports paths to the observers and returns result kv with
+// their combined output added under "diagnostics".
+func notifyMutation(ctx context.Context, kv []any, paths ...string) []any {
+	mutations.Add(1)
+	observers.mu.Lock()
+	list := append([]MutationObserver(nil), observers.list...)
+	observers.mu.Unlock()
+
+	var notes []string
+	for _, o := range list {
+		if text := o.FilesChanged(ctx, paths); text != "" {
+			notes = append(notes, text)
+		}
+	}
+	switch len(notes) {
+	case 0:
+		return kv
+	case 1:
+		return append(kv, "diagnostics", notes[0])
+	default:
+		return append(kv, "diagnostics", notes)
+	}
+}
+
+// FileEdit replaces the whole content of a Workspace file.
+type FileEdit struct {
+	Path    string
+	Content []byte
+}
+
+// ApplyEdits writes edits produced outside the model's own tool calls (for
+// example a language server rename) through the shared writer. Every file is
+// checked against the WritePolicy and for external changes before any is
+// written. Observers are not notified; the caller knows what changed.
+func ApplyEdits(edits []FileEdit) error {
+	for _, e := range edits {
+		if _, err := validateWithinWorkspace(e.Path); err != nil {
+			return fmt.Errorf("%s: %w", e.Path, err)
+		}
+		if err := checkWrite(e.Path); err != nil {
+			return fmt.Errorf("%s: %w", workspaceRel(e.Path), err)
+		}
+	}
+	mutations.Add(1)
+	for _, e := range edits {
+		if _, err := writeFileWith(e.Path, e.Content, writeOptions{Verbatim: true}); err != nil {
+			return fmt.Errorf("%s: %w", workspaceRel(e.Path), err)
+		}
+	}
+	return nil
+}
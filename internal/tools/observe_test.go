@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recordingObserver struct {
+	paths [][]string
+	reply string
+}
+
+func (r *recordingObserver) FilesChanged(ctx context.Context, paths []string) string {
+	r.paths = append(r.paths, paths)
+	return r.reply
+}
+
+func withObserver(t *testing.T, reply string) *recordingObserver {
+	t.Helper()
+	o := &recordingObserver{reply: reply}
+	AddMutationObserver(o)
+	t.Cleanup(ResetMutationObservers)
+	return o
+}
+
+func TestMutationObserver_seesFileMutations(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+	o := withObserver(t, "a.go:1:1: error: boom")
+
+	out := writeFileCall(t, "a.go", "package a\n")
+	if !strings.Contains(out, "a.go:1:1: error: boom") {
+		t.Fatalf("write = %q, want observer output attached", out)
+	}
+	callTool(t, &MovePath{}, map[string]any{"source": "a.go", "destination": "b.go"})
+	callTool(t, &DeletePath{}, map[string]any{"path": "b.go"})
+
+	want := [][]string{
+		{filepath.Join(dir, "a.go")},
+		{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")},
+		{filepath.Join(dir, "b.go")},
+	}
+	if len(o.paths) != len(want) {
+		t.Fatalf("observed %v, want %v", o.paths, want)
+	}
+	for i := range want {
+		if strings.Join(o.paths[i], ",") != strings.Join(want[i], ",") {
+			t.Fatalf("call %d observed %v, want %v", i, o.paths[i], want[i])
+		}
+	}
+}
+
+func TestMutationObserver_silentObserverAddsNothing(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+	withObserver(t, "")
+
+	if out := writeFileCall(t, "a.txt", "a"); strings.Contains(out, "diagnostics") {
+		t.Fatalf("write = %q, want no diagnostics key", out)
+	}
+}
+
+func TestApplyEdits_checksEveryFileFirst(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFileCall(t, "a.txt", "a")
+	writeFileCall(t, "b.txt", "b")
+	if err := os.WriteFile(b, []byte("changed elsewhere"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ApplyEdits([]FileEdit{{Path: a, Content: []byte("A")}, {Path: b, Content: []byte("B")}})
+	if !errors.Is(err, ErrStaleFile) {
+		t.Fatalf("err = %v, want ErrStaleFile", err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "a" {
+		t.Fatalf("a.txt = %q, want untouched", got)
+	}
+}
@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+var errWorkspaceRoot = errors.New("refusing to operate on the workspace root")
+
+// resolveManagedPath resolves a path argument for the file management tools,
+// which never act on the Workspace root itself.
+func resolveManagedPath(args inference.ToolCall, key string) (rel, abs string, err error) {
+	rel, ok := args.Function.Arguments[key].(string)
+	if !ok || rel == "" {
+		return "", "", fmt.Errorf("%s is required", key)
+	}
+	abs, err = ResolveWorkspacePath(rel)
+	if err != nil {
+		return "", "", err
+	}
+	if root, err := filepath.Abs(WorkspaceRoot()); err == nil && abs == filepath.Clean(root) {
+		return "", "", errWorkspaceRoot
+	}
+	return rel, abs, nil
+}
+
+// prepareDestination checks that dst may be created, or replaced when
+// overwrite is set. A symlink is never replaced: writing to it would
+// change its target instead.
+func prepareDestination(dst string, overwrite bool) error {
+	info, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkWrite(dst)
+	}
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		return errors.New("destination already exists; pass overwrite=true to replace it")
+	}
+	if info.IsDir() {
+		return errors.New("destination is an existing directory")
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return errors.New("destination is a symlink; delete it first")
+	}
+	return checkUnchanged(dst)
+}
+
+func pathPairDefinition(name, description string) map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        name,
+			"description": description,
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"source": map[string]any{
+						"type":        "string",
+						"description": "Relative path of the existing file or directory.",
+					},
+					"destination": map[string]any{
+						"type":        "string",
+						"description": "Relative path to create. Parent directories are created as needed.",
+					},
+					"overwrite": map[string]any{
+						"type":        "boolean",
+						"description": "Replace an existing destination file. Defaults to false.",
+					},
+				},
+				"required": []string{"source", "destination"},
+			},
+		},
+	}
+}
+
+type MovePath struct{}
+
+func (mp *MovePath) Name() string { return "move_path" }
+
+func (mp *MovePath) Definition() map[string]any {
+	return pathPairDefinition(mp.Name(), "Move or rename a file or directory within the workspace.")
+}
+
+func (mp *MovePath) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	src, srcAbs, err := resolveManagedPath(args, "source")
+	if err != nil {
+		return Failure(err)
+	}
+	dst, dstAbs, err := resolveManagedPath(args, "destination")
+	if err != nil {
+		return Failure(err)
+	}
+	info, err := os.Lstat(srcAbs)
+	if err != nil {
+		return Failure(err)
+	}
+	if _, inside := pathWithin(srcAbs, dstAbs); inside && info.IsDir() {
+		return Failure(errors.New("cannot move a directory into itself"))
+	}
+	overwrite, _ := args.Function.Arguments["overwrite"].(bool)
+	if err := prepareDestination(dstAbs, overwrite); err != nil {
+		return Failure(fmt.Errorf("%s: %w", dst, err))
+	}
+	if err := os.MkdirAll(filepath.Dir(dstAbs), 0o755); err != nil {
+		return Failure(err)
+	}
+	if err := movePath(srcAbs, dstAbs); err != nil {
+		return Failure(err)
+	}
+	retrackTree(srcAbs, dstAbs)
+	kv := []any{"source", src, "destination", dst, "is_dir", info.IsDir()}
+	return Success(notifyMutation(ctx, kv, srcAbs, dstAbs)...).WithSummary("→ %s", dst)
+}
+
+type CopyPath struct{}
+
+func (cp *CopyPath) Name() string { return "copy_path" }
+
+func (cp *CopyPath) Definition() map[string]any {
+	return pathPairDefinition(cp.Name(), "Copy a file or directory (recursively) within the workspace.")
+}
+
+func (cp *CopyPath) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	src, srcAbs, err := resolveManagedPath(args, "source")
+	if err != nil {
+		return Failure(err)
+	}
+	dst, dstAbs, err := resolveManagedPath(args, "destination")
+	if err != nil {
+		return Failure(err)
+	}
+	info, err := os.Stat(srcAbs)
+	if err != nil {
+		return Failure(err)
+	}
+	if _, inside := pathWithin(srcAbs, dstAbs); inside && info.IsDir() {
+		return Failure(errors.New("cannot copy a directory into itself"))
+	}
+	overwrite, _ := args.Function.Arguments["overwrite"].(bool)
+	if err := prepareDestination(dstAbs, overwrite); err != nil {
+		return Failure(fmt.Errorf("%s: %w", dst, err))
+	}
+	if err := copyTree(srcAbs, dstAbs, true); err != nil {
+		return Failure(err)
+	}
+	kv := []any{"source", src, "destination", dst, "is_dir", info.IsDir()}
+	return Success(notifyMutation(ctx, kv, dstAbs)...).WithSummary("→ %s", dst)
+}
+
+type DeletePath struct{}
+
+func (dp *DeletePath) Name() string { return "delete_path" }
+
+func (dp *DeletePath) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        dp.Name(),
+			"description": "Delete a file or directory in the workspace. It is moved to the session trash and can be brought back with restore_path.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{
+						"type":        "string",
+						"description": "Relative path of the file or directory to delete.",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+	}
+}
+
+func (dp *DeletePath) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	rel, abs, err := resolveManagedPath(args, "path")
+	if err != nil {
+		return Failure(err)
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return Failure(err)
+	}
+	if !info.IsDir() {
+		if err := checkUnchanged(abs); err != nil {
+			return Failure(fmt.Errorf("%s: %w", rel, err))
+		}
+	}
+	session.mu.Lock()
+	entry, err := session.trash.put(abs, rel, info.IsDir())
+	session.mu.Unlock()
+	if err != nil {
+		return Failure(err)
+	}
+	forgetTree(abs)
+	kv := []any{"path", rel, "is_dir", info.IsDir(), "trash_id", entry.ID}
+	return Success(notifyMutation(ctx, kv, abs)...).WithSummary("已移入回收站")
+}
+
+type RestorePath struct{}
+
+func (rp *RestorePath) Name() string { return "restore_path" }
+
+func (rp *RestorePath) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        rp.Name(),
+			"description": "Restore a file or directory removed by delete_path in this session.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"trash_id": map[string]any{
+						"type":        "string",
+						"description": "The trash_id returned by delete_path.",
+					},
+					"destination": map[string]any{
+						"type":        "string",
+						"description": "Relative path to restore to. Defaults to the original path.",
+					},
+				},
+				"required": []string{"trash_id"},
+			},
+		},
+	}
+}
+
+func (rp *RestorePath) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	id, ok := args.Function.Arguments["trash_id"].(string)
+	if !ok || id == "" {
+		return Failure(errors.New("trash_id is required"))
+	}
+	var dst, dstAbs string
+	if d, _ := args.Function.Arguments["destination"].(string); d != "" {
+		var err error
+		dst, dstAbs, err = resolveManagedPath(args, "destination")
+		if err != nil {
+			return Failure(err)
+		}
+	}
+	session.mu.Lock()
+	entry, err := session.trash.take(id, dstAbs)
+	session.mu.Unlock()
+	if err != nil {
+		return Failure(err)
+	}
+	if dst == "" {
+		dst, dstAbs = entry.Path, entry.original
+	}
+	kv := []any{"path", dst, "is_dir", entry.IsDir, "trash_id", entry.ID}
+	return Success(notifyMutation(ctx, kv, dstAbs)...).WithSummary("已恢复到 %s", dst)
+}
+
+type MakeDir struct{}
+
+func (md *MakeDir) Name() string { return "make_dir" }
+
+func (md *MakeDir) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        md.Name(),
+			"description": "Create a directory (and any missing parents) in the workspace.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{
+						"type":        "string",
+						"description": "Relative path of the directory to create.",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+	}
+}
+
+func (md *MakeDir) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	rel, abs, err := resolveManagedPath(args, "path")
+	if err != nil {
+		return Failure(err)
+	}
+	info, err := os.Stat(abs)
+	if err == nil {
+		if !info.IsDir() {
+			return Failure(fmt.Errorf("%s exists and is not a directory", rel))
+		}
+		return Success("path", rel, "created", false).WithSummary("已存在")
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return Failure(err)
+	}
+	return Success(notifyMutation(ctx, []any{"path", rel, "created", true}, abs)...).WithSummary("已创建")
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func callTool(t *testing.T, tool Tool, args map[string]any) string {
+	t.Helper()
+	resp := tool.Call(context.Background(), inference.ToolCall{
+		ID:       "call-1",
+		Function: inference.Function{Name: tool.Name(), Arguments: args},
+	})
+	content := resp.ModelText()
+	return content
+}
+
+func TestMovePath_renamesFile(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := callTool(t, &MovePath{}, map[string]any{"source": "a.txt", "destination": "sub/b.txt"})
+	if !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("move = %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("source still exists after move")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "sub", "b.txt")); string(got) != "a" {
+		t.Fatalf("destination content = %q", got)
+	}
+}
+
+func TestMovePath_refusesOverwriteByDefault(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := callTool(t, &MovePath{}, map[string]any{"source": "a.txt", "destination": "b.txt"})
+	if !strings.Contains(out, "already exists") {
+		t.Fatalf("move = %q, want overwrite refusal", out)
+	}
+	out = callTool(t, &MovePath{}, map[string]any{"source": "a.txt", "destination": "b.txt", "overwrite": true})
+	if !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("move with overwrite = %q", out)
+	}
+}
+
+func TestCopyPath_refusesSymlinkDestination(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	secret := filepath.Join(t.TempDir(), "bashrc")
+	if err := os.WriteFile(secret, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "link.txt")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("pwned\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := callTool(t, &CopyPath{}, map[string]any{"source": "a.txt", "destination": "link.txt", "overwrite": true})
+	if !strings.Contains(out, "symlink") {
+		t.Fatalf("copy = %q, want symlink refusal", out)
+	}
+	if got, _ := os.ReadFile(secret); string(got) != "old\n" {
+		t.Fatalf("symlink target = %q", got)
+	}
+}
+
+func TestCopyPath_copiesDirectoryWithModes(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	if err := os.MkdirAll(filepath.Join(dir, "src", "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "bin", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out := callTool(t, &CopyPath{}, map[string]any{"source": "src", "destination": "dst"})
+	if !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("copy = %q", out)
+	}
+	info, err := os.Stat(filepath.Join(dir, "dst", "bin", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("copied mode = %v, want 0755", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src", "bin", "run.sh")); err != nil {
+		t.Fatal("source missing after copy")
+	}
+}
+
+func TestDeletePath_restoreFromTrash(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	path := filepath.Join(dir, "keep", "data.txt")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("precious"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := callTool(t, &DeletePath{}, map[string]any{"path": "keep"})
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			TrashID string `json:"trash_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil || resp.Status != "SUCCESS" || resp.Data.TrashID == "" {
+		t.Fatalf("delete = %q", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("path still exists after delete")
+	}
+
+	out = callTool(t, &RestorePath{}, map[string]any{"trash_id": resp.Data.TrashID})
+	if !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("restore = %q", out)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "precious" {
+		t.Fatalf("restored content = %q", got)
+	}
+}
+
+func TestDeletePath_trashDiscardedWithSession(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	if err := os.WriteFile(filepath.Join(dir, "x.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	callTool(t, &DeletePath{}, map[string]any{"path": "x.txt"})
+	ResetSession()
+	if out := callTool(t, &RestorePath{}, map[string]any{"trash_id": "trash-1"}); !strings.Contains(out, "FAILED") {
+		t.Fatalf("restore after reset = %q, want FAILED", out)
+	}
+}
+
+func TestFileManagement_refusesWorkspaceRoot(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+
+	cases := []struct {
+		tool Tool
+		args map[string]any
+	}{
+		{&DeletePath{}, map[string]any{"path": "."}},
+		{&DeletePath{}, map[string]any{"path": dir}},
+		{&MovePath{}, map[string]any{"source": ".", "destination": "x"}},
+		{&CopyPath{}, map[string]any{"source": "sub/..", "destination": "x"}},
+		{&MakeDir{}, map[string]any{"path": "./"}},
+	}
+	for _, c := range cases {
+		if out := callTool(t, c.tool, c.args); !strings.Contains(out, "workspace root") {
+			t.Errorf("%s(%v) = %q, want workspace root refusal", c.tool.Name(), c.args, out)
+		}
+	}
+	if out := callTool(t, &DeletePath{}, map[string]any{"path": "../x"}); !strings.Contains(out, "path escapes workspace") {
+		t.Fatalf("delete outside = %q", out)
+	}
+}
+
+func TestMovePath_keepsDestinationOnRenameError(t *testing.T) {
+	t.Cleanup(ResetSession)
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	for _, f := range []string{filepath.Join(src, "a.txt"), filepath.Join(dst, "keep.txt")} {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Renaming onto a non-empty directory fails without crossing devices;
+	// it must not fall back to copying.
+	if err := movePath(src, dst); err == nil {
+		t.Fatal("rename onto a non-empty directory succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "keep.txt")); err != nil {
+		t.Fatalf("destination lost: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a.txt")); err == nil {
+		t.Fatal("source was copied into the destination")
+	}
+
+	if err := copyTree(src, filepath.Join(dir, "copy"), false); err != nil {
+		t.Fatal(err)
+	}
+	session.mu.Lock()
+	_, tracked := session.files[trackingKey(filepath.Join(dir, "copy", "a.txt"))]
+	session.mu.Unlock()
+	if tracked {
+		t.Fatal("an untracked copy recorded a stamp")
+	}
+}
+
+func TestMakeDir_createsParents(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+
+	obs := withObserver(t, "")
+	if out := callTool(t, &MakeDir{}, map[string]any{"path": "a/b/c"}); !strings.Contains(out, `"created":true`) {
+		t.Fatalf("make_dir = %q", out)
+	}
+	if len(obs.paths) != 1 || obs.paths[0][0] != filepath.Join(dir, "a", "b", "c") {
+		t.Fatalf("observer calls = %v", obs.paths)
+	}
+	if out := callTool(t, &MakeDir{}, map[string]any{"path": "a/b/c"}); !strings.Contains(out, `"created":false`) {
+		t.Fatalf("second make_dir = %q", out)
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+// Registry holds tools by name in registration order, so the tool
+// definitions sent to the Inference Backend are identical on every request
+// and provider-side prompt caches stay warm.
+type Registry struct {
+	order []string
+	tools map[string]Tool
+}
+
+func NewRegistry(ts ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool)}
+	r.Register(ts...)
+	return r
+}
+
+// Register adds tools at the end. A tool whose name is already registered
+// replaces the old one in its position.
+func (r *Registry) Register(ts ...Tool) {
+	for _, t := range ts {
+		name := t.Name()
+		if _, ok := r.tools[name]; !ok {
+			r.order = append(r.order, name)
+		}
+		r.tools[name] = t
+	}
+}
+
+// Remove drops the named tool and reports whether it was registered.
+func (r *Registry) Remove(name string) bool {
+	if _, ok := r.tools[name]; !ok {
+		return false
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (r *Registry) Get(name string) (Tool, bool) {
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+func (r *Registry) Len() int { return len(r.order) }
+
+// Tools returns the tools in registration order.
+func (r *Registry) Tools() []Tool {
+	ts := make([]Tool, len(r.order))
+	for i, name := range r.order {
+		ts[i] = r.tools[name]
+	}
+	return ts
+}
+
+// Definitions returns the definitions of the tools in registration order.
+func (r *Registry) Definitions() []map[string]any {
+	defs := make([]map[string]any, len(r.order))
+	for i, name := range r.order {
+		defs[i] = r.tools[name].Definition()
+	}
+	return defs
+}
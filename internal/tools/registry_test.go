@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func registryNames(r *Registry) []string {
+	var names []string
+	for _, tool := range r.Tools() {
+		names = append(names, tool.Name())
+	}
+	return names
+}
+
+func TestRegistry_keepsRegistrationOrder(t *testing.T) {
+	r := NewRegistry(&WriteFile{}, &ReadFile{}, &RunShell{})
+	r.Register(&ListFile{})
+	if got, want := registryNames(r), []string{"write_file", "read_file", "run_shell", "list_file"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+
+	// A replacement keeps the position of the tool it replaces.
+	replacement := &ReadFile{}
+	r.Register(replacement)
+	if got, _ := r.Get("read_file"); got != replacement || r.Len() != 4 || registryNames(r)[1] != "read_file" {
+		t.Fatalf("after replace: %v", registryNames(r))
+	}
+
+	if !r.Remove("read_file") || r.Remove("read_file") {
+		t.Fatal("Remove should report whether the tool was registered")
+	}
+	if _, ok := r.Get("read_file"); ok {
+		t.Fatal("removed tool is still registered")
+	}
+	defs := r.Definitions()
+	if len(defs) != 3 || defs[2]["function"].(map[string]any)["name"] != "list_file" {
+		t.Fatalf("definitions = %v", defs)
+	}
+}
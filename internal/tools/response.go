@@ -2,45 +2,77 @@ package tools
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 )
 
-func successResp(toolID string, kv ...any) map[string]any {
-	data := make(map[string]any)
+type Status string
+
+const (
+	StatusSuccess Status = "SUCCESS"
+	StatusFailed  Status = "FAILED"
+)
+
+// ToolResult is the outcome of a tool call. The Agent turns it into the
+// message the Inference Backend sees; the Transcript shows its Summary.
+type ToolResult struct {
+	Status Status
+	// Data holds the structured result; failures carry "error".
+	Data map[string]any
+	// Text replaces the default model-facing rendering of Data.
+	Text string
+	// Summary is a one-line description for the developer.
+	Summary string
+}
+
+// Success builds a result from key-value pairs of Data.
+func Success(kv ...any) ToolResult {
+	data := make(map[string]any, len(kv)/2)
 	for i := 0; i < len(kv); i += 2 {
 		data[kv[i].(string)] = kv[i+1]
 	}
-	return toolResp(toolID, data, "SUCCESS")
+	return ToolResult{Status: StatusSuccess, Data: data}
 }
 
-func FailResp(toolID string, err error) map[string]any {
-	return toolResp(toolID, map[string]any{"error": err.Error()}, "FAILED")
+// Failure reports err; the Violations of an ArgumentError are listed under
+// "violations".
+func Failure(err error) ToolResult {
+	data := map[string]any{"error": err.Error()}
+	var argErr *ArgumentError
+	if errors.As(err, &argErr) {
+		data["violations"] = argErr.Violations
+	}
+	summary, _, _ := strings.Cut(err.Error(), "\n")
+	return ToolResult{Status: StatusFailed, Data: data, Summary: summary}
 }
 
-func failResp(toolID string, err error) map[string]any {
-	return FailResp(toolID, err)
+// WithSummary returns r with its Summary set.
+func (r ToolResult) WithSummary(format string, a ...any) ToolResult {
+	r.Summary = fmt.Sprintf(format, a...)
+	return r
 }
 
-func toolResp(toolID string, data map[string]any, status string) map[string]any {
-	info := struct {
-		Status string         `json:"status"`
-		Data   map[string]any `json:"data"`
-	}{
-		Status: status,
-		Data:   data,
-	}
+func (r ToolResult) Failed() bool { return r.Status != StatusSuccess }
 
-	d, err := json.Marshal(info)
-	if err != nil {
-		return map[string]any{
-			"role":         "tool",
-			"tool_call_id": toolID,
-			"content":      `{"status": "FAILED", "data": "error marshaling tool response"}`,
-		}
-	}
+// Error returns the error of a failed result.
+func (r ToolResult) Error() string {
+	msg, _ := r.Data["error"].(string)
+	return msg
+}
 
-	return map[string]any{
-		"role":         "tool",
-		"tool_call_id": toolID,
-		"content":      string(d),
+// ModelText renders the result for the model: Text if set, otherwise
+// {"status": ..., "data": ...} as JSON.
+func (r ToolResult) ModelText() string {
+	if r.Text != "" {
+		return r.Text
+	}
+	d, err := json.Marshal(struct {
+		Status Status         `json:"status"`
+		Data   map[string]any `json:"data"`
+	}{r.Status, r.Data})
+	if err != nil {
+		return `{"status": "FAILED", "data": "error marshaling tool response"}`
 	}
+	return string(d)
 }
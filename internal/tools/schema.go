@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is one way the arguments of a call break the parameters schema
+// of its tool. Field is a path such as "edits[0].old"; it is empty for the
+// arguments as a whole.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ArgumentError reports every Violation of a call, so the model can fix
+// them all at once. Failure includes them in the result.
+type ArgumentError struct {
+	Tool       string
+	Violations []Violation
+}
+
+func (e *ArgumentError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		field := v.Field
+		if field == "" {
+			field = "arguments"
+		}
+		msgs[i] = field + ": " + v.Message
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(msgs, "; "))
+}
+
+// ValidateArguments checks args against the parameters schema of tool's
+// Definition. It understands the JSON Schema keywords tools use: type,
+// enum, const, properties, required, additionalProperties, items, anyOf,
+// the length, size and range bounds, and pattern. Other keywords are
+// ignored.
+func ValidateArguments(tool Tool, args map[string]any) error {
+	fn, _ := tool.Definition()["function"].(map[string]any)
+	schema, ok := fn["parameters"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	var vs []Violation
+	validateValue(schema, args, "", &vs)
+	if len(vs) == 0 {
+		return nil
+	}
+	return &ArgumentError{Tool: tool.Name(), Violations: vs}
+}
+
+func validateValue(schema map[string]any, v any, path string, vs *[]Violation) {
+	add := func(format string, a ...any) {
+		*vs = append(*vs, Violation{Field: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if branches, ok := schema["anyOf"].([]any); ok && len(branches) > 0 {
+		matched := false
+		for _, b := range branches {
+			if bs, ok := b.(map[string]any); ok {
+				var sub []Violation
+				validateValue(bs, v, path, &sub)
+				if len(sub) == 0 {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			add("does not match any of the allowed schemas")
+			return
+		}
+	}
+
+	if types := schemaStrings(schema["type"]); len(types) > 0 && !matchesType(types, v) {
+		add("expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+		return
+	}
+	if enum, ok := schemaList(schema["enum"]); ok && !containsJSON(enum, v) {
+		add("must be one of %s, got %s", formatEnum(enum), formatJSON(v))
+		return
+	}
+	if c, ok := schema["const"]; ok && !equalJSON(c, v) {
+		add("must be %s", formatJSON(c))
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if min, ok := schemaNumber(schema["minLength"]); ok && float64(n) < min {
+			add("must be at least %v characters long", min)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && float64(n) > max {
+			add("must be at most %v characters long", max)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(v) {
+				add("must match the pattern %s", p)
+			}
+		}
+	case float64:
+		if min, ok := schemaNumber(schema["minimum"]); ok && v < min {
+			add("must be at least %v", min)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && v > max {
+			add("must be at most %v", max)
+		}
+		if min, ok := schemaNumber(schema["exclusiveMinimum"]); ok && v <= min {
+			add("must be greater than %v", min)
+		}
+		if max, ok := schemaNumber(schema["exclusiveMaximum"]); ok && v >= max {
+			add("must be less than %v", max)
+		}
+	case []any:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			add("must have at least %v items", min)
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			add("must have at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), vs)
+			}
+		}
+	case map[string]any:
+		validateObject(schema, v, path, vs)
+	}
+}
+
+func validateObject(schema map[string]any, obj map[string]any, path string, vs *[]Violation) {
+	field := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			*vs = append(*vs, Violation{Field: field(name), Message: "is required"})
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ps, ok := props[name].(map[string]any); ok {
+			validateValue(ps, obj[name], field(name), vs)
+			continue
+		}
+		if _, ok := props[name]; ok {
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				*vs = append(*vs, Violation{Field: field(name), Message: "is not a known parameter"})
+			}
+		case map[string]any:
+			validateValue(extra, obj[name], field(name), vs)
+		}
+	}
+}
+
+func matchesType(types []string, v any) bool {
+	got := jsonType(v)
+	for _, t := range types {
+		if t == got || t == "number" && got == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// schemaStrings reads a string or a list of strings, which Go-built schemas
+// hold as []string and decoded ones as []any.
+func schemaStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// schemaList reads a list of any element type, such as an enum.
+func schemaList(v any) ([]any, bool) {
+	rv := reflect.ValueOf(v)
+	if v == nil || rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
+
+func schemaNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// equalJSON compares values by their JSON encoding, so 1 and 1.0 or
+// []string and []any are equal.
+func equalJSON(a, b any) bool {
+	return formatJSON(a) == formatJSON(b)
+}
+
+func containsJSON(list []any, v any) bool {
+	for _, item := range list {
+		if equalJSON(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func formatEnum(enum []any) string {
+	items := make([]string, len(enum))
+	for i, e := range enum {
+		items[i] = formatJSON(e)
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+// schemaTool is a Tool with a given parameters schema.
+type schemaTool map[string]any
+
+func (s schemaTool) Name() string { return "schema_tool" }
+
+func (s schemaTool) Definition() map[string]any {
+	return map[string]any{"function": map[string]any{"name": "schema_tool", "parameters": map[string]any(s)}}
+}
+
+func (s schemaTool) Call(context.Context, inference.ToolCall) ToolResult { return ToolResult{} }
+
+func TestValidateArguments(t *testing.T) {
+	schema := schemaTool{
+		"type": "object",
+		"properties": map[string]any{
+			"mode":  map[string]any{"type": "string", "enum": []string{"fast", "slow"}},
+			"count": map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+			"ratio": map[string]any{"type": "number", "exclusiveMaximum": 1},
+			"name":  map[string]any{"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+			"tags":  map[string]any{"type": "array", "maxItems": 2, "items": map[string]any{"type": "string"}},
+			"edits": map[string]any{"type": "array", "items": map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"old": map[string]any{"type": "string"}},
+				"required":             []string{"old"},
+				"additionalProperties": false,
+			}},
+			"id": map[string]any{"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "integer"},
+			}},
+			"note": map[string]any{"type": []any{"string", "null"}},
+		},
+		"required": []any{"mode"},
+	}
+
+	for _, tc := range []struct {
+		name string
+		args string
+		want []Violation
+	}{
+		{name: "valid", args: `{"mode": "fast", "count": 3, "ratio": 0.5, "name": "abc", "tags": ["a"], "edits": [{"old": "x"}], "id": 7, "note": null}`},
+		{name: "nil arguments", args: `null`, want: []Violation{{"mode", "is required"}}},
+		{name: "extra parameters are allowed", args: `{"mode": "slow", "other": 1}`},
+		{name: "enum", args: `{"mode": "medium"}`, want: []Violation{{"mode", `must be one of "fast", "slow", got "medium"`}}},
+		{name: "integer", args: `{"mode": "fast", "count": 2.5}`, want: []Violation{{"count", "expected integer, got number"}}},
+		{name: "range", args: `{"mode": "fast", "count": 11, "ratio": 1}`, want: []Violation{
+			{"count", "must be at most 10"}, {"ratio", "must be less than 1"},
+		}},
+		{name: "string bounds", args: `{"mode": "fast", "name": "A"}`, want: []Violation{
+			{"name", "must be at least 2 characters long"}, {"name", "must match the pattern ^[a-z]+$"},
+		}},
+		{name: "nested", args: `{"mode": "fast", "tags": ["a", 2, "c"], "edits": [{"old": "x"}, {"new": "y"}]}`, want: []Violation{
+			{"edits[1].old", "is required"}, {"edits[1].new", "is not a known parameter"},
+			{"tags", "must have at most 2 items"}, {"tags[1]", "expected string, got integer"},
+		}},
+		{name: "anyOf", args: `{"mode": "fast", "id": true}`, want: []Violation{{"id", "does not match any of the allowed schemas"}}},
+		{name: "type list", args: `{"mode": "fast", "note": 1}`, want: []Violation{{"note", "expected string or null, got integer"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(tc.args), &args); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateArguments(schema, args)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			var argErr *ArgumentError
+			if !errors.As(err, &argErr) {
+				t.Fatalf("err = %v, want an ArgumentError", err)
+			}
+			if !reflect.DeepEqual(argErr.Violations, tc.want) {
+				t.Fatalf("violations:\n got: %v\nwant: %v", argErr.Violations, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateArguments_builtinSchemas(t *testing.T) {
+	for _, tool := range Builtin() {
+		if err := ValidateArguments(tool, map[string]any{}); err != nil {
+			var argErr *ArgumentError
+			if !errors.As(err, &argErr) {
+				t.Fatalf("%s: %v", tool.Name(), err)
+			}
+			for _, v := range argErr.Violations {
+				if v.Message != "is required" {
+					t.Errorf("%s: empty arguments: %v", tool.Name(), v)
+				}
+			}
+		}
+	}
+}
+
+func TestFailure_listsViolations(t *testing.T) {
+	err := &ArgumentError{Tool: "read_file", Violations: []Violation{{"path", "is required"}, {"", "expected object, got array"}}}
+	var resp struct {
+		Data struct {
+			Error      string      `json:"error"`
+			Violations []Violation `json:"violations"`
+		} `json:"data"`
+	}
+	if jerr := json.Unmarshal([]byte(Failure(err).ModelText()), &resp); jerr != nil {
+		t.Fatal(jerr)
+	}
+	if resp.Data.Error != "invalid arguments for read_file: path: is required; arguments: expected object, got array" || len(resp.Data.Violations) != 2 {
+		t.Fatalf("resp = %+v", resp)
+	}
+}
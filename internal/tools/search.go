@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchMaxResults = 200
+	maxSearchLineDisplay    = 240
+	binarySniffLen          = 8000
+)
+
+// searchRequest describes one Workspace Search over a directory tree.
+type searchRequest struct {
+	Root       string
+	Pattern    string
+	Mode       string
+	MaxResults int
+	Workers    int
+}
+
+type searchResult struct {
+	Matches   []searchMatch
+	Truncated bool
+}
+
+type searchJob struct {
+	path string
+	rel  string
+	out  chan fileMatches
+}
+
+type fileMatches struct {
+	matches []searchMatch
+	err     error
+}
+
+// runSearch walks req.Root and returns matches ordered by path and line.
+// Files are scanned concurrently by a bounded worker pool, but results are
+// consumed strictly in walk order, so the output is identical to a
+// sequential scan and the result cap always cuts at the same point.
+func runSearch(ctx context.Context, req searchRequest) (searchResult, error) {
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultSearchMaxResults
+	}
+	if req.Workers <= 0 {
+		req.Workers = runtime.GOMAXPROCS(0)
+	}
+	if req.Mode == "filename" {
+		if _, err := filepath.Match(req.Pattern, ""); err != nil {
+			return searchResult{}, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan searchJob, req.Workers)
+	ordered := make(chan searchJob, req.Workers*4)
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		walkErr <- walkSearchTree(ctx, req.Root, ordered, jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for range req.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := ctx.Err(); err != nil {
+					job.out <- fileMatches{err: err}
+					continue
+				}
+				matches, err := matchFile(ctx, req, job)
+				job.out <- fileMatches{matches: matches, err: err}
+			}
+		}()
+	}
+
+	var (
+		res searchResult
+		err error
+	)
+	for job := range ordered {
+		var fm fileMatches
+		select {
+		case fm = <-job.out:
+		case <-ctx.Done():
+			fm.err = ctx.Err()
+		}
+		if fm.err != nil {
+			err = fm.err
+			break
+		}
+		if room := req.MaxResults - len(res.Matches); len(fm.matches) > room {
+			res.Matches = append(res.Matches, fm.matches[:room]...)
+			res.Truncated = true
+			break
+		}
+		res.Matches = append(res.Matches, fm.matches...)
+	}
+	cancel()
+	for range ordered {
+	}
+	wg.Wait()
+
+	switch {
+	case res.Truncated:
+		return res, nil
+	case err != nil:
+		return searchResult{}, err
+	}
+	if err := <-walkErr; err != nil {
+		return searchResult{}, err
+	}
+	return res, nil
+}
+
+func walkSearchTree(ctx context.Context, root string, ordered, jobs chan<- searchJob) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		job := searchJob{path: path, rel: filepath.ToSlash(rel), out: make(chan fileMatches, 1)}
+		for _, ch := range []chan<- searchJob{ordered, jobs} {
+			select {
+			case ch <- job:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+func matchFile(ctx context.Context, req searchRequest, job searchJob) ([]searchMatch, error) {
+	if req.Mode == "filename" {
+		matched, err := filepath.Match(req.Pattern, filepath.Base(job.path))
+		if err != nil || !matched {
+			return nil, err
+		}
+		return []searchMatch{{File: job.rel}}, nil
+	}
+
+	f, err := os.Open(job.path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	return scanContent(ctx, f, job.rel, req.Pattern, req.MaxResults)
+}
+
+// scanContent reports lines of r containing pattern. Lines of any length are
+// supported; binary content (a NUL byte near the start) is skipped.
+func scanContent(ctx context.Context, r io.Reader, rel, pattern string, limit int) ([]searchMatch, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	head, _ := br.Peek(binarySniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	needle := []byte(pattern)
+	var (
+		matches []searchMatch
+		line    []byte
+		lineNum int
+	)
+	for {
+		var err error
+		line, err = readLine(br, line)
+		if len(line) > 0 {
+			lineNum++
+			if lineNum%4096 == 0 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			text := bytes.TrimRight(line, "\r\n")
+			if bytes.Contains(text, needle) {
+				matches = append(matches, searchMatch{
+					File: rel, Line: lineNum, Content: clipMatchLine(string(text), pattern),
+				})
+				if len(matches) >= limit {
+					return matches, nil
+				}
+			}
+		}
+		if err == io.EOF {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+	}
+}
+
+// readLine returns the next line of br, including its terminator, reusing
+// buf. Unlike bufio.Scanner it has no maximum line length.
+func readLine(br *bufio.Reader, buf []byte) ([]byte, error) {
+	buf = buf[:0]
+	for {
+		chunk, err := br.ReadSlice('\n')
+		buf = append(buf, chunk...)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return buf, err
+		}
+	}
+}
+
+// clipMatchLine keeps very long lines (minified sources) readable by showing
+// a window around the first occurrence of pattern.
+func clipMatchLine(line, pattern string) string {
+	if len(line) <= maxSearchLineDisplay {
+		return line
+	}
+	at := strings.Index(line, pattern)
+	start := max(0, at-(maxSearchLineDisplay-len(pattern))/2)
+	end := min(len(line), start+maxSearchLineDisplay)
+	start = max(0, end-maxSearchLineDisplay)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end++
+	}
+	out := line[start:end]
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(line) {
+		out += "…"
+	}
+	return out
+}
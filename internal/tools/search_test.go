@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSearchTree(t testing.TB, root string, dirs, filesPerDir, linesPerFile int) {
+	t.Helper()
+	for d := range dirs {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%03d", d))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for f := range filesPerDir {
+			var b strings.Builder
+			for l := range linesPerFile {
+				if l%50 == 0 {
+					fmt.Fprintf(&b, "func needle%d_%d() {}\n", f, l)
+				} else {
+					fmt.Fprintf(&b, "// filler line %d in file %d\n", l, f)
+				}
+			}
+			path := filepath.Join(dir, fmt.Sprintf("file%03d.go", f))
+			if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRunSearch_deterministicOrder(t *testing.T) {
+	root := t.TempDir()
+	writeSearchTree(t, root, 8, 8, 120)
+
+	req := searchRequest{Root: root, Pattern: "needle", Mode: "content", MaxResults: 10000}
+	req.Workers = 1
+	sequential, err := runSearch(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Workers = 16
+	parallel, err := runSearch(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequential.Matches) != 8*8*3 {
+		t.Fatalf("matches = %d, want %d", len(sequential.Matches), 8*8*3)
+	}
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Fatal("parallel search returned a different result than sequential search")
+	}
+}
+
+func TestRunSearch_stopsAtResultCap(t *testing.T) {
+	root := t.TempDir()
+	writeSearchTree(t, root, 4, 4, 120)
+
+	res, err := runSearch(context.Background(), searchRequest{
+		Root: root, Pattern: "needle", Mode: "content", MaxResults: 5, Workers: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Matches) != 5 || !res.Truncated {
+		t.Fatalf("matches = %d truncated = %v, want 5 and true", len(res.Matches), res.Truncated)
+	}
+	if res.Matches[0].File != "pkg000/file000.go" || res.Matches[0].Line != 1 {
+		t.Fatalf("first match = %+v", res.Matches[0])
+	}
+}
+
+func TestRunSearch_longLines(t *testing.T) {
+	root := t.TempDir()
+	long := strings.Repeat("x", 1<<20) + "needle" + strings.Repeat("y", 1<<20)
+	if err := os.WriteFile(filepath.Join(root, "app.min.js"), []byte("first\n"+long+"\nlast needle\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := runSearch(context.Background(), searchRequest{Root: root, Pattern: "needle", Mode: "content"})
+	if err != nil {
+		t.Fatalf("runSearch: %v", err)
+	}
+	if len(res.Matches) != 2 {
+		t.Fatalf("matches = %d, want 2", len(res.Matches))
+	}
+	if res.Matches[0].Line != 2 || res.Matches[1].Line != 3 {
+		t.Fatalf("lines = %d, %d, want 2, 3", res.Matches[0].Line, res.Matches[1].Line)
+	}
+	if got := res.Matches[0].Content; len(got) > maxSearchLineDisplay+8 || !strings.Contains(got, "needle") {
+		t.Fatalf("long line not clipped around match: %d bytes", len(got))
+	}
+}
+
+func TestRunSearch_skipsBinaryFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "blob.bin"), []byte("needle\x00\x01"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := runSearch(context.Background(), searchRequest{Root: root, Pattern: "needle", Mode: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Matches) != 0 {
+		t.Fatalf("matches = %v, want none for binary file", res.Matches)
+	}
+}
+
+func TestRunSearch_honorsCancellation(t *testing.T) {
+	root := t.TempDir()
+	writeSearchTree(t, root, 4, 4, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := runSearch(ctx, searchRequest{Root: root, Pattern: "needle", Mode: "content"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunSearch_filenameMode(t *testing.T) {
+	root := t.TempDir()
+	writeSearchTree(t, root, 2, 2, 1)
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := runSearch(context.Background(), searchRequest{Root: root, Pattern: "*.md", Mode: "filename"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Matches) != 1 || res.Matches[0].File != "README.md" {
+		t.Fatalf("matches = %v, want README.md", res.Matches)
+	}
+}
+
+func BenchmarkRunSearch_content(b *testing.B) {
+	root := b.TempDir()
+	writeSearchTree(b, root, 40, 25, 400)
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			req := searchRequest{Root: root, Pattern: "needle", Mode: "content", MaxResults: 1 << 20, Workers: workers}
+			for b.Loop() {
+				if _, err := runSearch(context.Background(), req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRunSearch_earlyStop(b *testing.B) {
+	root := b.TempDir()
+	writeSearchTree(b, root, 40, 25, 400)
+
+	req := searchRequest{Root: root, Pattern: "needle", Mode: "content", MaxResults: 20}
+	for b.Loop() {
+		if _, err := runSearch(context.Background(), req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EnvNewFileWrites    = "MINI_AGENT_NEW_FILE_WRITES"
+	EnvUnreadFileWrites = "MINI_AGENT_UNREAD_FILE_WRITES"
+)
+
+var (
+	ErrStaleFile  = errors.New("file was modified outside the agent since it was last read; call read_file again before writing")
+	ErrUnreadFile = errors.New("file has not been read in this session; call read_file before writing")
+	ErrNewFile    = errors.New("creating new files is disabled by the write policy")
+)
+
+// WriteRule decides whether a File Mutation may touch a file the agent has
+// no record of.
+type WriteRule string
+
+const (
+	WriteAllow WriteRule = "allow"
+	WriteDeny  WriteRule = "deny"
+)
+
+// WritePolicy configures stale-write protection for files the Session has
+// not seen. Files that were read or written are always checked for external
+// changes.
+type WritePolicy struct {
+	NewFiles    WriteRule
+	UnreadFiles WriteRule
+}
+
+var DefaultWritePolicy = WritePolicy{NewFiles: WriteAllow, UnreadFiles: WriteAllow}
+
+func WritePolicyFromEnv() (WritePolicy, error) {
+	p := DefaultWritePolicy
+	for env, rule := range map[string]*WriteRule{
+		EnvNewFileWrites:    &p.NewFiles,
+		EnvUnreadFileWrites: &p.UnreadFiles,
+	} {
+		switch v := WriteRule(os.Getenv(env)); v {
+		case "":
+		case WriteAllow, WriteDeny:
+			*rule = v
+		default:
+			return WritePolicy{}, fmt.Errorf("%s must be %q or %q, got %q", env, WriteAllow, WriteDeny, v)
+		}
+	}
+	return p, nil
+}
+
+func SetWritePolicy(p WritePolicy) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.policy = p
+}
+
+// fileStamp is what the agent last saw of a file.
+type fileStamp struct {
+	hash    [sha256.Size]byte
+	size    int64
+	modTime time.Time
+}
+
+type sessionState struct {
+	mu     sync.Mutex
+	policy WritePolicy
+	files  map[string]fileStamp
+	trash  trash
+	// artifacts hold tool results too large for the model; see artifact.go.
+	artifacts artifactStore
+	// todos is the task list of todo_write.
+	todos []Todo
+}
+
+var session = &sessionState{policy: DefaultWritePolicy, files: make(map[string]fileStamp)}
+
+// ResetSession discards Session-scoped tool state: which files the agent has
+// read, the contents of the Session trash, the stored artifacts and the
+// task list.
+func ResetSession() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.files = make(map[string]fileStamp)
+	session.trash.discard()
+	session.artifacts = artifactStore{}
+	session.todos = nil
+}
+
+type untrackedReadsKey struct{}
+
+// WithUntrackedReads marks ctx so that reads made with it do not count as
+// the Session's: a sub-agent's read_file must not let the agent write a file
+// it never saw.
+func WithUntrackedReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untrackedReadsKey{}, true)
+}
+
+func tracksReads(ctx context.Context) bool {
+	untracked, _ := ctx.Value(untrackedReadsKey{}).(bool)
+	return !untracked
+}
+
+// recordFile remembers the current on-disk state of path after the agent
+// has read or written it.
+func recordFile(path string, hash [sha256.Size]byte) {
+	path = trackingKey(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.files[path] = fileStamp{hash: hash, size: info.Size(), modTime: info.ModTime()}
+}
+
+// retrackTree moves stamps for src, and anything beneath it, to dst.
+func retrackTree(src, dst string) {
+	src, dst = trackingKey(src), trackingKey(dst)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for path, stamp := range session.files {
+		if rel, ok := pathWithin(src, path); ok {
+			delete(session.files, path)
+			session.files[filepath.Join(dst, rel)] = stamp
+		}
+	}
+}
+
+// forgetTree drops stamps for path and anything beneath it.
+func forgetTree(path string) {
+	path = trackingKey(path)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for p := range session.files {
+		if _, ok := pathWithin(path, p); ok {
+			delete(session.files, p)
+		}
+	}
+}
+
+func pathWithin(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// checkWrite reports whether path may be mutated: it must be unchanged since
+// the agent last saw it, and untracked files follow the WritePolicy.
+func checkWrite(path string) error {
+	path = trackingKey(path)
+	session.mu.Lock()
+	stamp, tracked := session.files[path]
+	policy := session.policy
+	session.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if tracked {
+			return ErrStaleFile
+		}
+		if policy.NewFiles == WriteDeny {
+			return ErrNewFile
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !tracked {
+		if policy.UnreadFiles == WriteDeny {
+			return ErrUnreadFile
+		}
+		return nil
+	}
+	return checkStamp(path, info, stamp)
+}
+
+// checkUnchanged reports ErrStaleFile if path was modified externally since
+// the agent last saw it. Untracked paths always pass.
+func checkUnchanged(path string) error {
+	path = trackingKey(path)
+	session.mu.Lock()
+	stamp, tracked := session.files[path]
+	session.mu.Unlock()
+	if !tracked {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrStaleFile
+	}
+	if err != nil {
+		return err
+	}
+	return checkStamp(path, info, stamp)
+}
+
+func checkStamp(path string, info fs.FileInfo, stamp fileStamp) error {
+	if info.Size() == stamp.size && info.ModTime().Equal(stamp.modTime) {
+		return nil
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	if hash != stamp.hash {
+		return ErrStaleFile
+	}
+	// Touched but not changed: refresh the stamp.
+	recordFile(path, hash)
+	return nil
+}
+
+// trackingKey identifies a file independently of symlinks pointing at it.
+func trackingKey(path string) string {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		return target
+	}
+	return path
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
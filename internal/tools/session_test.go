@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func writeFileCall(t *testing.T, path, content string) string {
+	t.Helper()
+	wf := &WriteFile{}
+	resp := wf.Call(context.Background(), inference.ToolCall{
+		ID: "call-1",
+		Function: inference.Function{
+			Name:      "write_file",
+			Arguments: map[string]any{"path": path, "content": content},
+		},
+	})
+	out := resp.ModelText()
+	return out
+}
+
+func withWritePolicy(t *testing.T, p WritePolicy) {
+	t.Helper()
+	ResetSession()
+	SetWritePolicy(p)
+	t.Cleanup(func() {
+		ResetSession()
+		SetWritePolicy(DefaultWritePolicy)
+	})
+}
+
+func TestWriteFile_rejectsExternalChangeAfterRead(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("v1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readFileCall(t, map[string]any{"path": "notes.txt"})
+
+	if err := os.WriteFile(path, []byte("edited by user\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	out := writeFileCall(t, "notes.txt", "v2\n")
+	if !strings.Contains(out, "FAILED") || !strings.Contains(out, "read_file again") {
+		t.Fatalf("expected stale-write rejection, got %q", out)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "edited by user\n" {
+		t.Fatalf("file content = %q, user edit was overwritten", got)
+	}
+
+	readFileCall(t, map[string]any{"path": "notes.txt"})
+	if out := writeFileCall(t, "notes.txt", "v2\n"); !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("write after re-read = %q, want SUCCESS", out)
+	}
+}
+
+func TestWriteFile_consecutiveWritesAllowed(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	for _, content := range []string{"a", "b", "c"} {
+		if out := writeFileCall(t, "f.txt", content); !strings.Contains(out, "SUCCESS") {
+			t.Fatalf("write %q = %q, want SUCCESS", content, out)
+		}
+	}
+}
+
+func TestWriteFile_touchWithoutChangeAllowed(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("same"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readFileCall(t, map[string]any{"path": "f.txt"})
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := writeFileCall(t, "f.txt", "new"); !strings.Contains(out, "SUCCESS") {
+		t.Fatalf("write after touch = %q, want SUCCESS", out)
+	}
+}
+
+func TestWriteFile_policyForUntrackedFiles(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, WritePolicy{NewFiles: WriteDeny, UnreadFiles: WriteDeny})
+
+	if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out := writeFileCall(t, "old.txt", "x"); !strings.Contains(out, "has not been read") {
+		t.Fatalf("unread write = %q, want rejection", out)
+	}
+	if out := writeFileCall(t, "new.txt", "x"); !strings.Contains(out, "creating new files is disabled") {
+		t.Fatalf("new file write = %q, want rejection", out)
+	}
+}
+
+func TestWritePolicyFromEnv(t *testing.T) {
+	t.Setenv(EnvNewFileWrites, "")
+	t.Setenv(EnvUnreadFileWrites, "deny")
+	p, err := WritePolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.NewFiles != WriteAllow || p.UnreadFiles != WriteDeny {
+		t.Fatalf("policy = %+v", p)
+	}
+
+	t.Setenv(EnvNewFileWrites, "sometimes")
+	if _, err := WritePolicyFromEnv(); err == nil {
+		t.Fatal("expected error for invalid rule")
+	}
+}
+
+func TestCheckWrite_deletedAfterRead(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	path := filepath.Join(dir, "gone.txt")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readFileCall(t, map[string]any{"path": "gone.txt"})
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWrite(path); !errors.Is(err, ErrStaleFile) {
+		t.Fatalf("checkWrite = %v, want ErrStaleFile", err)
+	}
+}
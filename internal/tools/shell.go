@@ -10,8 +10,6 @@ import (
 	"github.com/loveRyujin/mini-agent/internal/inference"
 )
 
-var ErrShellDenied = errors.New("shell execution denied by user")
-
 type RunShell struct{}
 
 func (rs *RunShell) Name() string { return "run_shell" }
@@ -41,19 +39,21 @@ func (rs *RunShell) ApprovalSummary(args inference.ToolCall) string {
 	return cmd
 }
 
-func (rs *RunShell) Call(ctx context.Context, args inference.ToolCall) map[string]any {
+func (rs *RunShell) Call(ctx context.Context, args inference.ToolCall) ToolResult {
 	command, ok := args.Function.Arguments["command"].(string)
 	if !ok || strings.TrimSpace(command) == "" {
-		return failResp(args.ID, errors.New("command is required"))
+		return Failure(errors.New("command is required"))
 	}
-	stdout, stderr, exitCode, err := executeShell(ctx, command)
+	stdout, stderr, exitCode, err := ExecuteShell(ctx, command)
 	if err != nil {
-		return failResp(args.ID, err)
+		return Failure(err)
 	}
-	return successResp(args.ID, "stdout", stdout, "stderr", stderr, "exit_code", exitCode)
+	return Success("stdout", stdout, "stderr", stderr, "exit_code", exitCode).WithSummary("退出码 %d", exitCode)
 }
 
-func executeShell(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error) {
+// ExecuteShell runs command with sh -c in the Workspace root. A non-zero
+// exit status is reported through exitCode, not err.
+func ExecuteShell(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error) {
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Dir = WorkspaceRoot()
 	var outBuf, errBuf bytes.Buffer
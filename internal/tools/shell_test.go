@@ -23,7 +23,7 @@ func TestRunShell_executesInWorkspace(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "SUCCESS") {
 		t.Fatalf("expected SUCCESS, got %q", content)
 	}
@@ -45,7 +45,7 @@ func TestRunShell_rejectsMissingCommand(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "FAILED") {
 		t.Fatalf("expected FAILED, got %q", content)
 	}
@@ -69,7 +69,7 @@ func TestRunShell_runsInWorkspaceDir(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, dir) {
 		t.Fatalf("expected workspace dir in output, got %q", content)
 	}
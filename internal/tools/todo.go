@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+const TodoWriteName = "todo_write"
+
+type TodoStatus string
+
+const (
+	TodoPending    TodoStatus = "pending"
+	TodoInProgress TodoStatus = "in_progress"
+	TodoDone       TodoStatus = "done"
+)
+
+// Todo is one item of the task list the model keeps with todo_write.
+type Todo struct {
+	ID      string     `json:"id"`
+	Content string     `json:"content"`
+	Status  TodoStatus `json:"status"`
+}
+
+// Todos returns the Session's task list.
+func Todos() []Todo {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return slices.Clone(session.todos)
+}
+
+// TodoWrite replaces the Session's task list.
+type TodoWrite struct{}
+
+func (tw *TodoWrite) Name() string { return TodoWriteName }
+
+func (tw *TodoWrite) Definition() map[string]any {
+	return map[string]any{
+		"type": "function",
+		"function": map[string]any{
+			"name":        tw.Name(),
+			"description": "Keep a task list for multi-step work. Send the whole list every time: it replaces the previous one. Mark an item in_progress when you start it and done as soon as it is finished; keep at most one item in_progress. Skip the list for simple tasks.",
+			"parameters": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"todos": map[string]any{
+						"type":        "array",
+						"description": "The complete task list, in order.",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"id": map[string]any{
+									"type":        "string",
+									"description": "A short identifier that stays the same while the item exists, e.g. \"1\".",
+								},
+								"content": map[string]any{
+									"type":        "string",
+									"description": "What the item is about, in one line.",
+								},
+								"status": map[string]any{
+									"type": "string",
+									"enum": []string{string(TodoPending), string(TodoInProgress), string(TodoDone)},
+								},
+							},
+							"required":             []string{"id", "content", "status"},
+							"additionalProperties": false,
+						},
+					},
+				},
+				"required": []string{"todos"},
+			},
+		},
+	}
+}
+
+func (tw *TodoWrite) Call(ctx context.Context, args inference.ToolCall) ToolResult {
+	items, ok := args.Function.Arguments["todos"].([]any)
+	if !ok {
+		return Failure(errors.New("todos must be an array"))
+	}
+	todos := make([]Todo, 0, len(items))
+	seen := make(map[string]bool)
+	inProgress, done := 0, 0
+	for i, item := range items {
+		obj, _ := item.(map[string]any)
+		id, _ := obj["id"].(string)
+		content, _ := obj["content"].(string)
+		status, _ := obj["status"].(string)
+		switch {
+		case id == "" || content == "":
+			return Failure(fmt.Errorf("todos[%d]: id and content are required", i))
+		case seen[id]:
+			return Failure(fmt.Errorf("todos[%d]: duplicate id %q", i, id))
+		}
+		seen[id] = true
+		switch TodoStatus(status) {
+		case TodoPending:
+		case TodoInProgress:
+			inProgress++
+		case TodoDone:
+			done++
+		default:
+			return Failure(fmt.Errorf("todos[%d]: unknown status %q", i, status))
+		}
+		todos = append(todos, Todo{ID: id, Content: content, Status: TodoStatus(status)})
+	}
+	if inProgress > 1 {
+		return Failure(fmt.Errorf("%d items are in_progress; keep at most one", inProgress))
+	}
+
+	session.mu.Lock()
+	session.todos = todos
+	session.mu.Unlock()
+	return Success("todos", todos).WithSummary("%d/%d 完成", done, len(todos))
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/inference"
+)
+
+func writeTodos(todos ...map[string]any) ToolResult {
+	items := make([]any, len(todos))
+	for i, todo := range todos {
+		items[i] = todo
+	}
+	return (&TodoWrite{}).Call(context.Background(), inference.ToolCall{
+		Function: inference.Function{Name: TodoWriteName, Arguments: map[string]any{"todos": items}},
+	})
+}
+
+func TestTodoWrite_replacesSessionList(t *testing.T) {
+	t.Cleanup(ResetSession)
+	got := writeTodos(
+		map[string]any{"id": "1", "content": "读代码", "status": "done"},
+		map[string]any{"id": "2", "content": "改实现", "status": "in_progress"},
+		map[string]any{"id": "3", "content": "跑测试", "status": "pending"},
+	)
+	want := []Todo{{"1", "读代码", TodoDone}, {"2", "改实现", TodoInProgress}, {"3", "跑测试", TodoPending}}
+	if got.Failed() || got.Summary != "1/3 完成" || !reflect.DeepEqual(got.Data["todos"], want) {
+		t.Fatalf("result = %+v", got)
+	}
+	if !reflect.DeepEqual(Todos(), want) {
+		t.Fatalf("Todos() = %+v", Todos())
+	}
+
+	for _, bad := range [][]map[string]any{
+		{{"id": "1", "content": "a", "status": "pending"}, {"id": "1", "content": "b", "status": "pending"}},
+		{{"id": "1", "content": "a", "status": "in_progress"}, {"id": "2", "content": "b", "status": "in_progress"}},
+		{{"id": "1", "content": "", "status": "pending"}},
+	} {
+		if r := writeTodos(bad...); !r.Failed() {
+			t.Fatalf("%v accepted", bad)
+		}
+	}
+	if len(Todos()) != 3 {
+		t.Fatal("a rejected list replaced the Session list")
+	}
+
+	if r := writeTodos(); r.Failed() || len(Todos()) != 0 || !strings.Contains(r.ModelText(), `"todos":[]`) {
+		t.Fatalf("clearing: %s, %v", r.ModelText(), Todos())
+	}
+	writeTodos(map[string]any{"id": "1", "content": "a", "status": "pending"})
+	ResetSession()
+	if Todos() != nil {
+		t.Fatal("the list survived ResetSession")
+	}
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 
 	"github.com/loveRyujin/mini-agent/internal/inference"
 )
@@ -9,21 +10,65 @@ import (
 type Tool interface {
 	Name() string
 	Definition() map[string]any
-	Call(context.Context, inference.ToolCall) map[string]any
+	Call(context.Context, inference.ToolCall) ToolResult
 }
 
+var (
+	ErrToolDenied   = errors.New("tool call denied by user")
+	ErrPolicyDenied = errors.New("tool call denied by the approval policy")
+)
+
 type GatedTool interface {
 	Tool
 	ApprovalSummary(args inference.ToolCall) string
 }
 
+// ConditionalGate is a GatedTool whose harmless calls, such as read-only
+// subcommands, skip the Approval Gate.
+type ConditionalGate interface {
+	GatedTool
+	NeedsApproval(args inference.ToolCall) bool
+}
+
+// NeedsApproval reports whether calling tool with args must pass the
+// Approval Gate.
+func NeedsApproval(tool Tool, args inference.ToolCall) bool {
+	if cg, ok := tool.(ConditionalGate); ok {
+		return cg.NeedsApproval(args)
+	}
+	_, gated := tool.(GatedTool)
+	return gated
+}
+
+// ReadOnlyTool is a Tool whose calls, or some of them, only read the
+// Workspace. Read-only calls of one round may run concurrently.
+type ReadOnlyTool interface {
+	Tool
+	ReadOnly(args inference.ToolCall) bool
+}
+
+// IsReadOnly reports whether calling tool with args only reads.
+func IsReadOnly(tool Tool, args inference.ToolCall) bool {
+	ro, ok := tool.(ReadOnlyTool)
+	return ok && ro.ReadOnly(args)
+}
+
 // Builtin returns all Built-in Tools shipped with the application.
 func Builtin() []Tool {
 	return []Tool{
 		&ReadFile{},
 		&ListFile{},
 		&WriteFile{},
+		&MovePath{},
+		&CopyPath{},
+		&DeletePath{},
+		&RestorePath{},
+		&MakeDir{},
 		&WorkspaceSearch{},
+		&GoSymbols{},
+		&GoTool{},
+		&GitTool{},
 		&RunShell{},
+		&TodoWrite{},
 	}
 }
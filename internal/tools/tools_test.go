@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,12 +42,86 @@ func TestReadFile_withinWorkspace(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "hello") {
 		t.Fatalf("content = %q, want file contents", content)
 	}
 }
 
+func readFileCall(t *testing.T, args map[string]any) string {
+	t.Helper()
+	rf := &ReadFile{}
+	resp := rf.Call(context.Background(), inference.ToolCall{
+		ID:       "call-1",
+		Function: inference.Function{Name: "read_file", Arguments: args},
+	})
+	content := resp.ModelText()
+	return content
+}
+
+func TestReadFile_lineRange(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+
+	var b strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ten.txt"), []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readFileCall(t, map[string]any{"path": "ten.txt", "offset": float64(3), "limit": float64(2), "line_numbers": true})
+	if !strings.Contains(content, `     3\tline 3\n     4\tline 4\n"`) {
+		t.Fatalf("content = %q, want numbered lines 3-4", content)
+	}
+	if !strings.Contains(content, `"total_lines":10`) || !strings.Contains(content, "offset=5") {
+		t.Fatalf("content = %q, want total line count and continuation notice", content)
+	}
+
+	content = readFileCall(t, map[string]any{"path": "ten.txt", "offset": float64(9), "limit": float64(5)})
+	if !strings.Contains(content, `"end_line":10`) || strings.Contains(content, `"notice"`) {
+		t.Fatalf("content = %q, want lines 9-10 without a continuation notice", content)
+	}
+}
+
+func TestReadFile_sizeCap(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+
+	line := strings.Repeat("a", 99) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat(line, 5000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readFileCall(t, map[string]any{"path": "big.txt"})
+	if len(content) > defaultReadMaxBytes+1024 {
+		t.Fatalf("content length = %d, want capped near %d", len(content), defaultReadMaxBytes)
+	}
+	if !strings.Contains(content, `"notice"`) || !strings.Contains(content, "of 5000") {
+		t.Fatalf("expected truncation notice with total lines, got %q", content[len(content)-300:])
+	}
+}
+
+func TestReadFile_rejectsBinary(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "a.out"), []byte{0x7f, 'E', 'L', 'F', 0, 0, 1}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "latin1.txt"), []byte("caf\xe9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if content := readFileCall(t, map[string]any{"path": "a.out"}); !strings.Contains(content, "binary file") {
+		t.Fatalf("content = %q, want binary file error", content)
+	}
+	if content := readFileCall(t, map[string]any{"path": "latin1.txt"}); !strings.Contains(content, "not valid UTF-8") {
+		t.Fatalf("content = %q, want UTF-8 error", content)
+	}
+}
+
 func TestReadFile_rejectsEscape(t *testing.T) {
 	dir := t.TempDir()
 	chdirWorkspace(t, dir)
@@ -60,7 +135,7 @@ func TestReadFile_rejectsEscape(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "FAILED") {
 		t.Fatalf("expected FAILED status, got %q", content)
 	}
@@ -82,7 +157,7 @@ func TestListFile_rejectsEscape(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "FAILED") || !strings.Contains(content, "path escapes workspace") {
 		t.Fatalf("expected escape error, got %q", content)
 	}
@@ -104,7 +179,7 @@ func TestWriteFile_createsFile(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "SUCCESS") {
 		t.Fatalf("expected SUCCESS, got %q", content)
 	}
@@ -139,7 +214,7 @@ func TestWriteFile_overwritesExisting(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "SUCCESS") {
 		t.Fatalf("expected SUCCESS, got %q", content)
 	}
@@ -169,7 +244,7 @@ func TestWriteFile_rejectsEscape(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "FAILED") || !strings.Contains(content, "path escapes workspace") {
 		t.Fatalf("expected escape error, got %q", content)
 	}
@@ -195,7 +270,7 @@ func TestWorkspaceSearch_contentMatch(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "SUCCESS") {
 		t.Fatalf("expected SUCCESS, got %q", content)
 	}
@@ -228,7 +303,7 @@ func TestWorkspaceSearch_filenameMatch(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "SUCCESS") {
 		t.Fatalf("expected SUCCESS, got %q", content)
 	}
@@ -256,7 +331,7 @@ func TestWorkspaceSearch_rejectsEscape(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "FAILED") || !strings.Contains(content, "path escapes workspace") {
 		t.Fatalf("expected escape error, got %q", content)
 	}
@@ -282,8 +357,44 @@ func TestListFile_withinWorkspace(t *testing.T) {
 		},
 	})
 
-	content, _ := resp["content"].(string)
+	content := resp.ModelText()
 	if !strings.Contains(content, "a.txt") {
 		t.Fatalf("content = %q, want a.txt listed", content)
 	}
+	// The list is data, not a JSON string inside the result.
+	if files, ok := resp.Data["files"].([]string); !ok || len(files) != 2 || resp.Summary != "2 项" {
+		t.Fatalf("data = %#v, summary = %q", resp.Data, resp.Summary)
+	}
+	if !strings.Contains(content, `"files":["a.txt","sub/"]`) {
+		t.Fatalf("content = %q, want the files as a JSON array", content)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	call := func(args map[string]any) inference.ToolCall {
+		return inference.ToolCall{Function: inference.Function{Arguments: args}}
+	}
+	cases := []struct {
+		tool Tool
+		args map[string]any
+		want bool
+	}{
+		{&ReadFile{}, nil, true},
+		{&ListFile{}, nil, true},
+		{&WorkspaceSearch{}, nil, true},
+		{&GoSymbols{}, nil, true},
+		{&GitTool{}, map[string]any{"subcommand": "diff"}, true},
+		{&GitTool{}, map[string]any{"subcommand": "stash", "stash_action": "list"}, true},
+		{&GitTool{}, map[string]any{"subcommand": "commit"}, false},
+		{&GoTool{}, map[string]any{"subcommand": "doc"}, true},
+		{&GoTool{}, map[string]any{"subcommand": "test"}, false},
+		{&WriteFile{}, nil, false},
+		{&DeletePath{}, nil, false},
+		{&RunShell{}, nil, false},
+	}
+	for _, c := range cases {
+		if got := IsReadOnly(c.tool, call(c.args)); got != c.want {
+			t.Errorf("IsReadOnly(%s, %v) = %v, want %v", c.tool.Name(), c.args, got, c.want)
+		}
+	}
 }
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// trashEntry is one path removed by delete_path.
+type trashEntry struct {
+	ID        string    `json:"trash_id"`
+	Path      string    `json:"path"`
+	IsDir     bool      `json:"is_dir"`
+	DeletedAt time.Time `json:"deleted_at"`
+	stored    string
+	original  string
+}
+
+// trash keeps deleted paths for the rest of the Session so they can be
+// restored. It lives in a temporary directory outside the Workspace.
+type trash struct {
+	dir     string
+	nextID  int
+	entries map[string]*trashEntry
+}
+
+func (t *trash) put(abs, rel string, isDir bool) (*trashEntry, error) {
+	if t.dir == "" {
+		dir, err := os.MkdirTemp("", "mini-agent-trash-")
+		if err != nil {
+			return nil, err
+		}
+		t.dir = dir
+		t.entries = make(map[string]*trashEntry)
+	}
+	t.nextID++
+	e := &trashEntry{
+		ID:        fmt.Sprintf("trash-%d", t.nextID),
+		Path:      filepath.ToSlash(rel),
+		IsDir:     isDir,
+		DeletedAt: time.Now(),
+		original:  abs,
+	}
+	e.stored = filepath.Join(t.dir, e.ID)
+	if err := movePath(abs, e.stored); err != nil {
+		return nil, err
+	}
+	t.entries[e.ID] = e
+	return e, nil
+}
+
+func (t *trash) take(id, dest string) (*trashEntry, error) {
+	e, ok := t.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("no trash entry %q in this session", id)
+	}
+	if dest == "" {
+		dest = e.original
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return nil, fmt.Errorf("%s already exists", e.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, err
+	}
+	if err := movePath(e.stored, dest); err != nil {
+		return nil, err
+	}
+	delete(t.entries, id)
+	return e, nil
+}
+
+func (t *trash) discard() {
+	if t.dir != "" {
+		_ = os.RemoveAll(t.dir)
+	}
+	*t = trash{}
+}
+
+// movePath renames src to dst, falling back to copy and remove when they
+// are on different filesystems. The copy is not tracked; callers move the
+// stamps with retrackTree or drop them with forgetTree.
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if _, lerr := os.Lstat(dst); lerr == nil {
+		return err
+	}
+	if cerr := copyTree(src, dst, false); cerr != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file or directory verbatim, keeping permission bits.
+// With track, the copied files are tracked like files the agent wrote.
+func copyTree(src, dst string, track bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			_, err = writeFileWith(target, data, writeOptions{Mode: info.Mode().Perm(), Verbatim: true, Untracked: !track})
+			return err
+		default:
+			return nil
+		}
+	})
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -88,3 +89,38 @@ func validateWithinWorkspace(abs string) (string, error) {
 	}
 	return abs, nil
 }
+
+// resolveWorkspaceTarget follows the symlinks of path and refuses a path in
+// the Workspace that leads outside it. For a file that does not exist yet,
+// the nearest existing directory decides. Paths outside the Workspace, such
+// as the Session trash, are only resolved.
+func resolveWorkspaceTarget(path string) (string, error) {
+	if _, err := validateWithinWorkspace(path); err != nil {
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			return target, nil
+		}
+		return path, nil
+	}
+	root, err := filepath.EvalSymlinks(workspaceDir)
+	if err != nil {
+		return "", err
+	}
+	existing := path
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if errors.Is(err, fs.ErrNotExist) && filepath.Dir(existing) != existing {
+			existing = filepath.Dir(existing)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", errors.New("path resolves through a symlink to outside the workspace")
+		}
+		if existing == path {
+			return resolved, nil
+		}
+		return path, nil
+	}
+}
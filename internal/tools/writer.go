@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultFileMode fs.FileMode = 0o644
+
+// writeReport describes what writeWorkspaceFile did beyond copying bytes.
+type writeReport struct {
+	Created    bool
+	Normalized []string
+}
+
+// writeWorkspaceFile is the shared writer behind every File Mutation. It
+// writes content to a temporary file next to path and renames it over the
+// target, so readers never observe a partial file. An existing file keeps
+// its permission bits, ownership, line-ending style and trailing-newline
+// convention; any adjustment to content is listed in the report.
+func writeWorkspaceFile(path string, content []byte) (writeReport, error) {
+	return writeFileWith(path, content, writeOptions{})
+}
+
+type writeOptions struct {
+	// Mode, when non-zero, replaces the existing or default permissions.
+	Mode fs.FileMode
+	// Verbatim skips line-ending and trailing-newline matching.
+	Verbatim bool
+	// Untracked leaves the Session's stamps alone.
+	Untracked bool
+}
+
+func writeFileWith(path string, content []byte, opts writeOptions) (writeReport, error) {
+	var report writeReport
+
+	path, err := resolveWorkspaceTarget(path)
+	if err != nil {
+		return report, err
+	}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		report.Created = true
+	case err != nil:
+		return report, err
+	case info.IsDir():
+		return report, errors.New("path is a directory")
+	}
+
+	mode := defaultFileMode
+	if !report.Created {
+		mode = info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	}
+	if opts.Mode != 0 {
+		mode = opts.Mode
+	}
+	if !report.Created && !opts.Verbatim {
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return report, err
+		}
+		content, report.Normalized = matchTextStyle(old, content)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return report, err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return report, err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if tmpName != "" {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return report, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return report, err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return report, err
+	}
+	if !report.Created {
+		preserveOwner(tmp, info)
+	}
+	if err := tmp.Close(); err != nil {
+		return report, err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return report, err
+	}
+	tmpName = ""
+
+	if !opts.Untracked {
+		recordFile(path, sha256.Sum256(content))
+	}
+	return report, nil
+}
+
+// matchTextStyle adapts content to the line endings and trailing newline of
+// the file it replaces. Binary-looking or empty originals are left alone.
+func matchTextStyle(old, content []byte) ([]byte, []string) {
+	if len(old) == 0 || len(content) == 0 || bytes.IndexByte(old, 0) >= 0 {
+		return content, nil
+	}
+	var notes []string
+
+	oldCRLF := bytes.Count(old, []byte("\r\n"))
+	oldLF := bytes.Count(old, []byte("\n")) - oldCRLF
+	newCRLF := bytes.Count(content, []byte("\r\n"))
+	newLF := bytes.Count(content, []byte("\n")) - newCRLF
+	switch {
+	case oldCRLF > 0 && oldLF == 0 && newLF > 0:
+		content = bytes.ReplaceAll(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+		notes = append(notes, "converted line endings to CRLF to match the existing file")
+	case oldLF > 0 && oldCRLF == 0 && newCRLF > 0:
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		notes = append(notes, "converted line endings to LF to match the existing file")
+	}
+
+	eol := []byte("\n")
+	if oldCRLF > 0 && oldLF == 0 {
+		eol = []byte("\r\n")
+	}
+	oldHasEOL := bytes.HasSuffix(old, []byte("\n"))
+	newHasEOL := bytes.HasSuffix(content, []byte("\n"))
+	switch {
+	case oldHasEOL && !newHasEOL:
+		content = append(content, eol...)
+		notes = append(notes, "added the trailing newline the existing file had")
+	case !oldHasEOL && newHasEOL:
+		content = bytes.TrimSuffix(content, []byte("\n"))
+		content = bytes.TrimSuffix(content, []byte("\r"))
+		notes = append(notes, "removed the trailing newline the existing file did not have")
+	}
+	return content, notes
+}
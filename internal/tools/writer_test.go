@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteWorkspaceFile_preservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho old\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := writeWorkspaceFile(path, []byte("#!/bin/sh\necho new\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("mode = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestWriteWorkspaceFile_noTempFilesLeft(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "new.txt")
+
+	report, err := writeWorkspaceFile(path, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Created {
+		t.Fatal("expected Created for a new file")
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "new.txt" {
+		t.Fatalf("directory entries = %v, want only new.txt", entries)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != defaultFileMode {
+		t.Fatalf("mode = %v, want %v", info.Mode().Perm(), defaultFileMode)
+	}
+}
+
+func TestWriteWorkspaceFile_writesThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(target, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	if _, err := writeWorkspaceFile(link, []byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Lstat(link); fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("symlink was replaced by a regular file")
+	}
+	if got, _ := os.ReadFile(target); string(got) != "new\n" {
+		t.Fatalf("target content = %q", got)
+	}
+}
+
+func TestWriteWorkspaceFile_refusesSymlinkOutsideWorkspace(t *testing.T) {
+	root := t.TempDir()
+	SetWorkspaceRootForTest(root)
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "bashrc")
+	if err := os.WriteFile(secret, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rel := range []string{"link.txt", "dir/bashrc", "dir/new/file.txt"} {
+		if _, err := writeWorkspaceFile(filepath.Join(root, rel), []byte("pwned\n")); err == nil {
+			t.Errorf("%s: write succeeded", rel)
+		}
+	}
+	if got, _ := os.ReadFile(secret); string(got) != "old\n" {
+		t.Fatalf("file outside the workspace = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new")); err == nil {
+		t.Fatal("directory created outside the workspace")
+	}
+}
+
+func TestMatchTextStyle(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  string
+		want      string
+		wantNotes int
+	}{
+		{"unchanged LF", "a\nb\n", "c\nd\n", "c\nd\n", 0},
+		{"keeps CRLF", "a\r\nb\r\n", "c\nd\n", "c\r\nd\r\n", 1},
+		{"keeps LF", "a\nb\n", "c\r\nd\r\n", "c\nd\n", 1},
+		{"adds trailing newline", "a\n", "b", "b\n", 1},
+		{"adds trailing CRLF", "a\r\nb\r\n", "c\r\nd", "c\r\nd\r\n", 1},
+		{"keeps missing trailing newline", "a", "b\n", "b", 1},
+		{"CRLF and newline", "a\r\nb", "c\nd\n", "c\r\nd", 2},
+		{"mixed original untouched", "a\r\nb\n", "c\nd\n", "c\nd\n", 0},
+		{"empty original", "", "x", "x", 0},
+	}
+	for _, tt := range tests {
+		got, notes := matchTextStyle([]byte(tt.old), []byte(tt.new))
+		if string(got) != tt.want || len(notes) != tt.wantNotes {
+			t.Errorf("%s: got %q with notes %v, want %q with %d notes", tt.name, got, notes, tt.want, tt.wantNotes)
+		}
+	}
+}
+
+func TestWriteFile_reportsNormalization(t *testing.T) {
+	dir := t.TempDir()
+	chdirWorkspace(t, dir)
+	withWritePolicy(t, DefaultWritePolicy)
+
+	if err := os.WriteFile(filepath.Join(dir, "win.txt"), []byte("a\r\nb\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := writeFileCall(t, "win.txt", "x\ny\n")
+	if !strings.Contains(out, "converted line endings to CRLF") {
+		t.Fatalf("tool result = %q, want normalization note", out)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "win.txt"))
+	if !reflect.DeepEqual(got, []byte("x\r\ny\r\n")) {
+		t.Fatalf("file content = %q", got)
+	}
+}
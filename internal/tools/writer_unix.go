@@ -0,0 +1,19 @@
+//go:build unix
+
+package tools
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives f the owner and group of the file it replaces. It is
+// best-effort: an unprivileged process can usually only keep its own uid.
+func preserveOwner(f *os.File, orig fs.FileInfo) {
+	st, ok := orig.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	_ = f.Chown(int(st.Uid), int(st.Gid))
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/tools"
 )
 
 const responseContextHeight = 10
@@ -39,6 +41,16 @@ func renderCrush(entries []Entry, opts RenderOpts) string {
 			block = lipgloss.NewStyle().Foreground(opts.Theme.Dim).PaddingLeft(2).Render(e.Text)
 		case EntrySystem:
 			block = lipgloss.NewStyle().Foreground(opts.Theme.Dim).PaddingLeft(2).Render(e.Text)
+		case EntryVerify:
+			block = crushVerifyBlock(i, e, opts)
+		case EntryReview:
+			block = crushReviewBlock(i, e, opts)
+		case EntryDiff:
+			block = crushDiffBlock(i, e, opts)
+		case EntryTask:
+			block = crushTaskBlock(i, e, opts)
+		case EntryTodos:
+			block = lipgloss.NewStyle().PaddingLeft(2).Render(RenderTodos(e.Todos, e.Meta, opts.Theme))
 		}
 		if block != "" {
 			blocks = append(blocks, renderBlockFocus(i, block, opts))
@@ -80,7 +92,9 @@ func crushLeftBar(color lipgloss.Color, text string, dim bool) string {
 	return strings.Join(lines, "\n")
 }
 
-func crushToolHeaderEntry(e Entry, t Theme) string {
+// crushToolHeaderEntry renders the call line; once result is known it
+// shows whether the call failed, and the result's Summary.
+func crushToolHeaderEntry(e Entry, result *Entry, t Theme) string {
 	name := e.ToolName
 	param := e.Meta
 	if name == "" {
@@ -89,17 +103,25 @@ func crushToolHeaderEntry(e Entry, t Theme) string {
 	if param == "" {
 		param = e.Text
 	}
-	line := lipgloss.NewStyle().Foreground(t.Tool).Render("✓ "+name) + " " +
+	icon, color := "✓", t.Tool
+	if result != nil && result.Failed {
+		icon, color = "✗", t.Error
+	}
+	line := lipgloss.NewStyle().Foreground(color).Render(icon+" "+name) + " " +
 		lipgloss.NewStyle().Foreground(t.Dim).Render(param)
+	if result != nil && result.Meta != "" {
+		line += lipgloss.NewStyle().Foreground(t.Dim).Render(" · " + result.Meta)
+	}
 	return lipgloss.NewStyle().PaddingLeft(2).Render(line)
 }
 
 func renderCrushToolBlockEntry(callIdx int, entries []Entry, opts RenderOpts) string {
-	header := crushToolHeaderEntry(entries[callIdx], opts.Theme)
 	if !HasPairedToolResult(entries, callIdx) {
-		return header
+		return crushToolHeaderEntry(entries[callIdx], nil, opts.Theme)
 	}
-	body := crushToolBodyEntry(entries[callIdx+1], opts.Expanded[callIdx], opts.Theme)
+	result := entries[callIdx+1]
+	header := crushToolHeaderEntry(entries[callIdx], &result, opts.Theme)
+	body := crushToolBodyEntry(result, opts.Expanded[callIdx], opts.Theme)
 	return header + "\n" + body
 }
 
@@ -124,6 +146,194 @@ func crushToolBodyEntry(result Entry, expanded bool, t Theme) string {
 	return strings.Join(out, "\n")
 }
 
+func crushVerifyBlock(idx int, e Entry, opts RenderOpts) string {
+	t := opts.Theme
+	icon, status, color := "⟳", "运行中", t.Tool
+	switch {
+	case e.Done && e.ExitCode == 0:
+		icon, status = "✓", "通过"
+	case e.Done:
+		icon, status, color = "✗", fmt.Sprintf("失败（退出码 %d）", e.ExitCode), t.Error
+	}
+	line := lipgloss.NewStyle().Foreground(color).Render(fmt.Sprintf("%s 验证 #%d %s", icon, e.Attempt, status)) + " " +
+		lipgloss.NewStyle().Foreground(t.Dim).Render(e.Meta)
+	header := lipgloss.NewStyle().PaddingLeft(2).Render(line)
+	if e.Text == "" {
+		return header
+	}
+	return header + "\n" + crushToolBodyEntry(e, opts.Expanded[idx], t)
+}
+
+// collapsedReviewFindings is how many findings a review block shows until
+// it is expanded.
+const collapsedReviewFindings = 5
+
+func crushReviewBlock(idx int, e Entry, opts RenderOpts) string {
+	t := opts.Theme
+	r := e.Review
+	pad := lipgloss.NewStyle().PaddingLeft(2)
+	dim := lipgloss.NewStyle().Foreground(t.Dim)
+
+	summary := fmt.Sprintf("%d 个文件 · %d 个问题", len(r.Files), len(r.Findings))
+	icon, color := "◆", t.Tool
+	if len(r.Findings) == 0 {
+		icon, summary = "✓", fmt.Sprintf("%d 个文件 · 未发现问题", len(r.Files))
+	}
+	out := []string{pad.Render(lipgloss.NewStyle().Foreground(color).Render(icon+" 代码审查 "+r.Ref) + " " + dim.Render(summary))}
+
+	shown := r.Findings
+	if !opts.Expanded[idx] && len(shown) > collapsedReviewFindings {
+		shown = shown[:collapsedReviewFindings]
+	}
+	for i, f := range shown {
+		sev := lipgloss.NewStyle().Bold(true).Foreground(severityColor(f.Severity, t)).Render(severityLabel(f.Severity))
+		out = append(out,
+			pad.Render(fmt.Sprintf("#%d %s  ", i+1, sev)+lipgloss.NewStyle().Foreground(t.Agent).Render(f.Anchor())+"  "+f.Message),
+			pad.Render(dim.Render("    建议："+f.Suggestion)))
+	}
+	if hidden := len(r.Findings) - len(shown); hidden > 0 {
+		out = append(out, pad.Render(dim.Render(fmt.Sprintf("… 另有 %d 个问题 [Ctrl+T 对话区 → e 展开]", hidden))))
+	}
+	if r.Skipped > 0 {
+		out = append(out, pad.Render(lipgloss.NewStyle().Foreground(t.Error).Render(
+			fmt.Sprintf("有 %d 组文件的审查结果无法解析，未包含在内", r.Skipped))))
+	}
+	if len(r.Findings) > 0 {
+		out = append(out, pad.Render(dim.Render("输入 /fix N 让 Agent 应用第 N 条建议，/fix 应用全部")))
+	}
+	return strings.Join(out, "\n")
+}
+
+// collapsedDiffLines is how many diff lines a diff block shows until it is
+// expanded.
+const collapsedDiffLines = 20
+
+func crushDiffBlock(idx int, e Entry, opts RenderOpts) string {
+	t := opts.Theme
+	pad := lipgloss.NewStyle().PaddingLeft(2)
+	out := []string{pad.Render(lipgloss.NewStyle().Foreground(t.Tool).Render("◆ " + e.Meta))}
+	lines := strings.Split(e.Text, "\n")
+	hidden := 0
+	if !opts.Expanded[idx] && len(lines) > collapsedDiffLines {
+		hidden = len(lines) - collapsedDiffLines
+		lines = lines[:collapsedDiffLines]
+	}
+	for _, ln := range lines {
+		color := t.Dim
+		switch {
+		case strings.HasPrefix(ln, "+++"), strings.HasPrefix(ln, "---"), strings.HasPrefix(ln, "diff --git"):
+			color = t.Agent
+		case strings.HasPrefix(ln, "+"):
+			color = t.User
+		case strings.HasPrefix(ln, "-"):
+			color = t.Error
+		case strings.HasPrefix(ln, "@@"):
+			color = t.Tool
+		}
+		out = append(out, pad.Render(lipgloss.NewStyle().Foreground(color).Render(ln)))
+	}
+	if hidden > 0 {
+		out = append(out, pad.Render(lipgloss.NewStyle().Foreground(t.Dim).Render(fmt.Sprintf(truncateFmt, hidden))))
+	}
+	return strings.Join(out, "\n")
+}
+
+// collapsedTaskLines is how many steps, and lines of the report, a task
+// block shows until it is expanded.
+const collapsedTaskLines = 3
+
+func crushTaskBlock(idx int, e Entry, opts RenderOpts) string {
+	t := opts.Theme
+	pad := lipgloss.NewStyle().PaddingLeft(2)
+	nested := lipgloss.NewStyle().PaddingLeft(4)
+	dim := lipgloss.NewStyle().Foreground(t.Dim)
+	expanded := opts.Expanded[idx]
+
+	icon, color := "⟳", t.Tool
+	switch {
+	case e.Failed:
+		icon, color = "✗", t.Error
+	case e.Done:
+		icon = "✓"
+	}
+	out := []string{pad.Render(lipgloss.NewStyle().Foreground(color).Render(icon+" 子任务 "+e.Meta) + " " +
+		dim.Render(fmt.Sprintf("%d 次工具调用", len(e.Steps))))}
+
+	steps := e.Steps
+	if !expanded && len(steps) > collapsedTaskLines {
+		out = append(out, nested.Render(dim.Render(fmt.Sprintf("… 前 %d 步已折叠", len(steps)-collapsedTaskLines))))
+		steps = steps[len(steps)-collapsedTaskLines:]
+	}
+	for _, s := range steps {
+		if s.Kind == EntryError {
+			out = append(out, nested.Render(lipgloss.NewStyle().Foreground(t.Error).Render("错误: "+s.Text)))
+			continue
+		}
+		out = append(out, nested.Render(lipgloss.NewStyle().Foreground(t.Tool).Render("↳ "+s.ToolName)+" "+dim.Render(s.Meta)))
+	}
+	if !e.Done {
+		return strings.Join(out, "\n")
+	}
+
+	if e.Failed {
+		out = append(out, nested.Render(lipgloss.NewStyle().Foreground(t.Error).Render("失败: "+e.Text)))
+		return strings.Join(out, "\n")
+	}
+	report := strings.Split(e.Text, "\n")
+	hidden := 0
+	if !expanded && len(report) > collapsedTaskLines {
+		hidden = len(report) - collapsedTaskLines
+		report = report[:collapsedTaskLines]
+	}
+	for _, ln := range report {
+		out = append(out, nested.Render(crushLeftBar(t.Agent, ln, true)))
+	}
+	if hidden > 0 {
+		out = append(out, nested.Render(dim.Render(fmt.Sprintf(truncateFmt, hidden))))
+	}
+	return strings.Join(out, "\n")
+}
+
+// RenderTodos renders a task list as a checklist under a header carrying
+// summary.
+func RenderTodos(todos []tools.Todo, summary string, t Theme) string {
+	dim := lipgloss.NewStyle().Foreground(t.Dim)
+	out := []string{lipgloss.NewStyle().Foreground(t.Tool).Render("☰ 任务清单") + " " + dim.Render(summary)}
+	for _, todo := range todos {
+		line := TodoMark(todo.Status) + " " + todo.Content
+		switch todo.Status {
+		case tools.TodoDone:
+			line = dim.Strikethrough(true).Render(line)
+		case tools.TodoInProgress:
+			line = lipgloss.NewStyle().Foreground(t.Gold).Bold(true).Render(line)
+		}
+		out = append(out, "  "+line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func severityLabel(severity string) string {
+	switch severity {
+	case agent.SeverityHigh:
+		return "高"
+	case agent.SeverityMedium:
+		return "中"
+	default:
+		return "低"
+	}
+}
+
+func severityColor(severity string, t Theme) lipgloss.Color {
+	switch severity {
+	case agent.SeverityHigh:
+		return t.Error
+	case agent.SeverityMedium:
+		return t.Gold
+	default:
+		return t.Dim
+	}
+}
+
 func crushThinkingBlock(idx int, e Entry, opts RenderOpts) string {
 	t := opts.Theme
 	lines := strings.Split(e.Text, "\n")
@@ -1,10 +1,14 @@
 package transcript
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/loveRyujin/mini-agent/internal/agent"
 	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
 )
 
 type EntryKind int
@@ -19,6 +23,11 @@ const (
 	EntryToolResult
 	EntryApproval
 	EntrySystem
+	EntryVerify
+	EntryReview
+	EntryDiff
+	EntryTask
+	EntryTodos
 )
 
 const noStreaming EntryKind = -1
@@ -28,6 +37,26 @@ type Entry struct {
 	Text     string
 	Meta     string
 	ToolName string
+
+	// Attempt, ExitCode and Done describe an EntryVerify run.
+	Attempt  int
+	ExitCode int
+	Done     bool
+
+	// Review holds the result shown by an EntryReview.
+	Review *agent.Review
+
+	// TaskID and Steps describe an EntryTask: the delegate_task call and
+	// the tool calls its sub-agent made. Text holds the task until Done,
+	// then the report, or the error when Failed.
+	TaskID string
+	Steps  []Entry
+	// Failed also marks a failed EntryToolResult, whose Meta is the
+	// result's Summary.
+	Failed bool
+
+	// Todos is the task list shown by an EntryTodos.
+	Todos []tools.Todo
 }
 
 type Transcript struct {
@@ -48,12 +77,34 @@ func (t *Transcript) AddSystemMessage(text string) {
 	t.entries = append(t.entries, Entry{Kind: EntrySystem, Text: text})
 }
 
+// AddReview appends the findings of a /review. Text holds a plain copy for
+// the clipboard.
+func (t *Transcript) AddReview(r agent.Review) {
+	t.endStreaming()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review of changes against %s\n", r.Ref)
+	for i, f := range r.Findings {
+		fmt.Fprintf(&b, "\n#%d [%s] %s\n%s\nSuggested fix: %s\n", i+1, f.Severity, f.Anchor(), f.Message, f.Suggestion)
+	}
+	t.entries = append(t.entries, Entry{Kind: EntryReview, Text: strings.TrimSpace(b.String()), Meta: r.Ref, Review: &r})
+}
+
+// AddDiff appends a unified diff under title.
+func (t *Transcript) AddDiff(title, diff string) {
+	t.endStreaming()
+	t.entries = append(t.entries, Entry{Kind: EntryDiff, Text: strings.TrimRight(diff, "\n"), Meta: title})
+}
+
 func (t *Transcript) Reset() {
 	t.entries = nil
 	t.streaming = noStreaming
 }
 
 func (t *Transcript) Apply(e agent.Event) {
+	if e.TaskID != "" {
+		t.applyTaskEvent(e)
+		return
+	}
 	switch e.Kind {
 	case agent.EventReasoningDelta:
 		t.appendStreaming(EntryReasoning, e.Text)
@@ -61,6 +112,10 @@ func (t *Transcript) Apply(e agent.Event) {
 		t.appendStreaming(EntryAnswer, e.Text)
 	case agent.EventToolCall:
 		t.endStreaming()
+		if e.ToolName == agent.DelegateToolName {
+			t.entries = append(t.entries, newTaskEntry(e))
+			break
+		}
 		t.entries = append(t.entries, Entry{
 			Kind:     EntryToolCall,
 			Text:     fmt.Sprintf("%s(%v)", e.ToolName, e.ToolArguments),
@@ -72,7 +127,25 @@ func (t *Transcript) Apply(e agent.Event) {
 		t.entries = append(t.entries, Entry{Kind: EntryApproval, Text: e.Command})
 	case agent.EventToolResult:
 		t.endStreaming()
-		t.entries = append(t.entries, Entry{Kind: EntryToolResult, Text: e.ToolContent})
+		if e.ToolName == agent.DelegateToolName {
+			t.finishTask(e)
+			break
+		}
+		if e.ToolName == tools.TodoWriteName && t.updateTodos(e) {
+			break
+		}
+		t.entries = append(t.entries, Entry{
+			Kind:   EntryToolResult,
+			Text:   e.ToolResult.ModelText(),
+			Meta:   e.ToolResult.Summary,
+			Failed: e.ToolResult.Failed(),
+		})
+	case agent.EventVerifyStart:
+		t.endStreaming()
+		t.entries = append(t.entries, Entry{Kind: EntryVerify, Meta: e.Command, Attempt: e.Attempt})
+	case agent.EventVerifyResult:
+		t.endStreaming()
+		t.finishVerify(e)
 	case agent.EventTurnComplete:
 		t.endStreaming()
 	case agent.EventUsage:
@@ -88,16 +161,145 @@ func (t *Transcript) Apply(e agent.Event) {
 	}
 }
 
+// finishVerify records the outcome on the block opened by EventVerifyStart.
+func (t *Transcript) finishVerify(e agent.Event) {
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		entry := &t.entries[i]
+		if entry.Kind == EntryVerify && entry.Attempt == e.Attempt && !entry.Done {
+			entry.Text, entry.ExitCode, entry.Done = e.Text, e.ExitCode, true
+			return
+		}
+	}
+	t.entries = append(t.entries, Entry{
+		Kind: EntryVerify, Meta: e.Command, Text: e.Text,
+		Attempt: e.Attempt, ExitCode: e.ExitCode, Done: true,
+	})
+}
+
+// updateTodos turns the todo_write call that produced e into a checklist
+// when the task list changed. It reports whether it did.
+func (t *Transcript) updateTodos(e agent.Event) bool {
+	todos, ok := e.ToolResult.Data["todos"].([]tools.Todo)
+	n := len(t.entries)
+	if !ok || e.ToolResult.Failed() || n == 0 ||
+		t.entries[n-1].Kind != EntryToolCall || t.entries[n-1].ToolName != tools.TodoWriteName {
+		return false
+	}
+	for i := n - 2; i >= 0; i-- {
+		if t.entries[i].Kind == EntryTodos {
+			if slices.Equal(t.entries[i].Todos, todos) {
+				return false
+			}
+			break
+		}
+	}
+	t.entries[n-1] = Entry{Kind: EntryTodos, ToolName: tools.TodoWriteName, Text: FormatTodos(todos), Meta: e.ToolResult.Summary, Todos: todos}
+	return true
+}
+
+// FormatTodos renders a task list as plain text.
+func FormatTodos(todos []tools.Todo) string {
+	lines := make([]string, len(todos))
+	for i, todo := range todos {
+		lines[i] = TodoMark(todo.Status) + " " + todo.Content
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TodoMark is the checkbox shown for a task list item.
+func TodoMark(status tools.TodoStatus) string {
+	switch status {
+	case tools.TodoDone:
+		return "☑"
+	case tools.TodoInProgress:
+		return "◐"
+	default:
+		return "☐"
+	}
+}
+
+// maxTaskLabel bounds the label of a task block taken from the task itself.
+const maxTaskLabel = 60
+
+func newTaskEntry(e agent.Event) Entry {
+	task, _ := e.ToolArguments["task"].(string)
+	label, _ := e.ToolArguments["description"].(string)
+	if label = strings.TrimSpace(label); label == "" {
+		label, _, _ = strings.Cut(strings.TrimSpace(task), "\n")
+		if r := []rune(label); len(r) > maxTaskLabel {
+			label = string(r[:maxTaskLabel]) + "…"
+		}
+	}
+	return Entry{Kind: EntryTask, Text: task, Meta: label, TaskID: e.ToolCallID}
+}
+
+func (t *Transcript) task(id string) *Entry {
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		if t.entries[i].Kind == EntryTask && t.entries[i].TaskID == id {
+			return &t.entries[i]
+		}
+	}
+	return nil
+}
+
+// applyTaskEvent records the progress of a delegated task on its block.
+// Only tool calls and errors are kept; the answer arrives as the report.
+func (t *Transcript) applyTaskEvent(e agent.Event) {
+	task := t.task(e.TaskID)
+	if task == nil {
+		return
+	}
+	switch e.Kind {
+	case agent.EventToolCall:
+		task.Steps = append(task.Steps, Entry{
+			Kind:     EntryToolCall,
+			ToolName: e.ToolName,
+			Meta:     formatToolMeta(e.ToolName, e.ToolArguments),
+		})
+	case agent.EventError:
+		if e.Err != nil {
+			task.Steps = append(task.Steps, Entry{Kind: EntryError, Text: e.Err.Error()})
+		}
+	}
+}
+
+func (t *Transcript) finishTask(e agent.Event) {
+	task := t.task(e.ToolCallID)
+	if task == nil {
+		return
+	}
+	task.Done = true
+	if r := e.ToolResult; !r.Failed() {
+		task.Text, _ = r.Data["report"].(string)
+		return
+	}
+	task.Failed = true
+	task.Text = cmp.Or(e.ToolResult.Error(), e.ToolResult.ModelText())
+}
+
 func formatToolMeta(name string, args map[string]any) string {
 	if command, ok := args["command"].(string); ok && command != "" {
 		return command
 	}
+	if src, ok := args["source"].(string); ok && src != "" {
+		dst, _ := args["destination"].(string)
+		return src + " → " + dst
+	}
 	if path, ok := args["path"].(string); ok && path != "" {
+		if line, ok := args["line"].(float64); ok {
+			return fmt.Sprintf("%s:%d", path, int(line))
+		}
 		return path
 	}
+	if name, ok := args["name"].(string); ok && name != "" {
+		return name
+	}
 	if pattern, ok := args["pattern"].(string); ok && pattern != "" {
 		return pattern
 	}
+	if question, ok := args["question"].(string); ok && question != "" {
+		return question
+	}
 	return ""
 }
 
@@ -161,7 +363,7 @@ func IsFocusable(entries []Entry, idx int) bool {
 		return false
 	}
 	switch entries[idx].Kind {
-	case EntryReasoning, EntryToolCall:
+	case EntryReasoning, EntryToolCall, EntryVerify, EntryReview, EntryDiff, EntryTask:
 		return true
 	default:
 		return false
@@ -1,11 +1,15 @@
 package transcript
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/loveRyujin/mini-agent/internal/agent"
 	"github.com/loveRyujin/mini-agent/internal/inference"
+	"github.com/loveRyujin/mini-agent/internal/tools"
 )
 
 func TestTranscript_conversationStream(t *testing.T) {
@@ -34,9 +38,9 @@ func TestTranscript_toolCallOrder(t *testing.T) {
 	tr := New()
 	tr.AddUserMessage("read files")
 	tr.Apply(agent.Event{Kind: agent.EventToolCall, ToolName: "read_file", ToolArguments: map[string]any{"path": "main.go"}})
-	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: "read_file", ToolContent: `{"status":"SUCCESS","data":{"file_content":"package main"}}`})
+	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: "read_file", ToolResult: tools.Success("file_content", "package main")})
 	tr.Apply(agent.Event{Kind: agent.EventToolCall, ToolName: "list_file", ToolArguments: map[string]any{"path": "."}})
-	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: "list_file", ToolContent: `{"status":"SUCCESS","data":{"files":["main.go"]}}`})
+	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: "list_file", ToolResult: tools.Success("files", []string{"main.go"})})
 	tr.Apply(agent.Event{Kind: agent.EventAnswerDelta, Text: "done"})
 	tr.Apply(agent.Event{Kind: agent.EventTurnComplete})
 
@@ -67,3 +71,129 @@ var errTest = &testError{msg: "backend failed"}
 type testError struct{ msg string }
 
 func (e *testError) Error() string { return e.msg }
+
+func TestTranscript_verifyAttemptsAreSeparateBlocks(t *testing.T) {
+	tr := New()
+	tr.AddUserMessage("fix it")
+	for attempt, exit := range []int{1, 0} {
+		tr.Apply(agent.Event{Kind: agent.EventVerifyStart, Command: "go test ./...", Attempt: attempt + 1})
+		tr.Apply(agent.Event{Kind: agent.EventVerifyResult, Command: "go test ./...", Attempt: attempt + 1, ExitCode: exit, Text: "output"})
+	}
+
+	got := tr.EntryKinds()
+	want := []EntryKind{EntryUser, EntryVerify, EntryVerify}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("entry kinds:\n got: %v\nwant: %v", got, want)
+	}
+	first, second := tr.Entries()[1], tr.Entries()[2]
+	if !first.Done || first.ExitCode != 1 || first.Attempt != 1 || second.ExitCode != 0 || second.Attempt != 2 {
+		t.Fatalf("verify entries = %+v, %+v", first, second)
+	}
+	out := tr.Render(RenderOpts{})
+	for _, want := range []string{"验证 #1 失败（退出码 1）", "验证 #2 通过", "go test ./..."} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("render missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranscript_reviewCollapsesFindings(t *testing.T) {
+	var findings []agent.ReviewFinding
+	for i := range collapsedReviewFindings + 2 {
+		findings = append(findings, agent.ReviewFinding{
+			File: "a.go", Line: i + 1, Severity: agent.SeverityHigh,
+			Message: fmt.Sprintf("problem %d", i+1), Suggestion: "fix",
+		})
+	}
+	tr := New()
+	tr.AddReview(agent.Review{Ref: "main", Findings: findings})
+	if !IsFocusable(tr.Entries(), 0) {
+		t.Fatal("review entry should be focusable")
+	}
+
+	collapsed := tr.Render(RenderOpts{})
+	for _, want := range []string{"代码审查 main", "#1 高", "a.go:1", "建议：fix", "另有 2 个问题", "/fix N"} {
+		if !strings.Contains(collapsed, want) {
+			t.Fatalf("render missing %q:\n%s", want, collapsed)
+		}
+	}
+	if strings.Contains(collapsed, "problem 7") {
+		t.Fatal("collapsed review shows every finding")
+	}
+	if expanded := tr.Render(RenderOpts{Expanded: map[int]bool{0: true}}); !strings.Contains(expanded, "problem 7") {
+		t.Fatalf("expanded review:\n%s", expanded)
+	}
+	if text := tr.EntryText(0); !strings.Contains(text, "#7 [high] a.go:7") {
+		t.Fatalf("copy text = %q", text)
+	}
+}
+
+func TestTranscript_delegatedTasksNestUnderTheirCall(t *testing.T) {
+	tr := New()
+	for _, id := range []string{"t1", "t2"} {
+		tr.Apply(agent.Event{Kind: agent.EventToolCall, ToolCallID: id, ToolName: agent.DelegateToolName,
+			ToolArguments: map[string]any{"task": "find callers of " + id + "\nin detail"}})
+	}
+	// Progress of concurrent tasks interleaves.
+	for i := range collapsedTaskLines + 1 {
+		for _, id := range []string{"t2", "t1"} {
+			tr.Apply(agent.Event{Kind: agent.EventToolCall, TaskID: id, ToolName: "read_file",
+				ToolArguments: map[string]any{"path": fmt.Sprintf("%s-%d.go", id, i)}})
+			tr.Apply(agent.Event{Kind: agent.EventToolResult, TaskID: id, ToolName: "read_file", ToolResult: tools.Success()})
+		}
+	}
+	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolCallID: "t1", ToolName: agent.DelegateToolName,
+		ToolResult: tools.Success("report", "t1 has 2 callers")})
+	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolCallID: "t2", ToolName: agent.DelegateToolName,
+		ToolResult: tools.Failure(errors.New("tool loop limit exceeded"))})
+
+	if kinds := tr.EntryKinds(); len(kinds) != 2 || kinds[0] != EntryTask || kinds[1] != EntryTask {
+		t.Fatalf("entry kinds = %v", kinds)
+	}
+	if steps := tr.Entries()[0].Steps; len(steps) != collapsedTaskLines+1 || steps[0].Meta != "t1-0.go" {
+		t.Fatalf("t1 steps = %+v", steps)
+	}
+	if tr.EntryText(0) != "t1 has 2 callers" {
+		t.Fatalf("copy text = %q", tr.EntryText(0))
+	}
+
+	collapsed := tr.Render(RenderOpts{})
+	for _, want := range []string{"✓ 子任务 find callers of t1", "t1-3.go", "前 1 步已折叠", "t1 has 2 callers", "✗ 子任务 find callers of t2", "失败: tool loop limit exceeded"} {
+		if !strings.Contains(collapsed, want) {
+			t.Fatalf("render missing %q:\n%s", want, collapsed)
+		}
+	}
+	if strings.Contains(collapsed, "t1-0.go") {
+		t.Fatal("collapsed task shows every step")
+	}
+	if expanded := tr.Render(RenderOpts{Expanded: map[int]bool{0: true}}); !strings.Contains(expanded, "t1-0.go") {
+		t.Fatalf("expanded task:\n%s", expanded)
+	}
+}
+
+func TestTranscript_todoWriteShowsChecklistWhenChanged(t *testing.T) {
+	tr := New()
+	todos := []tools.Todo{{ID: "1", Content: "读代码", Status: tools.TodoDone}, {ID: "2", Content: "改实现", Status: tools.TodoInProgress}}
+	for range 2 {
+		tr.Apply(agent.Event{Kind: agent.EventToolCall, ToolName: tools.TodoWriteName, ToolArguments: map[string]any{}})
+		tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: tools.TodoWriteName,
+			ToolResult: tools.Success("todos", todos).WithSummary("1/2 完成")})
+	}
+	// The unchanged second list stays a compact tool call.
+	if kinds := tr.EntryKinds(); !reflect.DeepEqual(kinds, []EntryKind{EntryTodos, EntryToolCall, EntryToolResult}) {
+		t.Fatalf("entry kinds = %v", kinds)
+	}
+	if tr.EntryText(0) != "☑ 读代码\n◐ 改实现" {
+		t.Fatalf("copy text = %q", tr.EntryText(0))
+	}
+	if out := tr.Render(RenderOpts{}); !strings.Contains(out, "任务清单") || !strings.Contains(out, "1/2 完成") || !strings.Contains(out, "◐ 改实现") {
+		t.Fatalf("render:\n%s", out)
+	}
+
+	tr.Apply(agent.Event{Kind: agent.EventToolCall, ToolName: tools.TodoWriteName, ToolArguments: map[string]any{}})
+	tr.Apply(agent.Event{Kind: agent.EventToolResult, ToolName: tools.TodoWriteName,
+		ToolResult: tools.Success("todos", todos[:1]).WithSummary("1/1 完成")})
+	if kinds := tr.EntryKinds(); kinds[len(kinds)-1] != EntryTodos {
+		t.Fatalf("entry kinds = %v", kinds)
+	}
+}
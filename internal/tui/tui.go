@@ -10,6 +10,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/git"
 	"github.com/loveRyujin/mini-agent/internal/slash"
 	"github.com/loveRyujin/mini-agent/internal/tools"
 	"github.com/loveRyujin/mini-agent/internal/transcript"
@@ -45,6 +46,24 @@ type model struct {
 	approvalCommand string
 	approvalReplyCh chan<- bool
 
+	// question is the open ask_user modal.
+	question *questionState
+
+	commit     *commitState
+	commitBusy bool
+
+	reviewBusy   bool
+	lastFindings []agent.ReviewFinding
+
+	worktree *git.Worktree
+
+	// planReady is set when the last Turn of a planning mode proposed a
+	// plan that Ctrl+R approves.
+	planReady bool
+
+	// todos is the task list of the Agent's last todo_write call.
+	todos []tools.Todo
+
 	width, height  int
 	turnInProgress bool
 	followTail     bool
@@ -117,6 +136,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case eventMsg:
 		m.transcript.Apply(msg.event)
+		m.noteTurnComplete(msg.event)
+		m.noteTodos(msg.event)
 		m.clearSelection()
 		m.copyNotice = ""
 		if msg.event.Kind == agent.EventApprovalRequired {
@@ -124,12 +145,33 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.approvalReplyCh = msg.event.ApprovalReplyCh
 			m.textarea.Blur()
 		}
+		if msg.event.Kind == agent.EventQuestion && msg.event.AnswerReplyCh != nil {
+			m.openQuestion(msg.event)
+		}
 		m.syncViewport()
 		return m, m.waitEvent()
 
 	case turnDoneMsg:
 		m.turnInProgress = false
 		m.eventCh = nil
+		if m.planReady {
+			m.transcript.AddSystemMessage(fmt.Sprintf("按 Ctrl+R 批准以上计划并切换到 %s 模式执行，或继续输入以修改计划。", m.agent.Mode().ApproveTo))
+		}
+		m.syncViewport()
+		return m, nil
+
+	case commitDraftMsg:
+		m.handleCommitDraft(msg)
+		m.syncViewport()
+		return m, nil
+
+	case commitDoneMsg:
+		m.handleCommitDone(msg)
+		m.syncViewport()
+		return m, nil
+
+	case reviewDoneMsg:
+		m.handleReviewDone(msg)
 		m.syncViewport()
 		return m, nil
 
@@ -138,6 +180,16 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.handleApprovalKeys(msg)
 			return m, nil
 		}
+		if m.question != nil {
+			cmd := m.handleQuestionKeys(msg)
+			m.syncViewport()
+			return m, cmd
+		}
+		if m.commit != nil {
+			cmd := m.handleCommitKeys(msg)
+			m.syncViewport()
+			return m, cmd
+		}
 		if m.transcriptFocus {
 			if m.handleTranscriptKeys(msg) {
 				m.syncViewport()
@@ -179,8 +231,22 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.setAllExpanded(false)
 			m.syncViewport()
 			return m, nil
+		case tea.KeyShiftTab:
+			if m.turnInProgress || m.commitBusy || m.reviewBusy || len(m.agent.Modes) == 0 {
+				return m, nil
+			}
+			m.switchMode(m.agent.NextMode())
+			m.syncViewport()
+			return m, nil
+		case tea.KeyCtrlR:
+			if !m.planReady || m.turnInProgress || m.commitBusy || m.reviewBusy {
+				return m, nil
+			}
+			cmd := m.approvePlan()
+			m.syncViewport()
+			return m, cmd
 		case tea.KeyEnter:
-			if m.turnInProgress || m.transcriptFocus {
+			if m.turnInProgress || m.transcriptFocus || m.commitBusy || m.reviewBusy {
 				return m, nil
 			}
 			text := strings.TrimSpace(m.textarea.Value())
@@ -188,6 +254,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 			m.textarea.SetValue("")
+			m.planReady = false
 			switch result, arg := slash.Parse(text); result {
 			case slash.Quit:
 				return m, tea.Quit
@@ -195,6 +262,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.agent.ClearSession()
 				m.transcript.Reset()
 				m.expanded = make(map[int]bool)
+				m.lastFindings = nil
+				m.todos = nil
+				m.layout()
 				m.focusIdx = -1
 				m.transcriptFocus = false
 				m.textarea.Focus()
@@ -204,6 +274,49 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.transcript.AddSystemMessage(slash.HelpText())
 				m.syncViewport()
 				return m, nil
+			case slash.Commit:
+				if arg != "" && arg != "all" {
+					m.transcript.AddSystemMessage("用法：/commit [all]")
+					m.syncViewport()
+					return m, nil
+				}
+				m.commitBusy = true
+				m.transcript.AddSystemMessage("正在根据改动生成提交信息…")
+				m.syncViewport()
+				return m, draftCommit(m.agent, arg == "all")
+			case slash.Review:
+				if strings.ContainsAny(arg, " \t") {
+					m.transcript.AddSystemMessage("用法：/review [ref]")
+					m.syncViewport()
+					return m, nil
+				}
+				m.reviewBusy = true
+				ref := arg
+				if ref == "" {
+					ref = "HEAD"
+				}
+				m.transcript.AddSystemMessage(fmt.Sprintf("正在审查工作区相对 %s 的改动…", ref))
+				m.syncViewport()
+				return m, runReview(m.agent, arg)
+			case slash.Fix:
+				findings, err := selectFindings(m.lastFindings, arg)
+				if err != nil {
+					m.transcript.AddSystemMessage(err.Error())
+					m.syncViewport()
+					return m, nil
+				}
+				request := agent.FixRequest(findings)
+				m.transcript.AddUserMessage(request)
+				m.syncViewport()
+				return m, startTurn(m.agent, request)
+			case slash.Worktree:
+				m.showWorktreeDiff()
+				m.syncViewport()
+				return m, nil
+			case slash.Mode:
+				m.handleModeCommand(arg)
+				m.syncViewport()
+				return m, nil
 			case slash.Unknown:
 				msgText := "未知命令。"
 				if arg != "" {
@@ -242,6 +355,12 @@ func (m *model) View() string {
 	if m.approvalReplyCh != nil {
 		return overlayModal(renderApprovalModal(m), m.width, m.height)
 	}
+	if m.question != nil {
+		return overlayModal(renderQuestionModal(m), m.width, m.height)
+	}
+	if m.commit != nil {
+		return overlayModal(renderCommitModal(m), m.width, m.height)
+	}
 	return panel
 }
 
@@ -254,8 +373,14 @@ func renderPanel(m *model) string {
 	status := lipgloss.NewStyle().Foreground(t.User).Bold(true).Render("● 就绪")
 	if m.approvalReplyCh != nil {
 		status = lipgloss.NewStyle().Foreground(t.Gold).Bold(true).Render("● 等待批准")
+	} else if m.question != nil {
+		status = lipgloss.NewStyle().Foreground(t.Gold).Bold(true).Render("● 等待回答")
 	} else if m.turnInProgress {
 		status = lipgloss.NewStyle().Foreground(t.Tool).Bold(true).Render("● 生成中")
+	} else if m.commitBusy {
+		status = lipgloss.NewStyle().Foreground(t.Tool).Bold(true).Render("● 提交中")
+	} else if m.reviewBusy {
+		status = lipgloss.NewStyle().Foreground(t.Tool).Bold(true).Render("● 审查中")
 	}
 	title := titleStyle.Width(m.width).Render(
 		lipgloss.JoinHorizontal(lipgloss.Top,
@@ -263,11 +388,17 @@ func renderPanel(m *model) string {
 			lipgloss.PlaceHorizontal(m.width-lipgloss.Width(" mini-agent ")-lipgloss.Width(status), lipgloss.Right, status),
 		),
 	)
-	subtitle := lipgloss.NewStyle().Foreground(t.Dim).Width(m.width).Padding(0, 1).
-		Render(m.agent.Model + "  ·  " + m.workspace + "  ·  " + t.Name)
+	info := m.agent.ActiveModel() + "  ·  " + m.workspace + "  ·  " + t.Name
+	if mode := m.agent.Mode().Name; mode != "" {
+		info = mode + " 模式  ·  " + info
+	}
+	subtitle := lipgloss.NewStyle().Foreground(t.Dim).Width(m.width).Padding(0, 1).Render(info)
 	transcriptPanel := panelStyle.Width(m.width - 2).Render(m.viewport.View())
 	inputPanel := panelStyle.Width(m.width - 2).Render(renderInput(m))
 	footer := footerStyle.Width(m.width).Render(m.footerText())
+	if m.todosPinned() {
+		return lipgloss.JoinVertical(lipgloss.Left, title, subtitle, transcriptPanel, renderTodos(m), inputPanel, footer)
+	}
 	return lipgloss.JoinVertical(lipgloss.Left, title, subtitle, transcriptPanel, inputPanel, footer)
 }
 
@@ -275,6 +406,15 @@ func (m *model) footerText() string {
 	if m.approvalReplyCh != nil {
 		return "Y 允许执行  ·  N 拒绝"
 	}
+	if m.question != nil {
+		return "Enter 回答  ·  Esc 跳过"
+	}
+	if m.commit != nil {
+		return "Ctrl+S 提交  ·  Esc 取消"
+	}
+	if m.planReady {
+		return "Ctrl+R 批准计划并执行  ·  PgUp/PgDn 滚动  ·  Enter 发送"
+	}
 	if m.copyNotice != "" {
 		return m.copyNotice + "  ·  鼠标拖拽选中复制  ·  PgUp/PgDn 滚动  ·  Enter 发送"
 	}
@@ -306,7 +446,7 @@ func (m *model) layout() {
 	m.viewport.Width = m.width - 4
 
 	const chrome = 10
-	vpH := m.height - chrome - inputH
+	vpH := m.height - chrome - inputH - m.todoPanelHeight()
 	if vpH < 3 {
 		vpH = 3
 	}
@@ -376,8 +516,26 @@ func startTurn(a *agent.Agent, userText string) tea.Cmd {
 	}
 }
 
-func Run(a *agent.Agent) error {
-	p := tea.NewProgram(newModel(a), tea.WithAltScreen(), tea.WithMouseCellMotion())
+// Options configure a TUI Session beyond its Agent.
+type Options struct {
+	// Worktree is the worktree the Session runs in, if started with
+	// --worktree.
+	Worktree *git.Worktree
+	// Notices are shown at the top of the transcript, e.g. MCP servers that
+	// failed to start.
+	Notices []string
+}
+
+func Run(a *agent.Agent, opts Options) error {
+	m := newModel(a)
+	if opts.Worktree != nil {
+		m.worktree = opts.Worktree
+		m.workspace += "  ⎇ " + opts.Worktree.Branch
+	}
+	for _, n := range opts.Notices {
+		m.transcript.AddSystemMessage(n)
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	_, err := p.Run()
 	return err
 }
@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/git"
+)
+
+const maxCommitModalFiles = 8
+
+type commitDraftMsg struct {
+	draft agent.CommitDraft
+	err   error
+}
+
+type commitDoneMsg struct {
+	commit git.Commit
+	err    error
+}
+
+// commitState is the /commit modal: the draft being edited and the last
+// error from committing it.
+type commitState struct {
+	draft  agent.CommitDraft
+	editor textarea.Model
+	err    error
+}
+
+func draftCommit(a *agent.Agent, all bool) tea.Cmd {
+	return func() tea.Msg {
+		draft, err := a.DraftCommit(context.Background(), all)
+		return commitDraftMsg{draft: draft, err: err}
+	}
+}
+
+func runCommit(a *agent.Agent, draft agent.CommitDraft, message string) tea.Cmd {
+	return func() tea.Msg {
+		commit, err := a.CommitDraft(context.Background(), draft, message)
+		return commitDoneMsg{commit: commit, err: err}
+	}
+}
+
+func (m *model) handleCommitDraft(msg commitDraftMsg) {
+	m.commitBusy = false
+	switch {
+	case errors.Is(msg.err, agent.ErrNothingToCommit):
+		m.transcript.AddSystemMessage("没有可提交的改动。先暂存改动，或使用 /commit all。")
+		return
+	case msg.err != nil:
+		m.transcript.AddSystemMessage("生成提交信息失败：" + msg.err.Error())
+		return
+	}
+	editor := textarea.New()
+	editor.ShowLineNumbers = false
+	editor.CharLimit = 0
+	editor.SetWidth(m.commitModalWidth() - 6)
+	editor.SetHeight(8)
+	editor.SetValue(msg.draft.Message)
+	editor.Focus()
+	m.commit = &commitState{draft: msg.draft, editor: editor}
+	m.textarea.Blur()
+}
+
+func (m *model) handleCommitDone(msg commitDoneMsg) {
+	m.commitBusy = false
+	if msg.err != nil {
+		if m.commit != nil {
+			m.commit.err = msg.err
+			m.commit.editor.Focus()
+		}
+		return
+	}
+	m.closeCommit()
+	m.transcript.AddSystemMessage(fmt.Sprintf("✓ 已提交 %s  %s", shortHash(msg.commit.Hash), msg.commit.Subject))
+}
+
+func (m *model) handleCommitKeys(msg tea.KeyMsg) tea.Cmd {
+	if m.commitBusy {
+		return nil
+	}
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.closeCommit()
+		m.transcript.AddSystemMessage("已取消提交。")
+		return nil
+	case tea.KeyCtrlS:
+		message := strings.TrimSpace(m.commit.editor.Value())
+		if message == "" {
+			m.commit.err = errors.New("提交信息不能为空")
+			return nil
+		}
+		m.commitBusy = true
+		m.commit.err = nil
+		m.commit.editor.Blur()
+		return runCommit(m.agent, m.commit.draft, message)
+	}
+	var cmd tea.Cmd
+	m.commit.editor, cmd = m.commit.editor.Update(msg)
+	return cmd
+}
+
+func (m *model) closeCommit() {
+	m.commit = nil
+	m.textarea.Focus()
+}
+
+func (m *model) commitModalWidth() int {
+	return max(20, min(76, m.width-4))
+}
+
+func renderCommitModal(m *model) string {
+	t := m.theme
+	border := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border).
+		Background(lipgloss.Color("234")).
+		Padding(1, 2).
+		Width(m.commitModalWidth())
+
+	scope := "暂存区"
+	if m.commit.draft.All {
+		scope = "全部改动"
+	}
+	title := lipgloss.NewStyle().Foreground(t.Gold).Bold(true).Render("提交 — " + scope)
+
+	var files []string
+	for i, f := range m.commit.draft.Files {
+		if i == maxCommitModalFiles {
+			files = append(files, fmt.Sprintf("… 另有 %d 个文件", len(m.commit.draft.Files)-i))
+			break
+		}
+		files = append(files, fmt.Sprintf("%s  +%d -%d", f.Path, f.Added, f.Deleted))
+	}
+	fileList := lipgloss.NewStyle().Foreground(t.Dim).Render(strings.Join(files, "\n"))
+
+	parts := []string{title, "", fileList, "", m.commit.editor.View()}
+	if m.commit.err != nil {
+		parts = append(parts, "", lipgloss.NewStyle().Foreground(t.Error).Render("提交失败："+m.commit.err.Error()))
+	}
+	hint := "Ctrl+S 提交  ·  Esc 取消"
+	if m.commitBusy {
+		hint = "提交中…"
+	}
+	parts = append(parts, "", lipgloss.NewStyle().Foreground(t.Dim).Render(hint))
+	return border.Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
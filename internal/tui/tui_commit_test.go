@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+func newCommitModel() *model {
+	m := newModel(&agent.Agent{Model: "test-model", Tools: tools.NewRegistry()})
+	m.ready = true
+	m.width = 100
+	m.height = 30
+	m.layout()
+	return m
+}
+
+func TestCommitModal_editAndRecordHash(t *testing.T) {
+	m := newCommitModel()
+	m.commitBusy = true
+	m.Update(commitDraftMsg{draft: agent.CommitDraft{
+		Files:   []git.FileChange{{Path: "a.go", Added: 3, Deleted: 1}},
+		Message: "feat: add a",
+	}})
+	if m.commit == nil || m.commitBusy {
+		t.Fatal("draft did not open the commit modal")
+	}
+	view := ansi.Strip(m.View())
+	if !strings.Contains(view, "feat: add a") || !strings.Contains(view, "a.go  +3 -1") {
+		t.Fatalf("modal view:\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	if got := m.commit.editor.Value(); got != "feat: add a!" {
+		t.Fatalf("editor = %q", got)
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd == nil || !m.commitBusy {
+		t.Fatal("Ctrl+S did not start the commit")
+	}
+	m.Update(commitDoneMsg{commit: git.Commit{Hash: "0123456789abcdef", Subject: "feat: add a!"}})
+	if m.commit != nil {
+		t.Fatal("modal still open after commit")
+	}
+	entries := m.transcript.Entries()
+	if last := entries[len(entries)-1].Text; !strings.Contains(last, "0123456") || !strings.Contains(last, "feat: add a!") {
+		t.Fatalf("transcript = %q", last)
+	}
+}
+
+func TestCommitModal_failureKeepsDraft(t *testing.T) {
+	m := newCommitModel()
+	m.Update(commitDraftMsg{draft: agent.CommitDraft{Message: "fix: x"}})
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m.Update(commitDoneMsg{err: agent.ErrNothingToCommit})
+	if m.commit == nil || m.commit.err == nil || m.commitBusy {
+		t.Fatal("failed commit should keep the modal open with the error")
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.commit != nil {
+		t.Fatal("Esc did not close the modal")
+	}
+}
+
+func TestCommitModal_nothingToCommit(t *testing.T) {
+	m := newCommitModel()
+	m.Update(commitDraftMsg{err: agent.ErrNothingToCommit})
+	if m.commit != nil {
+		t.Fatal("modal opened without a draft")
+	}
+	entries := m.transcript.Entries()
+	if !strings.Contains(entries[len(entries)-1].Text, "/commit all") {
+		t.Fatalf("transcript = %q", entries[len(entries)-1].Text)
+	}
+}
@@ -102,7 +102,8 @@ func (m *model) allToolBlocksExpanded() bool {
 
 func (m *model) setAllExpanded(expanded bool) {
 	for i, e := range m.transcript.Entries() {
-		if e.Kind == transcript.EntryReasoning {
+		switch e.Kind {
+		case transcript.EntryReasoning, transcript.EntryVerify, transcript.EntryReview, transcript.EntryDiff, transcript.EntryTask:
 			m.expanded[i] = expanded
 		}
 		if transcript.HasPairedToolResult(m.transcript.Entries(), i) {
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+)
+
+// handleModeCommand lists the modes, or switches to the one named in arg.
+func (m *model) handleModeCommand(arg string) {
+	if arg == "" {
+		var b strings.Builder
+		b.WriteString("Agent 模式（Shift+Tab 切换）：")
+		for _, mode := range m.agent.Modes {
+			marker := "  "
+			if mode.Name == m.agent.Mode().Name {
+				marker = "▸ "
+			}
+			fmt.Fprintf(&b, "\n%s%-6s %s", marker, mode.Name, mode.Description)
+		}
+		m.transcript.AddSystemMessage(b.String())
+		return
+	}
+	m.switchMode(arg)
+}
+
+// switchMode activates the named mode and reports it in the Transcript.
+func (m *model) switchMode(name string) {
+	if err := m.agent.SetMode(name); err != nil {
+		names := make([]string, len(m.agent.Modes))
+		for i, mode := range m.agent.Modes {
+			names[i] = mode.Name
+		}
+		m.transcript.AddSystemMessage(fmt.Sprintf("没有 %s 模式。可用模式：%s", name, strings.Join(names, "、")))
+		return
+	}
+	m.planReady = false
+	mode := m.agent.Mode()
+	m.transcript.AddSystemMessage(fmt.Sprintf("已切换到 %s 模式：%s", mode.Name, mode.Description))
+}
+
+// noteTurnComplete offers to execute the answer of a planning mode once the
+// Turn is over.
+func (m *model) noteTurnComplete(e agent.Event) {
+	if e.Kind == agent.EventTurnComplete && e.TaskID == "" && strings.TrimSpace(e.AssistantMessage) != "" {
+		m.planReady = m.agent.Mode().ApproveTo != ""
+	}
+}
+
+// approvePlan switches to the mode that executes the plan and starts the
+// Turn that carries it out.
+func (m *model) approvePlan() tea.Cmd {
+	m.switchMode(m.agent.Mode().ApproveTo)
+	m.transcript.AddUserMessage(agent.PlanApprovedRequest)
+	return startTurn(m.agent, agent.PlanApprovedRequest)
+}
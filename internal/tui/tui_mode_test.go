@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+)
+
+func newModeModel() *model {
+	m := newCommitModel()
+	m.agent = agent.NewAgent("", "", "test-model", "system")
+	return m
+}
+
+func lastEntryText(m *model) string {
+	entries := m.transcript.Entries()
+	return entries[len(entries)-1].Text
+}
+
+func TestModeCommand(t *testing.T) {
+	m := newModeModel()
+	m.textarea.SetValue("/mode")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if text := lastEntryText(m); !strings.Contains(text, "▸ code") || !strings.Contains(text, "plan") {
+		t.Fatalf("mode list = %q", text)
+	}
+
+	m.textarea.SetValue("/mode plan")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.agent.Mode().Name != agent.ModePlan || !strings.Contains(lastEntryText(m), "已切换到 plan 模式") {
+		t.Fatalf("mode = %s, transcript = %q", m.agent.Mode().Name, lastEntryText(m))
+	}
+	if !strings.Contains(renderPanel(m), "plan 模式") {
+		t.Fatal("the panel does not show the mode")
+	}
+
+	m.textarea.SetValue("/mode yolo")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.agent.Mode().Name != agent.ModePlan || !strings.Contains(lastEntryText(m), "没有 yolo 模式") {
+		t.Fatalf("mode = %s, transcript = %q", m.agent.Mode().Name, lastEntryText(m))
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if m.agent.Mode().Name != agent.ModeAsk {
+		t.Fatalf("Shift+Tab switched to %s", m.agent.Mode().Name)
+	}
+}
+
+func TestPlanApproval(t *testing.T) {
+	m := newModeModel()
+	if err := m.agent.SetMode(agent.ModePlan); err != nil {
+		t.Fatal(err)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.turnInProgress || m.agent.Mode().Name != agent.ModePlan {
+		t.Fatal("Ctrl+R acted without a plan")
+	}
+
+	m.turnInProgress = true
+	m.Update(eventMsg{event: agent.Event{Kind: agent.EventTurnComplete, AssistantMessage: "1. Do it."}})
+	m.Update(turnDoneMsg{})
+	if !m.planReady || !strings.Contains(lastEntryText(m), "Ctrl+R") || !strings.Contains(m.footerText(), "Ctrl+R") {
+		t.Fatalf("plan not offered: ready = %v, transcript = %q", m.planReady, lastEntryText(m))
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil || m.planReady || m.agent.Mode().Name != agent.ModeCode {
+		t.Fatalf("after approval: cmd = %v, ready = %v, mode = %s", cmd, m.planReady, m.agent.Mode().Name)
+	}
+	if last := lastEntryText(m); last != agent.PlanApprovedRequest {
+		t.Fatalf("last entry = %q", last)
+	}
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+)
+
+// questionState is the ask_user modal: the question, the highlighted
+// choice and the free-text answer being typed.
+type questionState struct {
+	text    string
+	choices []string
+	cursor  int
+	input   textinput.Model
+	replyCh chan<- string
+}
+
+func (m *model) openQuestion(e agent.Event) {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "输入回答"
+	if len(e.Choices) > 0 {
+		input.Placeholder = "或输入其他回答"
+	}
+	input.CharLimit = 0
+	input.Width = m.questionModalWidth() - 8
+	input.Focus()
+	m.question = &questionState{text: e.Text, choices: e.Choices, input: input, replyCh: e.AnswerReplyCh}
+	m.textarea.Blur()
+}
+
+// handleQuestionKeys answers with the typed text, or else the highlighted
+// choice. Esc skips the question.
+func (m *model) handleQuestionKeys(msg tea.KeyMsg) tea.Cmd {
+	q := m.question
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.answerQuestion("")
+		return nil
+	case tea.KeyUp:
+		if q.cursor > 0 {
+			q.cursor--
+		}
+		return nil
+	case tea.KeyDown:
+		if q.cursor < len(q.choices)-1 {
+			q.cursor++
+		}
+		return nil
+	case tea.KeyEnter:
+		answer := strings.TrimSpace(q.input.Value())
+		if answer == "" && len(q.choices) > 0 {
+			answer = q.choices[q.cursor]
+		}
+		if answer != "" {
+			m.answerQuestion(answer)
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	q.input, cmd = q.input.Update(msg)
+	return cmd
+}
+
+func (m *model) answerQuestion(answer string) {
+	m.question.replyCh <- answer
+	m.question = nil
+	m.textarea.Focus()
+}
+
+func (m *model) questionModalWidth() int {
+	return max(20, min(70, m.width-4))
+}
+
+func renderQuestionModal(m *model) string {
+	t := m.theme
+	q := m.question
+	border := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border).
+		Background(lipgloss.Color("234")).
+		Padding(1, 2).
+		Width(m.questionModalWidth())
+
+	title := lipgloss.NewStyle().Foreground(t.Gold).Bold(true).Render("Agent 提问")
+	question := lipgloss.NewStyle().Foreground(t.Agent).Render(q.text)
+	parts := []string{title, "", question, ""}
+
+	typed := strings.TrimSpace(q.input.Value()) != ""
+	for i, choice := range q.choices {
+		line := fmt.Sprintf("  %d. %s", i+1, choice)
+		style := lipgloss.NewStyle().Foreground(t.Dim)
+		if i == q.cursor && !typed {
+			line = fmt.Sprintf("▸ %d. %s", i+1, choice)
+			style = lipgloss.NewStyle().Foreground(t.Tool).Bold(true)
+		}
+		parts = append(parts, style.Render(line))
+	}
+	if len(q.choices) > 0 {
+		parts = append(parts, "")
+	}
+	hint := "Enter 回答  ·  Esc 跳过"
+	if len(q.choices) > 0 {
+		hint = "↑/↓ 选择  ·  " + hint
+	}
+	parts = append(parts, q.input.View(), "", lipgloss.NewStyle().Foreground(t.Dim).Render(hint))
+	return border.Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+)
+
+func askQuestion(m *model, choices ...string) chan string {
+	ch := make(chan string, 1)
+	m.Update(eventMsg{event: agent.Event{Kind: agent.EventQuestion, Text: "Which database?", Choices: choices, AnswerReplyCh: ch}})
+	return ch
+}
+
+func TestQuestionModal(t *testing.T) {
+	m := newModeModel()
+	m.ready, m.width, m.height = true, 80, 30
+	m.layout()
+
+	ch := askQuestion(m, "sqlite", "postgres")
+	view := ansi.Strip(m.View())
+	if m.question == nil || !strings.Contains(view, "Which database?") || !strings.Contains(view, "▸ 1. sqlite") {
+		t.Fatalf("modal:\n%s", view)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if answer := <-ch; answer != "postgres" || m.question != nil {
+		t.Fatalf("answer = %q, modal open = %v", answer, m.question != nil)
+	}
+
+	ch = askQuestion(m, "sqlite")
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("mysql")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if answer := <-ch; answer != "mysql" {
+		t.Fatalf("free-text answer = %q", answer)
+	}
+
+	ch = askQuestion(m)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.question == nil {
+		t.Fatal("an empty answer closed the modal")
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if answer := <-ch; answer != "" || m.question != nil {
+		t.Fatalf("skipped answer = %q", answer)
+	}
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+)
+
+type reviewDoneMsg struct {
+	review agent.Review
+	err    error
+}
+
+func runReview(a *agent.Agent, ref string) tea.Cmd {
+	return func() tea.Msg {
+		review, err := a.Review(context.Background(), ref)
+		return reviewDoneMsg{review: review, err: err}
+	}
+}
+
+func (m *model) handleReviewDone(msg reviewDoneMsg) {
+	m.reviewBusy = false
+	switch {
+	case errors.Is(msg.err, agent.ErrNoChanges):
+		m.transcript.AddSystemMessage(fmt.Sprintf("工作区相对 %s 没有改动，无需审查。", msg.review.Ref))
+		return
+	case msg.err != nil:
+		m.transcript.AddSystemMessage("审查失败：" + msg.err.Error())
+		return
+	}
+	m.lastFindings = msg.review.Findings
+	m.transcript.AddReview(msg.review)
+}
+
+// selectFindings picks findings from the last review by their 1-based
+// numbers in arg; an empty arg selects all of them.
+func selectFindings(findings []agent.ReviewFinding, arg string) ([]agent.ReviewFinding, error) {
+	if len(findings) == 0 {
+		return nil, errors.New("没有可应用的审查建议，请先运行 /review。")
+	}
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return findings, nil
+	}
+	var selected []agent.ReviewFinding
+	seen := make(map[int]bool)
+	for _, f := range fields {
+		n, err := strconv.Atoi(strings.TrimPrefix(f, "#"))
+		if err != nil || n < 1 || n > len(findings) {
+			return nil, fmt.Errorf("没有第 %s 条建议（共 %d 条）。", f, len(findings))
+		}
+		if !seen[n] {
+			seen[n] = true
+			selected = append(selected, findings[n-1])
+		}
+	}
+	return selected, nil
+}
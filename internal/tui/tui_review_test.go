@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/transcript"
+)
+
+func TestSelectFindings(t *testing.T) {
+	findings := []agent.ReviewFinding{{Message: "one"}, {Message: "two"}, {Message: "three"}}
+
+	all, err := selectFindings(findings, "")
+	if err != nil || len(all) != 3 {
+		t.Fatalf("all = %v, %v", all, err)
+	}
+	some, err := selectFindings(findings, "3 #1 3")
+	if err != nil || len(some) != 2 || some[0].Message != "three" || some[1].Message != "one" {
+		t.Fatalf("some = %v, %v", some, err)
+	}
+	for _, arg := range []string{"0", "4", "x"} {
+		if _, err := selectFindings(findings, arg); err == nil {
+			t.Errorf("selectFindings(%q) succeeded", arg)
+		}
+	}
+	if _, err := selectFindings(nil, ""); err == nil || !strings.Contains(err.Error(), "/review") {
+		t.Fatalf("no review: %v", err)
+	}
+}
+
+func TestReviewDone_recordsFindings(t *testing.T) {
+	m := newCommitModel()
+	m.reviewBusy = true
+	review := agent.Review{Ref: "HEAD", Findings: []agent.ReviewFinding{{File: "a.go", Line: 2, Severity: agent.SeverityLow, Message: "m", Suggestion: "s"}}}
+	m.Update(reviewDoneMsg{review: review})
+
+	if m.reviewBusy || len(m.lastFindings) != 1 {
+		t.Fatalf("busy = %v, findings = %v", m.reviewBusy, m.lastFindings)
+	}
+	kinds := m.transcript.EntryKinds()
+	if kinds[len(kinds)-1] != transcript.EntryReview {
+		t.Fatalf("entry kinds = %v", kinds)
+	}
+
+	m.Update(reviewDoneMsg{review: agent.Review{Ref: "HEAD"}, err: agent.ErrNoChanges})
+	if len(m.lastFindings) != 1 {
+		t.Fatal("a failed review replaced the previous findings")
+	}
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+	"github.com/loveRyujin/mini-agent/internal/transcript"
+)
+
+// maxPinnedTodos bounds the items the pinned checklist shows.
+const maxPinnedTodos = 5
+
+// noteTodos keeps the pinned checklist in sync with the task list of the
+// Agent's todo_write calls.
+func (m *model) noteTodos(e agent.Event) {
+	if e.Kind != agent.EventToolResult || e.TaskID != "" || e.ToolName != tools.TodoWriteName || e.ToolResult.Failed() {
+		return
+	}
+	if todos, ok := e.ToolResult.Data["todos"].([]tools.Todo); ok {
+		m.todos = todos
+		m.layout()
+	}
+}
+
+// todosPinned reports whether the checklist is pinned: while some item is
+// not done.
+func (m *model) todosPinned() bool {
+	return slices.ContainsFunc(m.todos, func(t tools.Todo) bool { return t.Status != tools.TodoDone })
+}
+
+func (m *model) todoPanelHeight() int {
+	if !m.todosPinned() {
+		return 0
+	}
+	// The header and the two borders.
+	return 3 + min(len(m.todos), maxPinnedTodos)
+}
+
+// renderTodos renders the pinned checklist. A long list is shown from just
+// before its first open item.
+func renderTodos(m *model) string {
+	done := 0
+	for _, t := range m.todos {
+		if t.Status == tools.TodoDone {
+			done++
+		}
+	}
+	summary := fmt.Sprintf("%d/%d 完成", done, len(m.todos))
+	shown := m.todos
+	if len(shown) > maxPinnedTodos {
+		open := slices.IndexFunc(shown, func(t tools.Todo) bool { return t.Status != tools.TodoDone })
+		start := max(0, min(open-1, len(shown)-maxPinnedTodos))
+		shown = shown[start : start+maxPinnedTodos]
+		summary += fmt.Sprintf(" · 第 %d–%d 项", start+1, start+maxPinnedTodos)
+	}
+	// Long items are cut rather than wrapped so the panel keeps its height.
+	body := lipgloss.NewStyle().MaxWidth(m.width - 4).Render(transcript.RenderTodos(shown, summary, m.theme))
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(m.theme.Border).
+		Padding(0, 1).Width(m.width - 2).Render(body)
+}
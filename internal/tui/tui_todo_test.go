@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/loveRyujin/mini-agent/internal/agent"
+	"github.com/loveRyujin/mini-agent/internal/tools"
+)
+
+func todoEvent(todos ...tools.Todo) eventMsg {
+	return eventMsg{event: agent.Event{Kind: agent.EventToolResult, ToolName: tools.TodoWriteName, ToolResult: tools.Success("todos", todos)}}
+}
+
+func TestTodoPanel(t *testing.T) {
+	m := newModeModel()
+	m.ready, m.width, m.height = true, 80, 30
+	m.layout()
+	fullHeight := m.viewport.Height
+	lines := strings.Count(renderPanel(m), "\n")
+
+	var todos []tools.Todo
+	for i := range maxPinnedTodos + 3 {
+		todos = append(todos, tools.Todo{ID: fmt.Sprint(i), Content: fmt.Sprintf("step %d", i), Status: tools.TodoDone})
+	}
+	todos[6].Status = tools.TodoInProgress
+	m.Update(todoEvent(todos...))
+	if m.viewport.Height != fullHeight-3-maxPinnedTodos {
+		t.Fatalf("viewport height = %d with the panel, %d without", m.viewport.Height, fullHeight)
+	}
+	panel := renderPanel(m)
+	if !strings.Contains(panel, "7/8 完成 · 第 4–8 项") || !strings.Contains(panel, "◐ step 6") || strings.Contains(panel, "step 2") {
+		t.Fatalf("panel:\n%s", panel)
+	}
+	if got := strings.Count(panel, "\n"); got != lines {
+		t.Fatalf("the checklist changed the screen from %d to %d lines", lines+1, got+1)
+	}
+
+	todos[6].Status = tools.TodoDone
+	m.Update(todoEvent(todos...))
+	if m.todosPinned() || m.viewport.Height != fullHeight {
+		t.Fatal("the finished list stays pinned")
+	}
+
+	m.Update(todoEvent(todos[0], tools.Todo{ID: "9", Content: "again", Status: tools.TodoPending}))
+	m.textarea.SetValue("/clear")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.todos != nil || m.viewport.Height != fullHeight {
+		t.Fatal("/clear kept the list")
+	}
+}
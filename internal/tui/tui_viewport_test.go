@@ -9,7 +9,7 @@ import (
 )
 
 func TestSyncViewport_preservesScrollWhenNotFollowingTail(t *testing.T) {
-	a := &agent.Agent{Model: "test-model", Tools: make(map[string]tools.Tool)}
+	a := &agent.Agent{Model: "test-model", Tools: tools.NewRegistry()}
 	m := newModel(a)
 	m.ready = true
 	m.width = 80
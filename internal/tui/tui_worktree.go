@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+)
+
+// showWorktreeDiff adds the changes the Session made in its worktree,
+// relative to the commit it branched from, to the Transcript.
+func (m *model) showWorktreeDiff() {
+	w := m.worktree
+	if w == nil {
+		m.transcript.AddSystemMessage("当前 Session 未在 worktree 中运行，请以 --worktree 启动。")
+		return
+	}
+	ctx := context.Background()
+	changes, err := w.Changes(ctx)
+	if err != nil {
+		m.transcript.AddSystemMessage("读取 worktree 改动失败：" + err.Error())
+		return
+	}
+	base := w.BaseBranch
+	if base == "" {
+		base = shortHash(w.Base)
+	}
+	if len(changes) == 0 {
+		m.transcript.AddSystemMessage(fmt.Sprintf("worktree（分支 %s）相对 %s 没有改动。", w.Branch, base))
+		return
+	}
+	diff, err := w.Diff(ctx)
+	if err != nil {
+		m.transcript.AddSystemMessage("读取 worktree 改动失败：" + err.Error())
+		return
+	}
+	added, deleted := 0, 0
+	for _, c := range changes {
+		added += c.Added
+		deleted += c.Deleted
+	}
+	title := fmt.Sprintf("%s ← %s  %d 个文件 +%d -%d", w.Branch, base, len(changes), added, deleted)
+	m.transcript.AddDiff(title, diff)
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loveRyujin/mini-agent/internal/git"
+	"github.com/loveRyujin/mini-agent/internal/transcript"
+)
+
+func TestWorktreeDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=Dev", "-c", "user.email=dev@example.com", "commit", "-q", "--allow-empty", "-m", "start"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	ctx := context.Background()
+	origin, err := git.Open(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := git.CreateWorktree(ctx, origin, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = w.Remove(ctx) })
+
+	m := newCommitModel()
+	m.worktree = w
+	m.showWorktreeDiff()
+	entries := m.transcript.Entries()
+	if last := entries[len(entries)-1].Text; !strings.Contains(last, "没有改动") {
+		t.Fatalf("clean worktree: %q", last)
+	}
+
+	if err := os.WriteFile(filepath.Join(w.Dir, "new.txt"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.showWorktreeDiff()
+	entries = m.transcript.Entries()
+	last := entries[len(entries)-1]
+	if last.Kind != transcript.EntryDiff || !strings.Contains(last.Meta, "mini-agent/test ← main  1 个文件 +1 -0") || !strings.Contains(last.Text, "+hello") {
+		t.Fatalf("diff entry = %+v", last)
+	}
+}
+
+func TestWorktreeDiff_withoutWorktree(t *testing.T) {
+	m := newCommitModel()
+	m.showWorktreeDiff()
+	entries := m.transcript.Entries()
+	if last := entries[len(entries)-1].Text; !strings.Contains(last, "--worktree") {
+		t.Fatalf("transcript = %q", last)
+	}
+}